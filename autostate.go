@@ -0,0 +1,128 @@
+package searchcraigs
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// dataDir returns the directory for the files searchcraigs keeps between runs:
+// $XDG_DATA_HOME/searchcraigs, or ~/.local/share/searchcraigs.
+func dataDir() (string, error) {
+	dir := os.Getenv("XDG_DATA_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+
+		dir = filepath.Join(home, ".local", "share")
+	}
+
+	return filepath.Join(dir, "searchcraigs"), nil
+}
+
+// autoStatePath returns the file storing the entries seen by previous runs of the search
+// identified by searchKey (the search parameters), so that different searches don't share entries.
+func autoStatePath(searchKey string) (string, error) {
+	dir, err := dataDir()
+	if err != nil {
+		return "", err
+	}
+
+	dir = filepath.Join(dir, "seen")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256([]byte(strings.ToLower(strings.TrimSpace(searchKey))))
+	return filepath.Join(dir, fmt.Sprintf("%x.json", sum[:8])), nil
+}
+
+// userStateDir returns the directory storing the entries seen and the favorites of the -serve users
+// (a file per user) for the search identified by searchKey (see autoStatePath).
+func userStateDir(searchKey string) (string, error) {
+	dir, err := dataDir()
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256([]byte(strings.ToLower(strings.TrimSpace(searchKey))))
+	dir = filepath.Join(dir, "users", fmt.Sprintf("%x", sum[:8]))
+	return dir, os.MkdirAll(dir, 0o755)
+}
+
+// IsSeen returns true if the entry was seen before.
+func (s *SeenState) IsSeen(entry ResultEntry) bool {
+	for _, k := range s.entryKeys(entry) {
+		if _, ok := s.Seen[k]; ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Mark marks the entries as seen.
+func (s *SeenState) Mark(entries []ResultEntry) {
+	now := time.Now()
+
+	for _, e := range entries {
+		for _, k := range s.entryKeys(e) {
+			s.Seen[k] = now
+		}
+	}
+}
+
+// SplitSeen sets FirstSeenThisRun for the entries that were not seen before, without marking them as seen.
+// The new entries are moved first (keeping their order), and their number is returned.
+func (s *SeenState) SplitSeen(res *SearchResults) int {
+	n := 0
+
+	for i, e := range res.Entries {
+		isNew := !s.IsSeen(e)
+
+		res.Entries[i].FirstSeenThisRun = isNew
+		if isNew {
+			n++
+		}
+	}
+
+	sort.SliceStable(res.Entries, func(i, j int) bool {
+		return res.Entries[i].FirstSeenThisRun && !res.Entries[j].FirstSeenThisRun
+	})
+
+	res.split = true
+	return n
+}
+
+// MarkNew is SplitSeen, and then marks all entries as seen.
+func (s *SeenState) MarkNew(res *SearchResults) int {
+	n := s.SplitSeen(res)
+	s.Mark(res.Entries)
+	return n
+}
+
+// splitNew marks the entries not returned by previous runs of the same search (see MarkNew),
+// using the search state in the data directory.
+func splitNew(searchKey string, res *SearchResults, maxAge time.Duration) error {
+	path, err := autoStatePath(searchKey)
+	if err != nil {
+		return err
+	}
+
+	state, err := LoadSeenState(path, maxAge)
+	if err != nil {
+		return err
+	}
+
+	n := state.MarkNew(res)
+	log.Printf("%v new entries, %v seen before", n, len(res.Entries)-n)
+
+	return state.Save()
+}
@@ -0,0 +1,261 @@
+package searchcraigs
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// The for sale categories. Each category has a code for all listings, and one each for
+// listings by owner and by dealer (the latter are also the codes used in the listing URLs).
+var categoryTable = []struct {
+	All, Owner, Dealer Category
+	Name               string
+}{
+	{"ata", "atq", "atd", "antiques"},
+	{"ppa", "app", "ppd", "appliances"},
+	{"ara", "arg", "ard", "arts & crafts"},
+	{"pta", "pts", "ptd", "auto parts"},
+	{"baa", "bab", "bad", "baby & kid"},
+	{Bikes, "bik", "bid", "bicycles"},
+	{Boats, "boo", "bod", "boats"},
+	{"bka", "bks", "bkd", "books"},
+	{Cars, "cto", "ctd", "cars & trucks"},
+	{Cellphones, "mob", "mod", "cell phones"},
+	{"cla", "clo", "cld", "clothing & accessories"},
+	{"cba", "clt", "cbd", "collectibles"},
+	{Computers, "sys", "syd", "computers"},
+	{Electronics, "ele", "eld", "electronics"},
+	{"gra", "grd", "grq", "farm & garden"},
+	{Free, Free, Free, "free stuff"},
+	{Furniture, "fuo", "fud", "furniture"},
+	{"foa", "for", "fod", "general for sale"},
+	{"hsa", "hsh", "hsd", "household items"},
+	{"jwa", "jwl", "jwd", "jewelry"},
+	{"maa", "mat", "mad", "materials"},
+	{"mca", "mcy", "mcd", "motorcycles"},
+	{Music, "msg", "msd", "musical instruments"},
+	{"pha", "pho", "phd", "photo & video"},
+	{RVs, "rvs", "rvd", "rvs & camping"},
+	{Sporting, "spo", "sgd", "sporting goods"},
+	{"tia", "tix", "tid", "tickets"},
+	{Tools, "tls", "tld", "tools"},
+	{"taa", "tag", "tad", "toys & games"},
+	{"vga", "vgm", "vgd", "video gaming"},
+	{"waa", "wan", "wad", "wanted"},
+
+	// services and community listings have only one code
+	{"aos", "aos", "aos", "automotive services"},
+	{"bts", "bts", "bts", "beauty services"},
+	{"cps", "cps", "cps", "computer services"},
+	{"crs", "crs", "crs", "creative services"},
+	{"evs", "evs", "evs", "event services"},
+	{"hss", "hss", "hss", "household services"},
+	{"lbs", "lbs", "lbs", "labor & moving"},
+	{"lss", "lss", "lss", "lessons & tutoring"},
+	{"sks", "sks", "sks", "skilled trade services"},
+	{"act", "act", "act", "activities"},
+	{"cls", "cls", "cls", "classes"},
+	{"eve", "eve", "eve", "events"},
+	{"com", "com", "com", "general community"},
+	{"grp", "grp", "grp", "groups"},
+	{"laf", "laf", "laf", "lost & found"},
+	{"muc", "muc", "muc", "musicians"},
+	{"pet", "pet", "pet", "pets"},
+	{"rid", "rid", "rid", "rideshare"},
+	{"vol", "vol", "vol", "volunteers"},
+
+	// housing
+	{Apartments, Apartments, Apartments, "apartments / housing for rent"},
+	{Rooms, Rooms, Rooms, "rooms & shares"},
+	{Sublets, Sublets, Sublets, "sublets & temporary"},
+	{"rea", "reo", "reb", "real estate for sale"},
+
+	// jobs and gigs
+	{"acc", "acc", "acc", "accounting & finance"},
+	{"ofc", "ofc", "ofc", "admin / office"},
+	{"egr", "egr", "egr", "architect / engineer"},
+	{"med", "med", "med", "art / media / design"},
+	{"bus", "bus", "bus", "business / mgmt"},
+	{"csr", "csr", "csr", "customer service"},
+	{"edu", "edu", "edu", "education / teaching"},
+	{"fbh", "fbh", "fbh", "food / beverage / hospitality"},
+	{"lab", "lab", "lab", "general labor"},
+	{"hea", "hea", "hea", "healthcare"},
+	{"ret", "ret", "ret", "retail / wholesale"},
+	{"sls", "sls", "sls", "sales"},
+	{"sof", "sof", "sof", "software / qa / dba"},
+	{"trd", "trd", "trd", "skilled trades / artisan"},
+	{"trp", "trp", "trp", "transportation"},
+	{"web", "web", "web", "web / html / info design"},
+	{"cpg", "cpg", "cpg", "computer gigs"},
+	{"crg", "crg", "crg", "creative gigs"},
+	{"cwg", "cwg", "cwg", "crew gigs"},
+	{"dmg", "dmg", "dmg", "domestic gigs"},
+	{"evg", "evg", "evg", "event gigs"},
+	{"lbg", "lbg", "lbg", "labor gigs"},
+	{"tlg", "tlg", "tlg", "talent gigs"},
+	{"wrg", "wrg", "wrg", "writing gigs"},
+}
+
+// The category groups whose listings have no price.
+var unpricedGroups = map[Category][]Category{
+	Services:  {"aos", "bts", "cps", "crs", "evs", "hss", "lbs", "lss", "sks"},
+	Community: {"act", "cls", "eve", "com", "grp", "laf", "muc", "pet", "rid", "vol"},
+	Jobs:      {"acc", "ofc", "egr", "med", "bus", "csr", "edu", "fbh", "lab", "hea", "ret", "sls", "sof", "trd", "trp", "web"},
+	Gigs:      {"cpg", "crg", "cwg", "dmg", "evg", "lbg", "tlg", "wrg"},
+}
+
+// unpriced returns true for the services, community, jobs and gigs categories (or groups).
+func unpriced(code string) bool {
+	for group, cats := range unpricedGroups {
+		if Category(code) == group {
+			return true
+		}
+
+		for _, c := range cats {
+			if Category(code) == c {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// sellerCategory returns the owner or dealer variant of a category (i.e. cto for cta and owner),
+// or false if the category doesn't have variants.
+func sellerCategory(c Category, by string) (Category, bool) {
+	for _, info := range categoryTable {
+		if c != info.All || info.Owner == info.All {
+			continue
+		}
+
+		switch by {
+		case "owner":
+			return info.Owner, true
+		case "dealer":
+			return info.Dealer, true
+		}
+	}
+
+	return c, false
+}
+
+// UnknownCategory is the EntryCategory of entries with an unexpected href.
+const UnknownCategory = "unknown"
+
+// categoryFor returns the entry category from the listing href,
+// i.e. "bik" for https://sfbay.craigslist.org/eby/bik/d/oakland-road-bike/7368000000.html
+func categoryFor(href string) string {
+	u, err := url.Parse(href)
+	if err != nil {
+		return UnknownCategory
+	}
+
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(parts) < 2 || !strings.HasSuffix(parts[len(parts)-1], ".html") {
+		return UnknownCategory
+	}
+
+	parts = parts[:len(parts)-1]
+
+	for i, p := range parts {
+		if p == "d" && i > 0 {
+			parts = parts[:i]
+			break
+		}
+	}
+
+	if c := parts[len(parts)-1]; len(c) == 3 {
+		return c
+	}
+
+	return UnknownCategory
+}
+
+// categoryInfo returns the search category ("all" listings) and the name for a category code.
+func categoryInfo(code string) (Category, string) {
+	c := Category(code)
+
+	for _, info := range categoryTable {
+		if c == info.All || c == info.Owner || c == info.Dealer {
+			return info.All, info.Name
+		}
+	}
+
+	return c, code
+}
+
+type CategoryCount struct {
+	Category Category // the search category
+	Name     string
+	Count    int
+	Percent  float64
+}
+
+type CategoryBreakdown struct {
+	Categories []CategoryCount
+	Suggestion string `json:",omitempty"`
+}
+
+// BreakdownByCategory counts the entries in each category, most common first.
+func BreakdownByCategory(entries []ResultEntry) *CategoryBreakdown {
+	counts := map[Category]*CategoryCount{}
+
+	for _, e := range entries {
+		code := e.EntryCategory
+		if code == "" {
+			code = UnknownCategory
+		}
+
+		cat, name := categoryInfo(code)
+
+		if counts[cat] == nil {
+			counts[cat] = &CategoryCount{Category: cat, Name: name}
+		}
+
+		counts[cat].Count++
+	}
+
+	var b CategoryBreakdown
+
+	for _, c := range counts {
+		c.Percent = 100 * float64(c.Count) / float64(len(entries))
+		b.Categories = append(b.Categories, *c)
+	}
+
+	sort.Slice(b.Categories, func(i, j int) bool {
+		if b.Categories[i].Count == b.Categories[j].Count {
+			return b.Categories[i].Category < b.Categories[j].Category
+		}
+
+		return b.Categories[i].Count > b.Categories[j].Count
+	})
+
+	if len(b.Categories) > 1 && b.Categories[0].Category != UnknownCategory {
+		top := b.Categories[0]
+		b.Suggestion = fmt.Sprintf("narrow with -cat %v to skip %.0f%% of noise", top.Category, 100-top.Percent)
+	}
+
+	return &b
+}
+
+// Print writes the breakdown as a table.
+func (b *CategoryBreakdown) Print(w io.Writer) {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "CODE\tCATEGORY\tCOUNT\tPERCENT")
+
+	for _, c := range b.Categories {
+		fmt.Fprintf(tw, "%v\t%v\t%5d\t%6.1f%%\n", c.Category, c.Name, c.Count, c.Percent)
+	}
+
+	tw.Flush()
+
+	if b.Suggestion != "" {
+		fmt.Fprintln(w, b.Suggestion)
+	}
+}
@@ -0,0 +1,186 @@
+package searchcraigs
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseServices(t *testing.T) {
+	var results SearchResults
+	parseResults(loadFixture(t, "search-services.html"), "sfbay", "US", &results)
+
+	want := []struct {
+		category     string
+		meta         string
+		neighborhood string
+		hasPrice     bool
+	}{
+		{"sks", "serving the east bay", "oakland", false},
+		{"lbs", "serving all bay area", "san francisco", false},
+		{"cps", "", "san jose", false},
+		{"eve", "event date: sat 9/21", "berkeley", false},
+		{"rid", "", "palo alto", false},
+		{"zip", "", "mission district", true},
+	}
+
+	if len(results.Entries) != len(want) {
+		t.Fatalf("%v entries, want %v", len(results.Entries), len(want))
+	}
+
+	for i, w := range want {
+		e := results.Entries[i]
+
+		if e.EntryCategory != w.category || e.Meta != w.meta || e.Neighborhood != w.neighborhood || e.HasPrice() != w.hasPrice {
+			t.Errorf("%v: category %q, meta %q, neighborhood %q, has price %v, want %q, %q, %q, %v",
+				e.Title, e.EntryCategory, e.Meta, e.Neighborhood, e.HasPrice(), w.category, w.meta, w.neighborhood, w.hasPrice)
+		}
+
+		if e.Posted.IsZero() {
+			t.Errorf("%v: no posting date", e.Title)
+		}
+	}
+}
+
+func TestUnpriced(t *testing.T) {
+	tests := []struct {
+		code string
+		want bool
+	}{
+		{"bbb", true},
+		{"sks", true},
+		{"lss", true},
+		{"ccc", true},
+		{"act", true},
+		{"rid", true},
+		{"jjj", true},
+		{"sof", true},
+		{"ggg", true},
+		{"sss", false},
+		{"bik", false},
+		{"zip", false},
+		{"apa", false},
+		{UnknownCategory, false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			if got := unpriced(tt.code); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMapCategory(t *testing.T) {
+	tests := []struct {
+		name string
+		want Category
+	}{
+		{"services", Services},
+		{"skilled-trade", SkilledTrade},
+		{"computer-services", ComputerServices},
+		{"lessons", Lessons},
+		{"community", Community},
+		{"activities", Activities},
+		{"rideshare", Rideshare},
+		{"classes", Classes},
+		{"events", Events},
+		{"bikes", Bikes},
+		{"all", ForSale},
+		{"lbs", Category("lbs")}, // codes are used as they are
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mapCategory(tt.name); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCategoryFor(t *testing.T) {
+	tests := []struct {
+		href string
+		want string
+	}{
+		{"https://sfbay.craigslist.org/eby/sks/d/oakland-electrician/7800000001.html", "sks"},
+		{"https://london.craigslist.org/bik/d/london-bike/7702000001.html", "bik"},
+		{"https://sfbay.craigslist.org/eby/bik/7368000000.html", "bik"},
+		{"https://sfbay.craigslist.org/search/sss", UnknownCategory},
+		{"https://sfbay.craigslist.org/", UnknownCategory},
+		{"https://sfbay.craigslist.org/eby/bikes/d/x/1.html", UnknownCategory},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.href, func(t *testing.T) {
+			if got := categoryFor(tt.href); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// the services and community listings are shown with their meta line instead of a price
+func TestTemplateServices(t *testing.T) {
+	var results SearchResults
+	parseResults(loadFixture(t, "search-services.html"), "sfbay", "US", &results)
+
+	for _, layout := range sortedKeys(layouts) {
+		t.Run(layout, func(t *testing.T) {
+			tmpl, err := loadTemplate("", layout)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var b strings.Builder
+			if err := writeHTML(&b, tmpl, &results, true); err != nil {
+				t.Fatal(err)
+			}
+
+			page := b.String()
+
+			if n := strings.Count(page, "$0"); n != 1 {
+				t.Errorf("%v prices, want 1 (only for the free listing)", n)
+			}
+
+			for _, meta := range []string{"serving the east bay", "event date: sat 9/21"} {
+				if !strings.Contains(page, meta) {
+					t.Errorf("%q not found", meta)
+				}
+			}
+		})
+	}
+}
+
+func TestBreakdownServices(t *testing.T) {
+	var results SearchResults
+	parseResults(loadFixture(t, "search-services.html"), "sfbay", "US", &results)
+
+	b := BreakdownByCategory(results.Entries)
+
+	names := map[Category]string{}
+	for _, c := range b.Categories {
+		if c.Count != 1 {
+			t.Errorf("%v: count %v, want 1", c.Category, c.Count)
+		}
+
+		names[c.Category] = c.Name
+	}
+
+	want := map[Category]string{
+		"sks": "skilled trade services",
+		"lbs": "labor & moving",
+		"cps": "computer services",
+		"eve": "events",
+		"rid": "rideshare",
+		"zip": "free stuff",
+	}
+
+	for c, name := range want {
+		if names[c] != name {
+			t.Errorf("%v: name %q, want %q", c, names[c], name)
+		}
+	}
+}
@@ -0,0 +1,326 @@
+package searchcraigs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/gobs/httpclient"
+)
+
+// ErrBlocked is returned when craigslist blocks the requests (rate limiting, or a captcha page).
+var ErrBlocked = errors.New("blocked by craigslist (too many requests?)")
+
+// default base delay between retries
+const defaultBackoff = time.Second
+
+// ClientOption configures a ClClient (see New).
+type ClientOption func(c *ClClient) error
+
+// WithRetries makes the client retry up to n times the requests that fail
+// with 429 (Too Many Requests) or a 5xx status.
+func WithRetries(n int) ClientOption {
+	return func(c *ClClient) error {
+		c.retries = n
+		return nil
+	}
+}
+
+// WithBackoff sets the delay before the first retry. The delay doubles at each retry,
+// plus a random jitter of up to half the delay.
+func WithBackoff(d time.Duration) ClientOption {
+	return func(c *ClClient) error {
+		if d > 0 {
+			c.backoff = d
+		}
+
+		return nil
+	}
+}
+
+// WithTimeout sets the timeout for each request (including reading the response).
+func WithTimeout(d time.Duration) ClientOption {
+	return func(c *ClClient) error {
+		c.h.SetTimeout(d)
+		return nil
+	}
+}
+
+// WithProxy sends the requests through the proxy at proxyURL (http://, https:// or socks5://host:port,
+// with optional user:password). Invalid URLs are reported by New.
+func WithProxy(proxyURL string) ClientOption {
+	return func(c *ClClient) error {
+		if proxyURL == "" {
+			return nil
+		}
+
+		u, err := url.Parse(proxyURL)
+		if err != nil {
+			return fmt.Errorf("invalid proxy: %w", err)
+		}
+
+		switch u.Scheme {
+		case "http", "https", "socks5", "socks5h":
+		default:
+			return fmt.Errorf("invalid proxy %q: the scheme should be http, https or socks5", proxyURL)
+		}
+
+		if u.Host == "" {
+			return fmt.Errorf("invalid proxy %q: missing host", proxyURL)
+		}
+
+		// url.Parse doesn't always check the port (i.e. host:port:8080)
+		if strings.LastIndex(u.Host, ":") > strings.LastIndex(u.Host, "]") {
+			_, port, err := net.SplitHostPort(u.Host)
+			if err != nil {
+				return fmt.Errorf("invalid proxy: %w", err)
+			}
+
+			if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+				return fmt.Errorf("invalid proxy %q: invalid port %q", proxyURL, port)
+			}
+		}
+
+		tr, ok := c.h.GetTransport().(*http.Transport)
+		if !ok {
+			return fmt.Errorf("cannot set the proxy on %T", c.h.GetTransport())
+		}
+
+		tr.Proxy = http.ProxyURL(u)
+		return nil
+	}
+}
+
+// WithUserAgent sets the User-Agent sent with each request (the default is a recent desktop browser).
+func WithUserAgent(ua string) ClientOption {
+	return func(c *ClClient) error {
+		if ua != "" {
+			c.h.UserAgent = ua
+		}
+
+		return nil
+	}
+}
+
+// WithHeader adds a header sent with each request.
+func WithHeader(key, value string) ClientOption {
+	return func(c *ClClient) error {
+		if c.h.Headers == nil {
+			c.h.Headers = map[string]string{}
+		}
+
+		c.h.Headers[key] = value
+		return nil
+	}
+}
+
+// WithRateLimit limits the requests sent by the client (from all goroutines) to requestsPerMinute.
+// 0 means no limit.
+func WithRateLimit(requestsPerMinute int) ClientOption {
+	return func(c *ClClient) error {
+		if requestsPerMinute < 0 {
+			return fmt.Errorf("invalid rate limit %v", requestsPerMinute)
+		}
+
+		if requestsPerMinute > 0 {
+			c.limiter = &rateLimiter{interval: time.Minute / time.Duration(requestsPerMinute)}
+		}
+
+		return nil
+	}
+}
+
+// WithLogger logs the requests (URL, status and time), the rate limiter delays
+// and the number of results parsed in each page, at debug level, and the retries at warn level.
+func WithLogger(l *slog.Logger) ClientOption {
+	return func(c *ClClient) error {
+		c.logger = l
+		return nil
+	}
+}
+
+// WithDumpHTML writes the body of each page fetched to path, before parsing it
+// (to debug the parsing, or to make a test fixture). The following pages are written
+// to path with -2, -3... added before the extension.
+func WithDumpHTML(path string) ClientOption {
+	return func(c *ClClient) error {
+		c.dumpPath = path
+		return nil
+	}
+}
+
+// WithVerbose is WithLogger with a logger writing to stderr (unless WithLogger is used too).
+func WithVerbose(verbose bool) ClientOption {
+	return func(c *ClClient) error {
+		c.verbose = verbose
+		return nil
+	}
+}
+
+// rateLimiter spaces the requests at least interval apart. It's safe for concurrent use.
+type rateLimiter struct {
+	interval time.Duration
+
+	mu   sync.Mutex
+	next time.Time // when the next request can be sent
+}
+
+// wait waits for the next request slot, or until ctx is done.
+// It returns how long it waited.
+func (l *rateLimiter) wait(ctx context.Context) (time.Duration, error) {
+	l.mu.Lock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+
+	delay := l.next.Sub(now)
+	l.next = l.next.Add(l.interval)
+	l.mu.Unlock()
+
+	if delay <= 0 {
+		return 0, nil
+	}
+
+	select {
+	case <-ctx.Done():
+		return delay, ctx.Err()
+	case <-time.After(delay):
+		return delay, nil
+	}
+}
+
+func retryable(status int) bool {
+	return status == http.StatusTooManyRequests || status >= 500
+}
+
+// send sends the request with ctx, after waiting for the rate limiter,
+// retrying with exponential backoff on 429 and 5xx responses.
+// A Retry-After header (in seconds) overrides the computed delay.
+func (c *ClClient) send(ctx context.Context, reqs ...httpclient.RequestOption) (*httpclient.HttpResponse, error) {
+	return c.sendTimeout(ctx, 0, reqs...)
+}
+
+// sendTimeout is send with a timeout (if > 0) for each attempt, including reading the response body.
+// The timeout starts after the rate limiter wait, so that queued requests don't time out before they are sent.
+func (c *ClClient) sendTimeout(ctx context.Context, timeout time.Duration, reqs ...httpclient.RequestOption) (*httpclient.HttpResponse, error) {
+	delay := c.backoff
+	reqs = reqs[:len(reqs):len(reqs)] // the context is appended for each attempt
+
+	for attempt := 0; ; attempt++ {
+		if c.limiter != nil {
+			waited, err := c.limiter.wait(ctx)
+			if err != nil {
+				return nil, err
+			}
+
+			if waited > 0 {
+				c.debug("rate limit", "delay", waited.Round(time.Millisecond))
+			}
+		}
+
+		start := time.Now()
+
+		reqCtx, cancel := ctx, context.CancelFunc(func() {})
+		if timeout > 0 {
+			reqCtx, cancel = context.WithTimeout(ctx, timeout)
+		}
+
+		res, err := c.h.SendRequest(append(reqs, httpclient.Context(reqCtx))...)
+		if err != nil {
+			cancel()
+			c.debug("request failed", "error", err, "time", time.Since(start).Round(time.Millisecond))
+		} else {
+			res.Body = cancelOnClose{ReadCloser: res.Body, cancel: cancel}
+			c.debug("request", "url", res.Request.URL, "status", res.StatusCode, "time", time.Since(start).Round(time.Millisecond))
+		}
+
+		if err != nil || attempt >= c.retries || !retryable(res.StatusCode) {
+			return res, err
+		}
+
+		wait := delay + time.Duration(rand.Int63n(int64(delay)/2+1))
+		if s, err := strconv.Atoi(res.Header.Get("Retry-After")); err == nil && s > 0 {
+			wait = time.Duration(s) * time.Second
+		}
+
+		res.Body.Close()
+
+		c.warn("retrying", "url", res.Request.URL, "status", res.Status, "delay", wait.Round(time.Millisecond))
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(wait):
+		}
+
+		delay *= 2
+	}
+}
+
+// cancelOnClose is a response body that cancels the request context when closed.
+type cancelOnClose struct {
+	io.ReadCloser
+
+	cancel context.CancelFunc
+}
+
+func (b cancelOnClose) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+func (c *ClClient) debug(msg string, args ...any) {
+	if c.logger != nil {
+		c.logger.Debug(msg, args...)
+	}
+}
+
+func (c *ClClient) warn(msg string, args ...any) {
+	if c.logger != nil {
+		c.logger.Warn(msg, args...)
+	}
+}
+
+// readDocument parses the response body (and closes it), writing it to the dump file first if there is one.
+func (c *ClClient) readDocument(res *httpclient.HttpResponse) (*goquery.Document, error) {
+	defer res.Body.Close()
+
+	if c.dumpPath == "" {
+		return goquery.NewDocumentFromReader(res.Body)
+	}
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	path := c.dumpPath
+	if n := c.dumps.Add(1); n > 1 {
+		ext := filepath.Ext(path)
+		path = fmt.Sprintf("%v-%v%v", strings.TrimSuffix(path, ext), n, ext)
+	}
+
+	if err := os.WriteFile(path, body, 0o644); err != nil {
+		c.warn("cannot save the page", "file", path, "error", err)
+	} else {
+		c.debug("page saved", "url", res.Request.URL, "file", path)
+	}
+
+	return goquery.NewDocumentFromReader(bytes.NewReader(body))
+}
@@ -0,0 +1,291 @@
+package searchcraigs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/gobs/httpclient"
+)
+
+const (
+	blockedPage = `<html><body><p>This IP has been automatically blocked.</p><p>If you have questions, please email: blocks-b1@craigslist.org</p></body></html>`
+	captchaPage = `<html><body><form><div class="h-captcha" data-sitekey="x"></div></form></body></html>`
+	noResults   = `<html><body><div class="cl-results-page"><p>no results</p></div></body></html>`
+)
+
+func readTestdata(t *testing.T, name string) string {
+	t.Helper()
+
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(data)
+}
+
+func TestIsBlocked(t *testing.T) {
+	tests := []struct {
+		name string
+		page string
+		want bool
+	}{
+		{"blocked", blockedPage, true},
+		{"captcha", captchaPage, true},
+		{"recaptcha", `<html><body><div class="g-recaptcha"></div></body></html>`, true},
+		{"captcha iframe", `<html><body><iframe src="https://www.google.com/recaptcha/api2/anchor"></iframe></body></html>`, true},
+		{"security check", `<html><body><h1>Please complete the security check to access craigslist</h1></body></html>`, true},
+		{"no results", noResults, false},
+		{"results", readTestdata(t, "search-gallery.html"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := goquery.NewDocumentFromReader(strings.NewReader(tt.page))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := isBlocked(doc); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFetchBlocked(t *testing.T) {
+	tests := []struct {
+		name string
+		res  testResponse
+		rows int
+		err  error
+	}{
+		{"results", testResponse{status: 200, body: readTestdata(t, "search-gallery.html")}, 4, nil},
+		{"no results", testResponse{status: 200, body: noResults}, 0, nil},
+		{"block page", testResponse{status: 200, body: blockedPage}, 0, ErrBlocked},
+		{"captcha page", testResponse{status: 200, body: captchaPage}, 0, ErrBlocked},
+		{"forbidden", testResponse{status: 403, body: blockedPage}, 0, ErrBlocked},
+		{"too many requests", testResponse{status: 429}, 0, ErrBlocked},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t, responses(tt.res))
+			c := s.client(t)
+
+			var results SearchResults
+
+			rows, err := c.fetch(context.Background(), &results, httpclient.URLString(s.URL))
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("got error %v, want %v", err, tt.err)
+			}
+
+			if rows != tt.rows || len(results.Entries) != tt.rows {
+				t.Errorf("%v rows, %v entries, want %v", rows, len(results.Entries), tt.rows)
+			}
+		})
+	}
+}
+
+// errNotFound stands for the error returned for a 404 status
+var errNotFound = errors.New("not found")
+
+func TestRetry(t *testing.T) {
+	page := testResponse{status: 200, body: readTestdata(t, "search-gallery.html")}
+	tooMany := testResponse{status: 429, body: "slow down"}
+	unavailable := testResponse{status: 503, body: "try later"}
+
+	tests := []struct {
+		name      string
+		retries   int
+		responses []testResponse
+		requests  int
+		err       error // ErrBlocked, or any error for errNotFound
+	}{
+		{"no retries", 0, []testResponse{tooMany, page}, 1, ErrBlocked},
+		{"429", 3, []testResponse{tooMany, tooMany, page}, 3, nil},
+		{"503", 3, []testResponse{unavailable, page}, 2, nil},
+		{"too many retries", 2, []testResponse{tooMany}, 3, ErrBlocked},
+		{"not retryable", 3, []testResponse{{status: 404}, page}, 1, errNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t, responses(tt.responses...))
+			c := s.client(t, WithRetries(tt.retries), WithBackoff(10*time.Millisecond))
+
+			var results SearchResults
+
+			_, err := c.fetch(context.Background(), &results, httpclient.URLString(s.URL))
+
+			if tt.err == errNotFound {
+				if err == nil || errors.Is(err, ErrBlocked) {
+					t.Errorf("got error %v, want the 404 status", err)
+				}
+			} else if !errors.Is(err, tt.err) {
+				t.Errorf("got error %v, want %v", err, tt.err)
+			}
+
+			if n := len(s.received()); n != tt.requests {
+				t.Errorf("%v requests, want %v", n, tt.requests)
+			}
+
+			if tt.err == nil && err == nil && len(results.Entries) != 4 {
+				t.Errorf("%v entries, want 4", len(results.Entries))
+			}
+		})
+	}
+}
+
+// the delay before each retry doubles, and Retry-After overrides it
+func TestRetryBackoff(t *testing.T) {
+	const backoff = 20 * time.Millisecond
+
+	s := newTestServer(t, responses(testResponse{status: 429}, testResponse{status: 429}, testResponse{status: 429},
+		testResponse{status: 200, body: noResults}))
+
+	c := s.client(t, WithRetries(3), WithBackoff(backoff))
+
+	if _, err := c.fetch(context.Background(), &SearchResults{}, httpclient.URLString(s.URL)); err != nil {
+		t.Fatal(err)
+	}
+
+	requests := s.received()
+	if len(requests) != 4 {
+		t.Fatalf("%v requests, want 4", len(requests))
+	}
+
+	for i, want := 1, backoff; i < len(requests); i, want = i+1, want*2 {
+		// the delay has a jitter of up to half the delay
+		if d := requests[i].time.Sub(requests[i-1].time); d < want || d > want*3/2+100*time.Millisecond {
+			t.Errorf("retry %v after %v, want %v-%v", i, d, want, want*3/2)
+		}
+	}
+
+	s = newTestServer(t, responses(testResponse{status: 503, header: map[string]string{"Retry-After": "1"}},
+		testResponse{status: 200, body: noResults}))
+	c = s.client(t, WithRetries(3), WithBackoff(backoff))
+
+	if _, err := c.fetch(context.Background(), &SearchResults{}, httpclient.URLString(s.URL)); err != nil {
+		t.Fatal(err)
+	}
+
+	if requests := s.received(); len(requests) != 2 {
+		t.Errorf("%v requests, want 2", len(requests))
+	} else if d := requests[1].time.Sub(requests[0].time); d < time.Second {
+		t.Errorf("retry after %v, want at least the 1s in Retry-After", d)
+	}
+}
+
+// the retries are logged with the client logger, at warn level
+func TestRetryLogger(t *testing.T) {
+	s := newTestServer(t, responses(testResponse{status: 503}, testResponse{status: 200, body: noResults}))
+
+	var b bytes.Buffer
+	c := s.client(t, WithRetries(1), WithBackoff(time.Millisecond),
+		WithLogger(slog.New(slog.NewTextHandler(&b, &slog.HandlerOptions{Level: slog.LevelWarn}))))
+
+	if _, err := c.fetch(context.Background(), &SearchResults{}, httpclient.URLString(s.URL)); err != nil {
+		t.Fatal(err)
+	}
+
+	if out := b.String(); !strings.Contains(out, "level=WARN msg=retrying") || !strings.Contains(out, "503") {
+		t.Errorf("log %q", out)
+	}
+}
+
+// a cancelled context stops the retries
+func TestRetryCancel(t *testing.T) {
+	s := newTestServer(t, responses(testResponse{status: 429}))
+	c := s.client(t, WithRetries(5), WithBackoff(time.Minute))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+
+	if _, err := c.fetch(ctx, &SearchResults{}, httpclient.URLString(s.URL)); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got error %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	if d := time.Since(start); d > 10*time.Second {
+		t.Errorf("the retry was not cancelled (%v)", d)
+	}
+
+	if n := len(s.received()); n != 1 {
+		t.Errorf("%v requests, want 1", n)
+	}
+}
+
+// concurrent requests are spaced at least interval apart
+func TestRateLimiter(t *testing.T) {
+	const interval = 20 * time.Millisecond
+	const n = 8
+
+	l := &rateLimiter{interval: interval}
+	start := time.Now()
+
+	var mu sync.Mutex
+	var sent []time.Duration
+
+	var wg sync.WaitGroup
+	for range n {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			if _, err := l.wait(context.Background()); err != nil {
+				t.Error(err)
+			}
+
+			mu.Lock()
+			sent = append(sent, time.Since(start))
+			mu.Unlock()
+		}()
+	}
+
+	wg.Wait()
+	slices.Sort(sent)
+
+	for i, d := range sent {
+		// the first slot starts when the first goroutine calls wait, a bit after start
+		if d < time.Duration(i)*interval {
+			t.Errorf("request %v sent after %v, want at least %v", i, d, time.Duration(i)*interval)
+		}
+	}
+}
+
+func TestRateLimiterCancel(t *testing.T) {
+	l := &rateLimiter{interval: time.Hour}
+
+	if d, err := l.wait(context.Background()); d != 0 || err != nil {
+		t.Fatalf("first request: waited %v, %v", d, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	if _, err := l.wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got %v, want context.DeadlineExceeded", err)
+	}
+
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("returned after %v", d)
+	}
+
+	if _, err := l.wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("done context: got %v, want context.DeadlineExceeded", err)
+	}
+}
@@ -0,0 +1,8 @@
+// The searchcraigs command searches craigslist (see the README for the options).
+package main
+
+import "github.com/raff/searchcraigs"
+
+func main() {
+	searchcraigs.Main()
+}
@@ -0,0 +1,109 @@
+package searchcraigs
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// DefaultFields are the columns written by WriteCSV when no fields are specified.
+var DefaultFields = []string{"title", "price", "datetime", "neighborhood", "nearby", "href", "image"}
+
+// csvFields maps the column names to the entry values
+var csvFields = map[string]func(e *ResultEntry) string{
+	"title":        func(e *ResultEntry) string { return e.Title },
+	"price":        func(e *ResultEntry) string { return e.Price },
+	"pricevalue":   func(e *ResultEntry) string { return strconv.Itoa(e.PriceValue) },
+	"currency":     func(e *ResultEntry) string { return e.Currency },
+	"datetime":     func(e *ResultEntry) string { return e.Datetime },
+	"neighborhood": func(e *ResultEntry) string { return e.Neighborhood },
+	"nearby":       func(e *ResultEntry) string { return e.NearbyDesc },
+	"href":         func(e *ResultEntry) string { return e.Href },
+	"image":        func(e *ResultEntry) string { return e.Image },
+	"region":       func(e *ResultEntry) string { return e.Region },
+	"category":     func(e *ResultEntry) string { return e.EntryCategory },
+	"meta":         func(e *ResultEntry) string { return e.Meta },
+	"extras":       func(e *ResultEntry) string { return e.Extras },
+	"distance":     func(e *ResultEntry) string { return strconv.FormatFloat(e.Distance, 'f', 1, 64) },
+	"new":          func(e *ResultEntry) string { return strconv.FormatBool(e.FirstSeenThisRun) },
+
+	"schema_version": func(e *ResultEntry) string { return strconv.Itoa(SchemaVersion) },
+}
+
+// ParseFields parses a comma separated list of column names, checking that they are valid.
+func ParseFields(s string) ([]string, error) {
+	if s == "" {
+		return DefaultFields, nil
+	}
+
+	var fields []string
+
+	for _, f := range strings.Split(s, ",") {
+		f = strings.ToLower(strings.TrimSpace(f))
+		if _, ok := csvFields[f]; !ok {
+			return nil, fmt.Errorf("unknown field %q", f)
+		}
+
+		fields = append(fields, f)
+	}
+
+	return fields, nil
+}
+
+// WriteCSV writes the entries as CSV, with a header line, one row per entry.
+// fields selects the columns (DefaultFields if empty). The last column is always
+// schema_version (see SchemaVersion), if not already selected.
+func WriteCSV(w io.Writer, entries []ResultEntry, fields []string) error {
+	return writeDelimited(w, entries, fields, ',')
+}
+
+// WriteTSV is like WriteCSV, with tab separated columns.
+func WriteTSV(w io.Writer, entries []ResultEntry, fields []string) error {
+	return writeDelimited(w, entries, fields, '\t')
+}
+
+func writeDelimited(w io.Writer, entries []ResultEntry, fields []string, comma rune) error {
+	if len(fields) == 0 {
+		fields = DefaultFields
+	}
+
+	if !slices.Contains(fields, "schema_version") {
+		fields = append(fields[:len(fields):len(fields)], "schema_version")
+	}
+
+	values := make([]func(e *ResultEntry) string, len(fields))
+
+	for i, f := range fields {
+		v, ok := csvFields[f]
+		if !ok {
+			return fmt.Errorf("unknown field %q", f)
+		}
+
+		values[i] = v
+	}
+
+	cw := csv.NewWriter(w)
+	cw.Comma = comma
+
+	if err := cw.Write(fields); err != nil {
+		return err
+	}
+
+	row := make([]string, len(fields))
+
+	for i := range entries {
+		for j, v := range values {
+			row[j] = v(&entries[i])
+		}
+
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,39 @@
+package searchcraigs
+
+import (
+	"fmt"
+	"time"
+)
+
+// DedupStore persists the hashes of the entries already returned, so that the same listing
+// (or a re-post of it) is filtered out on subsequent runs. It's a SeenState with different keys.
+//
+// By default an entry is a duplicate if an entry with the same Hash was seen before,
+// even if it's a different posting. With ByHref set it's only a duplicate
+// if it also has the same Href.
+type DedupStore struct {
+	*SeenState
+
+	ByHref bool
+}
+
+// LoadDedupStore loads the store from path (a missing file is an empty store),
+// removing the entries not seen for longer than ttl (if ttl > 0).
+func LoadDedupStore(path string, ttl time.Duration) (*DedupStore, error) {
+	state, err := LoadSeenState(path, ttl)
+	if err != nil {
+		return nil, err
+	}
+
+	store := &DedupStore{SeenState: state}
+	state.keys = store.keys
+	return store, nil
+}
+
+func (s *DedupStore) keys(entry ResultEntry) []string {
+	if s.ByHref {
+		return []string{fmt.Sprintf("%016x %v", entry.Hash(), entry.Href)}
+	}
+
+	return []string{fmt.Sprintf("%016x", entry.Hash())}
+}
@@ -0,0 +1,119 @@
+package searchcraigs
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func dedupTitles(entries []ResultEntry) []string {
+	var titles []string
+	for _, e := range entries {
+		titles = append(titles, e.Title+" "+e.Href)
+	}
+
+	return titles
+}
+
+func TestDedupStore(t *testing.T) {
+	bike := ResultEntry{Title: "road bike", PriceValue: 500, Href: "https://sfbay.craigslist.org/1.html"}
+	repost := ResultEntry{Title: "Road Bike", PriceValue: 500, Href: "https://sfbay.craigslist.org/2.html"}
+	other := ResultEntry{Title: "canoe", PriceValue: 900, Href: "https://sfbay.craigslist.org/3.html"}
+
+	tests := []struct {
+		name   string
+		byHref bool
+		want   [][]ResultEntry // entries returned by each run, on [bike, other] then [repost, bike, other]
+	}{
+		{"hash", false, [][]ResultEntry{{bike, other}, nil}},
+		{"href", true, [][]ResultEntry{{bike, other}, {repost}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "dedup.json")
+
+			for run, entries := range [][]ResultEntry{{bike, other}, {repost, bike, other}} {
+				store, err := LoadDedupStore(path, time.Hour)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				store.ByHref = tt.byHref
+
+				got := dedupTitles(store.Filter(entries))
+				if want := dedupTitles(tt.want[run]); !slices.Equal(got, want) {
+					t.Errorf("run %v: Filter %q, want %q", run+1, got, want)
+				}
+
+				if err := store.Save(); err != nil {
+					t.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestDedupStoreTTL(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dedup.json")
+	bike := ResultEntry{Title: "road bike", PriceValue: 500}
+	canoe := ResultEntry{Title: "canoe", PriceValue: 900}
+
+	// the format written by previous versions: hash keys and no watermarks
+	data, err := json.Marshal(map[string]any{"seen": map[string]time.Time{
+		(&DedupStore{}).keys(bike)[0]:  time.Now().Add(-2 * time.Hour),
+		(&DedupStore{}).keys(canoe)[0]: time.Now(),
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	store, err := LoadDedupStore(path, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := dedupTitles(store.Filter([]ResultEntry{bike, canoe}))
+	if len(got) != 1 || got[0] != "road bike " {
+		t.Errorf("Filter %q, want the expired road bike only", got)
+	}
+}
+
+func TestDedupStoreLimit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dedup.json")
+
+	var entries []ResultEntry
+	for i := range 5 {
+		entries = append(entries, ResultEntry{Title: fmt.Sprintf("bike %v", i), Href: fmt.Sprintf("https://sfbay.craigslist.org/%v.html", i)})
+	}
+
+	// -dedupfile with -limit 2, as in Main
+	want := [][]ResultEntry{entries[0:2], entries[2:4], entries[4:5], nil}
+
+	for run := range want {
+		store, err := LoadDedupStore(path, time.Hour)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		res := SearchResults{Entries: store.Unseen(entries)}
+		res.Limit(2)
+		store.Mark(res.Entries)
+
+		if err := store.Save(); err != nil {
+			t.Fatal(err)
+		}
+
+		if got, want := dedupTitles(res.Entries), dedupTitles(want[run]); !slices.Equal(got, want) {
+			t.Errorf("run %v: %q, want %q", run+1, got, want)
+		}
+	}
+}
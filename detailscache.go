@@ -0,0 +1,229 @@
+package searchcraigs
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// DetailsCache stores the listings and the images already fetched, so that an interrupted run
+// with -details or -embed-images only fetches the remaining ones when it's restarted.
+// Each listing or image is recorded in a journal file (path.journal) as soon as it's fetched,
+// Save moves them to the cache file.
+type DetailsCache struct {
+	Listings map[string]CachedListing `json:"listings"`         // href -> listing
+	Images   map[string]CachedImage   `json:"images,omitempty"` // image URL -> data URI
+
+	path    string
+	mu      sync.Mutex
+	journal *os.File
+}
+
+// CachedListing is a listing and the time it was fetched.
+type CachedListing struct {
+	Fetched time.Time
+	Listing *Listing
+}
+
+// CachedImage is an image downloaded by EmbedImages and the time it was fetched.
+type CachedImage struct {
+	Fetched time.Time
+	Data    string // the data: URI
+}
+
+// journalEntry is a line of the journal file: a listing or an image.
+type journalEntry struct {
+	Href    string    `json:"href,omitempty"`
+	Listing *Listing  `json:"listing,omitempty"`
+	Image   string    `json:"image,omitempty"`
+	Data    string    `json:"data,omitempty"`
+	Fetched time.Time `json:"fetched"`
+}
+
+// WithDetailsCache makes GetDetails and EmbedImages use the listings and images in cache, and add
+// the ones they fetch (the caller saves it, see DetailsCache.Save).
+func WithDetailsCache(cache *DetailsCache) ClientOption {
+	return func(c *ClClient) error {
+		c.detailsCache = cache
+		return nil
+	}
+}
+
+// LoadDetailsCache loads the cache from path (a missing file is an empty cache), and the
+// entries recorded in the journal by an interrupted run, removing the listings and images
+// fetched more than ttl ago (if ttl > 0).
+func LoadDetailsCache(path string, ttl time.Duration) (*DetailsCache, error) {
+	cache := DetailsCache{path: path}
+
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	if err == nil {
+		if err := json.Unmarshal(data, &cache); err != nil {
+			return nil, fmt.Errorf("%v: %w", path, err)
+		}
+	}
+
+	if cache.Listings == nil {
+		cache.Listings = map[string]CachedListing{}
+	}
+
+	if cache.Images == nil {
+		cache.Images = map[string]CachedImage{}
+	}
+
+	if err := cache.replay(); err != nil {
+		return nil, err
+	}
+
+	if ttl > 0 {
+		expire := time.Now().Add(-ttl)
+
+		for href, l := range cache.Listings {
+			if l.Fetched.Before(expire) {
+				delete(cache.Listings, href)
+			}
+		}
+
+		for url, img := range cache.Images {
+			if img.Fetched.Before(expire) {
+				delete(cache.Images, url)
+			}
+		}
+	}
+
+	return &cache, nil
+}
+
+func (c *DetailsCache) journalPath() string {
+	return c.path + ".journal"
+}
+
+// replay adds the entries in the journal. The last line can be incomplete if the run was killed
+// while writing it, and is ignored.
+func (c *DetailsCache) replay() error {
+	f, err := os.Open(c.journalPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 16*1024*1024) // the images are up to -max-embed-size, base64 encoded
+
+	for scanner.Scan() {
+		var e journalEntry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			continue
+		}
+
+		switch {
+		case e.Href != "" && e.Listing != nil:
+			c.Listings[e.Href] = CachedListing{Fetched: e.Fetched, Listing: e.Listing}
+		case e.Image != "":
+			c.Images[e.Image] = CachedImage{Fetched: e.Fetched, Data: e.Data}
+		}
+	}
+
+	return scanner.Err()
+}
+
+// Apply sets the Details of the entries that are in the cache, returning how many were found.
+func (c *DetailsCache) Apply(entries []ResultEntry) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	found := 0
+
+	for i, e := range entries {
+		if l, ok := c.Listings[e.Href]; ok && e.Details == nil {
+			entries[i].Details = l.Listing
+			found++
+		}
+	}
+
+	return found
+}
+
+// AddListing adds a listing just fetched, recording it in the journal.
+func (c *DetailsCache) AddListing(href string, listing *Listing) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	c.Listings[href] = CachedListing{Fetched: now, Listing: listing}
+	return c.record(journalEntry{Href: href, Listing: listing, Fetched: now})
+}
+
+// Image returns the cached image at url.
+func (c *DetailsCache) Image(url string) (CachedImage, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	img, ok := c.Images[url]
+	return img, ok
+}
+
+// AddImage adds an image just downloaded (data is the data: URI), recording it in the journal.
+func (c *DetailsCache) AddImage(url, data string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	c.Images[url] = CachedImage{Fetched: now, Data: data}
+	return c.record(journalEntry{Image: url, Data: data, Fetched: now})
+}
+
+// record appends e to the journal, opening it if needed.
+func (c *DetailsCache) record(e journalEntry) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	if c.journal == nil {
+		if c.journal, err = os.OpenFile(c.journalPath(), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644); err != nil {
+			return err
+		}
+	}
+
+	_, err = c.journal.Write(append(data, '\n'))
+	return err
+}
+
+// Save writes the cache to its file, and removes the journal. The file is replaced atomically,
+// so an interrupted run leaves the previous version (and the journal).
+func (c *DetailsCache) Save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	data, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+
+	if err := writeFileAtomic(c.path, data); err != nil {
+		return err
+	}
+
+	if c.journal != nil {
+		c.journal.Close()
+		c.journal = nil
+	}
+
+	if err := os.Remove(c.journalPath()); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,262 @@
+package searchcraigs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// listings serves a listing page for any path, and an image for the .jpg paths. After stopAfter
+// requests (if > 0) it calls interrupt instead, and waits for the client to give up.
+type listings struct {
+	mu        sync.Mutex
+	served    int
+	stopAfter int
+	interrupt func()
+}
+
+func (l *listings) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	l.mu.Lock()
+	stop := l.stopAfter > 0 && l.served >= l.stopAfter
+	if !stop {
+		l.served++
+	}
+	l.mu.Unlock()
+
+	if stop {
+		l.interrupt()
+		<-r.Context().Done()
+		return
+	}
+
+	if strings.HasSuffix(r.URL.Path, ".jpg") {
+		fmt.Fprintf(w, "\x89PNG\r\n\x1a\nimage %v", r.URL.Path)
+		return
+	}
+
+	fmt.Fprintf(w, `<html><body><h1><span id="titletextonly">listing %v</span></h1><section id="postingbody">description of %v</section></body></html>`,
+		r.URL.Path, r.URL.Path)
+}
+
+// fetched returns the number of requests for each URL from the user agent
+// (late requests from an interrupted run are not counted for the next one).
+func fetched(s *testServer, agent string) map[string]int {
+	counts := map[string]int{}
+	for _, r := range s.received() {
+		if r.userAgent == agent {
+			counts["https://"+r.url]++
+		}
+	}
+
+	return counts
+}
+
+// an interrupted run records the listings and images fetched, and the next run fetches only the other ones
+func TestDetailsCacheResume(t *testing.T) {
+	const n = 20
+
+	tests := []struct {
+		name   string
+		run    func(ctx context.Context, c *ClClient, entries []ResultEntry) error
+		url    func(e ResultEntry) string // the URL fetched for the entry
+		done   func(e ResultEntry) bool
+		cached func(cache *DetailsCache) int
+	}{
+		{
+			name: "details",
+			run: func(ctx context.Context, c *ClClient, entries []ResultEntry) error {
+				return c.GetDetailsContext(ctx, entries, 0)
+			},
+			url:    func(e ResultEntry) string { return e.Href },
+			done:   func(e ResultEntry) bool { return e.Details != nil },
+			cached: func(cache *DetailsCache) int { return len(cache.Listings) },
+		},
+		{
+			name: "images",
+			run: func(ctx context.Context, c *ClClient, entries []ResultEntry) error {
+				return c.EmbedImagesContext(ctx, entries, 1024)
+			},
+			url: func(e ResultEntry) string {
+				return strings.Replace(e.Href, "sfbay.craigslist.org", "images.craigslist.org", 1) + ".jpg"
+			},
+			done:   func(e ResultEntry) bool { return strings.HasPrefix(e.Image, "data:image/png;base64,") },
+			cached: func(cache *DetailsCache) int { return len(cache.Images) },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := &listings{}
+			s := newTestServer(t, l)
+			path := filepath.Join(t.TempDir(), "details.json")
+
+			newEntries := func() []ResultEntry {
+				entries := make([]ResultEntry, n)
+				for i := range entries {
+					entries[i] = ResultEntry{Title: fmt.Sprint("listing ", i), Href: fmt.Sprintf("https://sfbay.craigslist.org/%v", i)}
+					entries[i].Image = tt.url(entries[i])
+				}
+				return entries
+			}
+
+			run := func(ctx context.Context, agent string, entries []ResultEntry) (*DetailsCache, error) {
+				cache, err := LoadDetailsCache(path, time.Hour)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				return cache, tt.run(ctx, s.client(t, WithUserAgent(agent), WithDetailsCache(cache)), entries)
+			}
+
+			// first run, killed after 7 requests: the cache is not saved
+			ctx, cancel := context.WithCancel(context.Background())
+			l.stopAfter, l.interrupt = 7, cancel
+
+			entries := newEntries()
+
+			killed, err := run(ctx, "run 1", entries)
+			if !errors.Is(err, context.Canceled) {
+				t.Fatalf("got %v, want %v", err, context.Canceled)
+			}
+
+			t.Cleanup(func() {
+				if killed.journal != nil {
+					killed.journal.Close()
+				}
+			})
+
+			first := map[string]bool{}
+			for _, e := range entries {
+				if tt.done(e) {
+					first[tt.url(e)] = true
+				}
+			}
+
+			// a response being read when the run is interrupted is lost
+			if len(first) == 0 || len(first) > 7 {
+				t.Fatalf("%v fetched by the interrupted run, want 1-7", len(first))
+			}
+
+			if _, err := os.Stat(path + ".journal"); err != nil {
+				t.Fatalf("no journal: %v", err)
+			}
+
+			// second run, with new entries (as from a new search)
+			l.mu.Lock()
+			l.stopAfter = 0
+			l.mu.Unlock()
+
+			entries = newEntries()
+
+			cache, err := run(context.Background(), "run 2", entries)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			second := fetched(s, "run 2")
+
+			for _, e := range entries {
+				u := tt.url(e)
+
+				if !tt.done(e) {
+					t.Errorf("%v: not fetched after the second run", u)
+					continue
+				}
+
+				switch {
+				case first[u] && second[u] > 0:
+					t.Errorf("%v was fetched again", u)
+				case !first[u] && second[u] != 1:
+					t.Errorf("%v was fetched %v times by the second run, want 1", u, second[u])
+				}
+			}
+
+			if total := len(second); total != n-len(first) {
+				t.Errorf("the second run fetched %v, want %v", total, n-len(first))
+			}
+
+			// everything is cached now, and the journal removed
+			if err := cache.Save(); err != nil {
+				t.Fatal(err)
+			}
+
+			files, _ := filepath.Glob(filepath.Join(filepath.Dir(path), "*"))
+			if len(files) != 1 {
+				t.Errorf("files left in the cache directory: %v", files)
+			}
+
+			if cache, err = LoadDetailsCache(path, time.Hour); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := tt.cached(cache); got != n {
+				t.Errorf("%v cached, want %v", got, n)
+			}
+		})
+	}
+}
+
+func TestDetailsCacheTTL(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "details.json")
+
+	old := &DetailsCache{Listings: map[string]CachedListing{
+		"https://sfbay.craigslist.org/1.html": {Fetched: time.Now().Add(-48 * time.Hour), Listing: &Listing{Title: "old"}},
+		"https://sfbay.craigslist.org/2.html": {Fetched: time.Now().Add(-time.Hour), Listing: &Listing{Title: "recent"}},
+	}}
+
+	data, err := json.Marshal(old)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		ttl  time.Duration
+		want int
+	}{
+		{0, 2},
+		{24 * time.Hour, 1},
+		{time.Minute, 0},
+	}
+
+	for _, tt := range tests {
+		cache, err := LoadDetailsCache(path, tt.ttl)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(cache.Listings) != tt.want {
+			t.Errorf("ttl %v: %v listings, want %v", tt.ttl, len(cache.Listings), tt.want)
+		}
+	}
+
+	entries := []ResultEntry{{Href: "https://sfbay.craigslist.org/2.html"}, {Href: "https://sfbay.craigslist.org/3.html"}}
+
+	cache, _ := LoadDetailsCache(path, 24*time.Hour)
+	if found := cache.Apply(entries); found != 1 || entries[0].Details == nil || entries[0].Details.Title != "recent" {
+		t.Errorf("applied %v: %+v", found, entries)
+	}
+
+	if cache, err := LoadDetailsCache(filepath.Join(t.TempDir(), "missing.json"), 0); err != nil || len(cache.Listings) != 0 {
+		t.Errorf("missing file: %v, %v", cache, err)
+	}
+
+	if err := os.WriteFile(path, []byte("{"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := LoadDetailsCache(path, 0); err == nil {
+		t.Error("no error for an invalid cache file")
+	}
+}
@@ -0,0 +1,130 @@
+package searchcraigs
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// parsePrice returns the price in whole currency units (the cents are dropped) for strings like "$1,250",
+// "CA$1,200.50", "£50", "€1.200" or "1.234,50 €" (see priceCurrency for the currency).
+// With both "," and "." the last one is the decimal separator. With only one of them, it separates
+// the thousands if it's followed by groups of 3 digits, the decimals if it's followed by 1 or 2 digits,
+// and otherwise it's the decimal separator of the currency (see decimalSeparator).
+// Free or missing prices (and anything we can't parse) are 0.
+func parsePrice(price, currency string) int {
+	price = strings.TrimSpace(price)
+
+	for _, cs := range currencySymbols {
+		price = strings.TrimPrefix(price, cs.symbol)
+		price = strings.TrimSuffix(price, cs.symbol)
+	}
+
+	// spaces (and non-breaking spaces) and apostrophes are only used to group the thousands
+	price = strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '\u00a0', '\u202f', '\'':
+			return -1
+		}
+
+		return r
+	}, price)
+
+	integer := price
+
+	if i := strings.LastIndexAny(price, ".,"); i >= 0 {
+		sep, decimals := price[i], price[i+1:]
+
+		switch {
+		case strings.ContainsAny(price[:i], ".,") && !strings.Contains(price[:i], string(sep)): // "1.234,50"
+			integer = price[:i]
+		case strings.Count(price, string(sep)) > 1 || len(decimals) == 3:
+			integer = price
+		case len(decimals) == 1 || len(decimals) == 2 || sep == decimalSeparator(currency):
+			integer = price[:i]
+		}
+
+		integer = strings.NewReplacer(".", "", ",", "").Replace(integer)
+	}
+
+	v, err := strconv.Atoi(integer)
+	if err != nil {
+		return 0
+	}
+
+	return v
+}
+
+// parseDatetime parses the result datetime ("2021-08-20 13:45"), or a numeric date
+// in the order used in the country (see parseLocalDate).
+// Datetimes without a timezone are in local time. Invalid datetimes return the zero time.
+func parseDatetime(datetime, country string) time.Time {
+	for _, layout := range []string{"2006-01-02 15:04", "2006-01-02 15:04:05", time.RFC3339} {
+		if t, err := time.ParseInLocation(layout, strings.TrimSpace(datetime), time.Local); err == nil {
+			return t
+		}
+	}
+
+	if t, ok := parseLocalDate(datetime, country); ok {
+		return t
+	}
+
+	return time.Time{}
+}
+
+// SortEntries sorts the entries in place by price (PriceAsc, PriceDesc) or date (Date, most recent first).
+// Other sort types leave the entries as they are.
+func SortEntries(entries []ResultEntry, by SortType) {
+	var less func(a, b *ResultEntry) bool
+
+	switch by {
+	case PriceAsc:
+		less = func(a, b *ResultEntry) bool { return a.PriceValue < b.PriceValue }
+
+	case PriceDesc:
+		less = func(a, b *ResultEntry) bool { return a.PriceValue > b.PriceValue }
+
+	case Date:
+		less = func(a, b *ResultEntry) bool { return a.Posted.After(b.Posted) }
+
+	default:
+		return
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return less(&entries[i], &entries[j])
+	})
+}
+
+// FilterPrice returns the entries with a price between min and max (inclusive).
+// A max of 0 means no upper limit.
+func FilterPrice(entries []ResultEntry, min, max int) []ResultEntry {
+	out := make([]ResultEntry, 0, len(entries))
+
+	for _, e := range entries {
+		if e.PriceValue < min || (max > 0 && e.PriceValue > max) {
+			continue
+		}
+
+		out = append(out, e)
+	}
+
+	return out
+}
+
+// FilterNearby returns the entries that are not from a nearby area (see NearbyResults).
+// The entries are removed, not changed, so their Hash is the same as in the searches that keep them.
+func FilterNearby(entries []ResultEntry) []ResultEntry {
+	out := make([]ResultEntry, 0, len(entries))
+
+	for _, e := range entries {
+		if e.NearbyLoc != "" || e.NearbyDesc != "" {
+			continue
+		}
+
+		out = append(out, e)
+	}
+
+	return out
+}
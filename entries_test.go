@@ -0,0 +1,101 @@
+package searchcraigs
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func entryTitles(entries []ResultEntry) []string {
+	var titles []string
+	for _, e := range entries {
+		titles = append(titles, e.Title)
+	}
+
+	return titles
+}
+
+func TestSortEntries(t *testing.T) {
+	day := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	entries := []ResultEntry{
+		{Title: "b", PriceValue: 200, Posted: day},
+		{Title: "free", PriceValue: 0, Posted: day.Add(time.Hour)},
+		{Title: "a", PriceValue: 100, Posted: day.Add(-time.Hour)},
+		{Title: "c", PriceValue: 200, Posted: day.Add(2 * time.Hour)},
+		{Title: "undated", PriceValue: 50},
+	}
+
+	tests := []struct {
+		by   SortType
+		want []string
+	}{
+		{PriceAsc, []string{"free", "undated", "a", "b", "c"}},
+		{PriceDesc, []string{"b", "c", "a", "undated", "free"}}, // same price: the original order
+		{Date, []string{"c", "free", "b", "a", "undated"}},
+		{Relevance, []string{"b", "free", "a", "c", "undated"}},
+		{"", []string{"b", "free", "a", "c", "undated"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.by), func(t *testing.T) {
+			sorted := slices.Clone(entries)
+			SortEntries(sorted, tt.by)
+
+			if got := entryTitles(sorted); !slices.Equal(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterPrice(t *testing.T) {
+	entries := []ResultEntry{
+		{Title: "free", Price: "free", PriceValue: 0},
+		{Title: "no price", PriceValue: 0},
+		{Title: "cheap", Price: "$50", PriceValue: 50},
+		{Title: "mid", Price: "$1,250", PriceValue: 1250},
+		{Title: "expensive", Price: "$5,000", PriceValue: 5000},
+	}
+
+	tests := []struct {
+		name     string
+		min, max int
+		want     []string
+	}{
+		{"no bounds", 0, 0, []string{"free", "no price", "cheap", "mid", "expensive"}},
+		{"min only", 50, 0, []string{"cheap", "mid", "expensive"}},
+		{"max only", 0, 1250, []string{"free", "no price", "cheap", "mid"}},
+		{"min and max", 51, 4999, []string{"mid"}},
+		{"inclusive", 50, 50, []string{"cheap"}},
+		{"none", 6000, 0, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := entryTitles(FilterPrice(entries, tt.min, tt.max)); !slices.Equal(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHash(t *testing.T) {
+	bike := ResultEntry{Title: "Road bike", Price: "$500", PriceValue: 500, Posted: time.Now(), Href: "https://sfbay.craigslist.org/1.html"}
+
+	repost := bike
+	repost.Title = "road bike"
+	repost.Posted = bike.Posted.AddDate(0, 0, -7)
+	repost.Href = "https://sfbay.craigslist.org/2.html"
+
+	if bike.Hash() != repost.Hash() {
+		t.Error("a re-post has a different hash")
+	}
+
+	cheaper := bike
+	cheaper.PriceValue = 450
+
+	if bike.Hash() == cheaper.Hash() {
+		t.Error("a price change has the same hash")
+	}
+}
@@ -0,0 +1,78 @@
+package searchcraigs
+
+import (
+	"fmt"
+	"io"
+)
+
+// default max number of requests a run can send without -yes
+const defaultMaxRequests = 100
+
+// max number of results in a search page, to estimate the images downloaded by -embed-images
+const resultsPerPage = 120
+
+// RequestEstimate is a rough upper bound of the number of requests a run will send.
+type RequestEstimate struct {
+	Regions    int
+	Categories int
+	Pages      int // max result pages per region and category
+	Details    int // listing details fetched
+	Sellers    int // seller listing pages fetched
+	Wayback    int // archived pages fetched per region
+	Images     int // thumbnails downloaded (-embed-images)
+
+	RegionList bool // the region list is downloaded to check the regions (not in the cache)
+}
+
+// Searches returns the number of search result pages.
+func (e RequestEstimate) Searches() int {
+	return max(e.Regions, 1) * max(e.Categories, 1) * max(e.Pages, 1)
+}
+
+// Archive returns the number of requests to the Wayback Machine (one CDX query plus the snapshots, for each region).
+func (e RequestEstimate) Archive() int {
+	if e.Wayback <= 0 {
+		return 0
+	}
+
+	return max(e.Regions, 1) * (1 + e.Wayback)
+}
+
+// Total returns the total number of requests.
+func (e RequestEstimate) Total() int {
+	total := e.Searches() + e.Details + e.Sellers + e.Archive() + e.Images
+	if e.RegionList {
+		total++
+	}
+
+	return total
+}
+
+// Print prints the breakdown of the estimate.
+func (e RequestEstimate) Print(w io.Writer) {
+	fmt.Fprintf(w, "%-10v %5v (%v regions x %v categories x %v pages)\n", "searches", e.Searches(),
+		max(e.Regions, 1), max(e.Categories, 1), max(e.Pages, 1))
+
+	if e.Details > 0 {
+		fmt.Fprintf(w, "%-10v %5v\n", "details", e.Details)
+	}
+
+	if e.Sellers > 0 {
+		fmt.Fprintf(w, "%-10v %5v\n", "sellers", e.Sellers)
+	}
+
+	if e.Images > 0 {
+		fmt.Fprintf(w, "%-10v %5v\n", "images", e.Images)
+	}
+
+	if e.Archive() > 0 {
+		fmt.Fprintf(w, "%-10v %5v (%v regions x %v snapshots, plus index)\n", "wayback", e.Archive(),
+			max(e.Regions, 1), e.Wayback)
+	}
+
+	if e.RegionList {
+		fmt.Fprintf(w, "%-10v %5v (region list)\n", "regions", 1)
+	}
+
+	fmt.Fprintf(w, "%-10v %5v\n", "total", e.Total())
+}
@@ -0,0 +1,50 @@
+package searchcraigs
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRequestEstimate(t *testing.T) {
+	tests := []struct {
+		name string
+		est  RequestEstimate
+		want int
+	}{
+		{"defaults", RequestEstimate{}, 1},
+		{"one page", RequestEstimate{Regions: 1, Categories: 1, Pages: 1}, 1},
+		{"pages", RequestEstimate{Regions: 1, Categories: 1, Pages: 20}, 20},
+		{"regions", RequestEstimate{Regions: 3, Categories: 1, Pages: 2}, 6},
+		{"categories", RequestEstimate{Regions: 2, Categories: 3, Pages: 2}, 12},
+		{"details", RequestEstimate{Regions: 1, Pages: 1, Details: 25}, 26},
+		{"sellers", RequestEstimate{Regions: 1, Pages: 1, Details: 25, Sellers: 5}, 31},
+		{"wayback", RequestEstimate{Regions: 2, Pages: 1, Wayback: 10}, 2 + 2*11},
+		{"region list", RequestEstimate{Regions: 3, Pages: 2, RegionList: true}, 7},
+		{"images", RequestEstimate{Regions: 1, Pages: 20, Images: 20 * resultsPerPage}, 20 + 20*resultsPerPage},
+		{"all", RequestEstimate{Regions: 2, Categories: 1, Pages: 3, Details: 10, Sellers: 2, Wayback: 5, Images: 50}, 6 + 10 + 2 + 12 + 50},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.est.Total(); got != tt.want {
+				t.Errorf("Total() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequestEstimatePrint(t *testing.T) {
+	var b strings.Builder
+
+	RequestEstimate{Regions: 2, Pages: 3, Details: 10, Images: 50}.Print(&b)
+
+	want := `searches       6 (2 regions x 1 categories x 3 pages)
+details       10
+images        50
+total         66
+`
+
+	if b.String() != want {
+		t.Errorf("got\n%v\nwant\n%v", b.String(), want)
+	}
+}
@@ -0,0 +1,206 @@
+package searchcraigs
+
+import (
+	"encoding/xml"
+	"fmt"
+	"html"
+	"io"
+	"strings"
+	"time"
+)
+
+// RSS 2.0 and Atom output
+
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	AtomNS  string     `xml:"xmlns:atom,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	Self          atomLink  `xml:"atom:link"`
+	LastBuildDate string    `xml:"lastBuildDate,omitempty"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string        `xml:"title"`
+	Link        string        `xml:"link"`
+	Description string        `xml:"description"`
+	GUID        rssGUID       `xml:"guid"`
+	PubDate     string        `xml:"pubDate,omitempty"`
+	Enclosure   *rssEnclosure `xml:"enclosure"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+type rssEnclosure struct {
+	URL    string `xml:"url,attr"`
+	Length int    `xml:"length,attr"`
+	Type   string `xml:"type,attr"`
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Updated string      `xml:"updated"`
+	Author  atomAuthor  `xml:"author"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type atomEntry struct {
+	Title   string   `xml:"title"`
+	ID      string   `xml:"id"`
+	Updated string   `xml:"updated"`
+	Link    atomLink `xml:"link"`
+	Summary atomText `xml:"summary"`
+}
+
+type atomText struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+func feedItemTitle(e ResultEntry) string {
+	if e.Price == "" {
+		return e.Title
+	}
+
+	return e.Title + " - " + e.Price
+}
+
+// feedItemDescription returns the HTML description for an entry
+func feedItemDescription(e ResultEntry) string {
+	var b strings.Builder
+
+	if e.Image != "" {
+		fmt.Fprintf(&b, `<img src="%v" alt="%v"/>`, html.EscapeString(e.Image), html.EscapeString(e.Title))
+	}
+
+	fmt.Fprintf(&b, "<p>Price: %v<br/>%v</p>",
+		html.EscapeString(e.Price),
+		html.EscapeString(strings.TrimSpace(e.NearbyDesc+" "+e.Neighborhood)))
+
+	return b.String()
+}
+
+// latestPosted returns the most recent posting time, or now if no entry has one.
+func latestPosted(entries []ResultEntry) time.Time {
+	var t time.Time
+
+	for _, e := range entries {
+		if e.Posted.After(t) {
+			t = e.Posted
+		}
+	}
+
+	if t.IsZero() {
+		t = time.Now()
+	}
+
+	return t
+}
+
+// WriteRSS writes the search results as an RSS 2.0 feed.
+func WriteRSS(w io.Writer, res *SearchResults) error {
+	feed := rssFeed{
+		Version: "2.0",
+		AtomNS:  "http://www.w3.org/2005/Atom",
+		Channel: rssChannel{
+			Title:         res.Title,
+			Link:          res.Url,
+			Description:   strings.TrimSpace("craigslist search: " + res.Title + " " + res.Subtitle),
+			Self:          atomLink{Href: res.Url, Rel: "self", Type: "application/rss+xml"},
+			LastBuildDate: latestPosted(res.Entries).Format(time.RFC1123Z),
+		},
+	}
+
+	for _, e := range res.Entries {
+		item := rssItem{
+			Title:       feedItemTitle(e),
+			Link:        e.Href,
+			Description: feedItemDescription(e),
+			GUID:        rssGUID{IsPermaLink: true, Value: e.Href},
+		}
+
+		if !e.Posted.IsZero() {
+			item.PubDate = e.Posted.Format(time.RFC1123Z)
+		}
+
+		if e.Image != "" {
+			item.Enclosure = &rssEnclosure{URL: e.Image, Type: "image/jpeg"}
+		}
+
+		feed.Channel.Items = append(feed.Channel.Items, item)
+	}
+
+	return writeXML(w, feed)
+}
+
+// WriteAtom writes the search results as an Atom feed.
+func WriteAtom(w io.Writer, res *SearchResults) error {
+	updated := latestPosted(res.Entries)
+
+	feed := atomFeed{
+		Title:   res.Title,
+		ID:      res.Url,
+		Updated: updated.Format(time.RFC3339),
+		Author:  atomAuthor{Name: "craigslist"},
+		Links: []atomLink{
+			{Href: res.Url, Rel: "self"},
+			{Href: res.Url, Rel: "alternate", Type: "text/html"},
+		},
+	}
+
+	for _, e := range res.Entries {
+		posted := e.Posted
+		if posted.IsZero() {
+			posted = updated
+		}
+
+		feed.Entries = append(feed.Entries, atomEntry{
+			Title:   feedItemTitle(e),
+			ID:      e.Href,
+			Updated: posted.Format(time.RFC3339),
+			Link:    atomLink{Href: e.Href, Rel: "alternate"},
+			Summary: atomText{Type: "html", Body: feedItemDescription(e)},
+		})
+	}
+
+	return writeXML(w, feed)
+}
+
+func writeXML(w io.Writer, v interface{}) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+
+	if err := enc.Encode(v); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(w, "\n")
+	return err
+}
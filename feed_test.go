@@ -0,0 +1,397 @@
+package searchcraigs
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+const atomNS = "http://www.w3.org/2005/Atom"
+
+func feedResults() *SearchResults {
+	return &SearchResults{
+		Title:    "road bike",
+		Subtitle: "Price: 100-500",
+		Url:      "https://sfbay.craigslist.org/search/bia?max_price=500&min_price=100&query=road+bike",
+		Entries: []ResultEntry{
+			{
+				Title:        "Road bike <56cm> & pedals",
+				Href:         "https://sfbay.craigslist.org/eby/bik/d/oakland-road-bike/7780000001.html",
+				Image:        "https://images.craigslist.org/00K0K_1aBcDeFgHiJ_300x300.jpg",
+				Price:        "$450",
+				Neighborhood: "oakland",
+				Posted:       time.Date(2024, 9, 16, 9, 30, 0, 0, time.UTC),
+			},
+			{
+				Title:      "Vintage road bike",
+				Href:       "https://sacramento.craigslist.org/bik/d/davis-vintage-road-bike/7780000002.html",
+				Price:      "$120",
+				NearbyDesc: "davis",
+				Posted:     time.Date(2024, 9, 15, 18, 5, 0, 0, time.UTC),
+			},
+			{
+				Title: "Road bike, no date or price",
+				Href:  "https://sfbay.craigslist.org/sfc/bik/d/san-francisco-road-bike/7780000003.html",
+			},
+		},
+	}
+}
+
+// the elements of a feed, as read by a feed reader (not the structs used to write them)
+type xmlNode struct {
+	XMLName xml.Name
+	Attrs   []xml.Attr `xml:",any,attr"`
+	Text    string     `xml:",chardata"`
+	Nodes   []xmlNode  `xml:",any"`
+}
+
+func (n xmlNode) attr(name string) (string, bool) {
+	for _, a := range n.Attrs {
+		if a.Name.Local == name {
+			return a.Value, true
+		}
+	}
+
+	return "", false
+}
+
+func (n xmlNode) all(space, local string) []xmlNode {
+	var nodes []xmlNode
+
+	for _, c := range n.Nodes {
+		if c.XMLName.Local == local && c.XMLName.Space == space {
+			nodes = append(nodes, c)
+		}
+	}
+
+	return nodes
+}
+
+// one returns the only child element named local, or an error if there is none or more than one.
+func (n xmlNode) one(space, local string) (xmlNode, error) {
+	nodes := n.all(space, local)
+	if len(nodes) != 1 {
+		return xmlNode{}, fmt.Errorf("<%v> has %v <%v> elements, want 1", n.XMLName.Local, len(nodes), local)
+	}
+
+	return nodes[0], nil
+}
+
+func checkAbsoluteURL(what, s string) error {
+	u, err := url.Parse(s)
+	if err != nil || !u.IsAbs() || u.Host == "" {
+		return fmt.Errorf("%v %q is not an absolute URL", what, s)
+	}
+
+	return nil
+}
+
+// validateRSS checks the rules of the RSS 2.0 specification (and the RSS advisory board
+// recommendations checked by the W3C feed validator) that apply to our feeds.
+func validateRSS(data []byte) []error {
+	var errs []error
+	check := func(err error) {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	var rss xmlNode
+	if err := xml.Unmarshal(data, &rss); err != nil {
+		return []error{err}
+	}
+
+	if rss.XMLName.Local != "rss" {
+		return []error{fmt.Errorf("root element <%v>, want <rss>", rss.XMLName.Local)}
+	}
+
+	if v, _ := rss.attr("version"); v != "2.0" {
+		check(fmt.Errorf("version %q, want 2.0", v))
+	}
+
+	channel, err := rss.one("", "channel")
+	if err != nil {
+		return []error{err}
+	}
+
+	for _, name := range []string{"title", "link", "description"} {
+		_, err := channel.one("", name)
+		check(err)
+	}
+
+	if link, err := channel.one("", "link"); err == nil {
+		check(checkAbsoluteURL("channel link", link.Text))
+	}
+
+	if date, err := channel.one("", "lastBuildDate"); err == nil {
+		if _, err := time.Parse(time.RFC1123Z, date.Text); err != nil {
+			check(fmt.Errorf("lastBuildDate: %w", err))
+		}
+	}
+
+	// recommended: an atom:link to the feed itself
+	self, err := channel.one(atomNS, "link")
+	check(err)
+
+	if rel, _ := self.attr("rel"); err == nil && rel != "self" {
+		check(fmt.Errorf("atom:link rel %q, want self", rel))
+	}
+
+	for i, item := range channel.all("", "item") {
+		title, terr := item.one("", "title")
+		_, derr := item.one("", "description")
+		if terr != nil && derr != nil {
+			check(fmt.Errorf("item %v has no title or description", i))
+		}
+
+		if terr == nil && title.Text == "" {
+			check(fmt.Errorf("item %v has an empty title", i))
+		}
+
+		if link, err := item.one("", "link"); err == nil {
+			check(checkAbsoluteURL(fmt.Sprintf("item %v link", i), link.Text))
+		}
+
+		if guid, err := item.one("", "guid"); err == nil {
+			if perma, _ := guid.attr("isPermaLink"); perma != "false" {
+				check(checkAbsoluteURL(fmt.Sprintf("item %v guid", i), guid.Text))
+			}
+		} else {
+			check(fmt.Errorf("item %v: %w", i, err))
+		}
+
+		for _, date := range item.all("", "pubDate") {
+			if _, err := time.Parse(time.RFC1123Z, date.Text); err != nil {
+				check(fmt.Errorf("item %v pubDate: %w", i, err))
+			}
+		}
+
+		for _, enc := range item.all("", "enclosure") {
+			for _, attr := range []string{"url", "length", "type"} {
+				if _, ok := enc.attr(attr); !ok {
+					check(fmt.Errorf("item %v enclosure has no %v", i, attr))
+				}
+			}
+
+			u, _ := enc.attr("url")
+			check(checkAbsoluteURL(fmt.Sprintf("item %v enclosure", i), u))
+		}
+	}
+
+	return errs
+}
+
+// validateAtom checks the rules of RFC 4287 that apply to our feeds.
+func validateAtom(data []byte) []error {
+	var errs []error
+	check := func(err error) {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	var feed xmlNode
+	if err := xml.Unmarshal(data, &feed); err != nil {
+		return []error{err}
+	}
+
+	if feed.XMLName.Space != atomNS || feed.XMLName.Local != "feed" {
+		return []error{fmt.Errorf("root element %v, want the atom feed", feed.XMLName)}
+	}
+
+	checkDate := func(what string, n xmlNode) {
+		d, err := n.one(atomNS, "updated")
+		if err != nil {
+			check(fmt.Errorf("%v: %w", what, err))
+			return
+		}
+
+		if _, err := time.Parse(time.RFC3339, d.Text); err != nil {
+			check(fmt.Errorf("%v updated: %w", what, err))
+		}
+	}
+
+	for _, name := range []string{"id", "title"} {
+		_, err := feed.one(atomNS, name)
+		check(err)
+	}
+
+	checkDate("feed", feed)
+
+	if id, err := feed.one(atomNS, "id"); err == nil {
+		check(checkAbsoluteURL("feed id", id.Text))
+	}
+
+	// the feed needs an author if any entry doesn't have one
+	if author, err := feed.one(atomNS, "author"); err != nil {
+		check(err)
+	} else if _, err := author.one(atomNS, "name"); err != nil {
+		check(err)
+	}
+
+	hasSelf := false
+	for _, l := range feed.all(atomNS, "link") {
+		if rel, _ := l.attr("rel"); rel == "self" {
+			hasSelf = true
+		}
+	}
+
+	if !hasSelf {
+		check(fmt.Errorf("no self link"))
+	}
+
+	ids := map[string]bool{}
+
+	for i, entry := range feed.all(atomNS, "entry") {
+		what := fmt.Sprintf("entry %v", i)
+
+		for _, name := range []string{"id", "title"} {
+			if _, err := entry.one(atomNS, name); err != nil {
+				check(fmt.Errorf("%v: %w", what, err))
+			}
+		}
+
+		checkDate(what, entry)
+
+		if id, err := entry.one(atomNS, "id"); err == nil {
+			check(checkAbsoluteURL(what+" id", id.Text))
+
+			if ids[id.Text] {
+				check(fmt.Errorf("%v: duplicate id %v", what, id.Text))
+			}
+
+			ids[id.Text] = true
+		}
+
+		// entries without content need an alternate link
+		if len(entry.all(atomNS, "content")) == 0 {
+			alternate := false
+			for _, l := range entry.all(atomNS, "link") {
+				if rel, ok := l.attr("rel"); !ok || rel == "alternate" {
+					alternate = true
+				}
+			}
+
+			if !alternate {
+				check(fmt.Errorf("%v has no content and no alternate link", what))
+			}
+		}
+
+		if summary, err := entry.one(atomNS, "summary"); err == nil {
+			if typ, _ := summary.attr("type"); typ != "text" && typ != "html" && typ != "xhtml" && typ != "" {
+				check(fmt.Errorf("%v summary type %q", what, typ))
+			}
+		}
+	}
+
+	return errs
+}
+
+func TestWriteRSS(t *testing.T) {
+	var b strings.Builder
+	if err := WriteRSS(&b, feedResults()); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, err := range validateRSS([]byte(b.String())) {
+		t.Error(err)
+	}
+
+	feed := b.String()
+
+	for _, s := range []string{
+		`<title>road bike</title>`,
+		`<link>https://sfbay.craigslist.org/search/bia?max_price=500&amp;min_price=100&amp;query=road+bike</link>`,
+		`<title>Road bike &lt;56cm&gt; &amp; pedals - $450</title>`,
+		`<pubDate>Mon, 16 Sep 2024 09:30:00 +0000</pubDate>`,
+		`<enclosure url="https://images.craigslist.org/00K0K_1aBcDeFgHiJ_300x300.jpg" length="0" type="image/jpeg"></enclosure>`,
+		`&lt;p&gt;Price: $120&lt;br/&gt;davis&lt;/p&gt;`,
+		`<title>Road bike, no date or price</title>`,
+		`<lastBuildDate>Mon, 16 Sep 2024 09:30:00 +0000</lastBuildDate>`,
+	} {
+		if !strings.Contains(feed, s) {
+			t.Errorf("%v not found in\n%v", s, feed)
+		}
+	}
+
+	if n := strings.Count(feed, "<pubDate>"); n != 2 {
+		t.Errorf("%v pubDate, want 2 (no date for the last entry)", n)
+	}
+}
+
+func TestWriteAtom(t *testing.T) {
+	var b strings.Builder
+	if err := WriteAtom(&b, feedResults()); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, err := range validateAtom([]byte(b.String())) {
+		t.Error(err)
+	}
+
+	feed := b.String()
+
+	for _, s := range []string{
+		`<feed xmlns="http://www.w3.org/2005/Atom">`,
+		`<updated>2024-09-16T09:30:00Z</updated>`,
+		`<link href="https://sfbay.craigslist.org/search/bia?max_price=500&amp;min_price=100&amp;query=road+bike" rel="self"></link>`,
+		`<title>Road bike &lt;56cm&gt; &amp; pedals - $450</title>`,
+		`<summary type="html">`,
+	} {
+		if !strings.Contains(feed, s) {
+			t.Errorf("%v not found in\n%v", s, feed)
+		}
+	}
+}
+
+// the validators catch the errors they are meant to catch
+func TestFeedValidators(t *testing.T) {
+	rss := []struct {
+		name string
+		feed string
+	}{
+		{"not xml", `<rss version="2.0"><channel>`},
+		{"version", `<rss version="0.91"><channel><title>t</title><link>https://x.org/</link><description>d</description>` +
+			`<atom:link xmlns:atom="http://www.w3.org/2005/Atom" href="https://x.org/" rel="self"/></channel></rss>`},
+		{"no link", `<rss version="2.0" xmlns:atom="http://www.w3.org/2005/Atom"><channel><title>t</title><description>d</description>` +
+			`<atom:link href="https://x.org/" rel="self"/></channel></rss>`},
+		{"bad date", `<rss version="2.0" xmlns:atom="http://www.w3.org/2005/Atom"><channel><title>t</title><link>https://x.org/</link><description>d</description>` +
+			`<atom:link href="https://x.org/" rel="self"/><item><title>i</title><guid>https://x.org/1</guid><pubDate>2024-09-16</pubDate></item></channel></rss>`},
+		{"relative link", `<rss version="2.0" xmlns:atom="http://www.w3.org/2005/Atom"><channel><title>t</title><link>https://x.org/</link><description>d</description>` +
+			`<atom:link href="https://x.org/" rel="self"/><item><title>i</title><link>/1.html</link><guid>https://x.org/1</guid></item></channel></rss>`},
+	}
+
+	for _, tt := range rss {
+		t.Run("rss "+tt.name, func(t *testing.T) {
+			if errs := validateRSS([]byte(tt.feed)); len(errs) == 0 {
+				t.Error("the feed is valid")
+			}
+		})
+	}
+
+	atom := []struct {
+		name string
+		feed string
+	}{
+		{"not atom", `<feed><title>t</title></feed>`},
+		{"no updated", `<feed xmlns="http://www.w3.org/2005/Atom"><title>t</title><id>https://x.org/</id><author><name>a</name></author>` +
+			`<link href="https://x.org/" rel="self"/></feed>`},
+		{"no author", `<feed xmlns="http://www.w3.org/2005/Atom"><title>t</title><id>https://x.org/</id><updated>2024-09-16T09:30:00Z</updated>` +
+			`<link href="https://x.org/" rel="self"/></feed>`},
+		{"duplicate id", `<feed xmlns="http://www.w3.org/2005/Atom"><title>t</title><id>https://x.org/</id><updated>2024-09-16T09:30:00Z</updated>` +
+			`<author><name>a</name></author><link href="https://x.org/" rel="self"/>` +
+			`<entry><title>e</title><id>https://x.org/1</id><updated>2024-09-16T09:30:00Z</updated><link href="https://x.org/1"/></entry>` +
+			`<entry><title>e</title><id>https://x.org/1</id><updated>2024-09-16T09:30:00Z</updated><link href="https://x.org/1"/></entry></feed>`},
+	}
+
+	for _, tt := range atom {
+		t.Run("atom "+tt.name, func(t *testing.T) {
+			if errs := validateAtom([]byte(tt.feed)); len(errs) == 0 {
+				t.Error("the feed is valid")
+			}
+		})
+	}
+}
@@ -0,0 +1,437 @@
+package searchcraigs
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Filter is a predicate over the search results, see ParseFilter.
+type Filter func(e *ResultEntry) bool
+
+// Apply returns the entries matching the filter.
+func (f Filter) Apply(in []ResultEntry) []ResultEntry {
+	out := make([]ResultEntry, 0, len(in))
+
+	for i := range in {
+		if f(&in[i]) {
+			out = append(out, in[i])
+		}
+	}
+
+	return out
+}
+
+// filterFields are the string fields of the field selectors (field:value, field~regexp, field=value)
+var filterFields = map[string]func(e *ResultEntry) string{
+	"title":    func(e *ResultEntry) string { return e.Title },
+	"hood":     func(e *ResultEntry) string { return strings.TrimSpace(e.Neighborhood + " " + e.NearbyLoc) },
+	"region":   func(e *ResultEntry) string { return e.Region },
+	"category": func(e *ResultEntry) string { return e.EntryCategory },
+	"meta":     func(e *ResultEntry) string { return e.Meta },
+	"extras":   func(e *ResultEntry) string { return e.Extras },
+	"desc": func(e *ResultEntry) string {
+		if e.Details == nil {
+			return ""
+		}
+
+		return e.Details.Description
+	},
+}
+
+// filterNumbers are the numeric fields of the field selectors (field<value, field>=value, ...)
+var filterNumbers = map[string]func(e *ResultEntry) float64{
+	"price":    func(e *ResultEntry) float64 { return float64(e.PriceValue) },
+	"distance": func(e *ResultEntry) float64 { return e.Distance },
+}
+
+// the field selector operators, longest first
+var filterOps = []string{"<=", ">=", "<", ">", "=", ":", "~"}
+
+// ParseFilter parses a filter expression:
+//
+//	road bike            title contains "road" and "bike" (also road&bike or road,bike)
+//	road|gravel          title contains "road" or "gravel" (| has lower precedence than and)
+//	"road bike"          title contains the phrase
+//	-carbon              title doesn't contain "carbon" (also !carbon or ^carbon)
+//	(road|gravel) bike   parentheses group terms
+//	price<800            numeric fields (price, distance) with <, <=, >, >=, =
+//	hood:oakland         string fields (title, hood, region, category, meta, extras, desc) contain the value
+//	title~"road (bike|frame)"   string fields match the regular expression
+//
+// All the matches are case insensitive.
+func ParseFilter(s string) (Filter, error) {
+	return parseFilter(s, nil)
+}
+
+// parseFilter is ParseFilter, matching the title words and phrases with their synonyms.
+func parseFilter(s string, syn Synonyms) (Filter, error) {
+	tokens, err := tokenizeFilter(s)
+	if err != nil {
+		return nil, err
+	}
+
+	p := filterParser{tokens: tokens, syn: syn}
+
+	if p.peek().kind == tokEOF {
+		return func(e *ResultEntry) bool { return true }, nil
+	}
+
+	f, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, t.errorf("unexpected %q", t.text)
+	}
+
+	return f, nil
+}
+
+// applyFilter returns the entries matching the filter expression f (see ParseFilter).
+func applyFilter(f string, syn Synonyms, in []ResultEntry) ([]ResultEntry, error) {
+	filter, err := parseFilter(f, syn)
+	if err != nil {
+		return nil, err
+	}
+
+	return filter.Apply(in), nil
+}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokWord
+	tokPhrase
+	tokField // field, op and value
+	tokNot
+	tokAnd
+	tokOr
+	tokOpen
+	tokClose
+)
+
+type filterToken struct {
+	kind tokenKind
+	pos  int // byte offset in the expression
+	text string
+
+	field, op, value string // for tokField
+}
+
+func (t filterToken) errorf(format string, args ...any) error {
+	return fmt.Errorf("invalid filter: %v at position %v", fmt.Sprintf(format, args...), t.pos+1)
+}
+
+// characters that end an unquoted word
+const filterStops = " \t\n()|&,\""
+
+func tokenizeFilter(s string) ([]filterToken, error) {
+	var tokens []filterToken
+
+	for i := 0; i < len(s); {
+		c := s[i]
+
+		switch {
+		case c == ' ' || c == '\t' || c == '\n':
+			i++
+
+		case c == '(':
+			tokens = append(tokens, filterToken{kind: tokOpen, pos: i, text: "("})
+			i++
+
+		case c == ')':
+			tokens = append(tokens, filterToken{kind: tokClose, pos: i, text: ")"})
+			i++
+
+		case c == '|':
+			tokens = append(tokens, filterToken{kind: tokOr, pos: i, text: "|"})
+			i++
+
+		case c == '&' || c == ',':
+			tokens = append(tokens, filterToken{kind: tokAnd, pos: i, text: string(c)})
+			i++
+
+		case c == '-' || c == '!' || c == '^':
+			tokens = append(tokens, filterToken{kind: tokNot, pos: i, text: string(c)})
+			i++
+
+		case c == '"':
+			phrase, n, err := readPhrase(s, i)
+			if err != nil {
+				return nil, err
+			}
+
+			tokens = append(tokens, filterToken{kind: tokPhrase, pos: i, text: phrase})
+			i += n
+
+		default:
+			start := i
+			for i < len(s) && !strings.ContainsRune(filterStops, rune(s[i])) {
+				i++
+			}
+
+			word := s[start:i]
+			t := filterToken{kind: tokWord, pos: start, text: word}
+
+			if field, op, value, ok := splitSelector(word); ok {
+				t.kind, t.field, t.op, t.value = tokField, field, op, value
+
+				if value == "" && i < len(s) && s[i] == '"' {
+					phrase, n, err := readPhrase(s, i)
+					if err != nil {
+						return nil, err
+					}
+
+					t.value = phrase
+					i += n
+				}
+
+				if t.value == "" {
+					return nil, t.errorf("missing value in %q", word)
+				}
+
+				if err := checkSelector(t); err != nil {
+					return nil, err
+				}
+			}
+
+			tokens = append(tokens, t)
+		}
+	}
+
+	return append(tokens, filterToken{kind: tokEOF, pos: len(s), text: "end of filter"}), nil
+}
+
+// readPhrase reads the quoted phrase at s[start], returning the phrase and its length (with the quotes).
+// A \" in the phrase is a quote.
+func readPhrase(s string, start int) (string, int, error) {
+	var b strings.Builder
+
+	for i := start + 1; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && i+1 < len(s) && s[i+1] == '"':
+			b.WriteByte('"')
+			i++
+
+		case s[i] == '"':
+			return b.String(), i + 1 - start, nil
+
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+
+	return "", 0, filterToken{pos: start}.errorf("unterminated quote")
+}
+
+// splitSelector splits a field selector (i.e. price<=500) in field, operator and value.
+// Words that don't start with letters followed by an operator are not selectors.
+func splitSelector(word string) (field, op, value string, ok bool) {
+	i := 0
+	for i < len(word) && word[i] >= 'a' && word[i] <= 'z' {
+		i++
+	}
+
+	if i == 0 {
+		return "", "", "", false
+	}
+
+	for _, op := range filterOps {
+		if strings.HasPrefix(word[i:], op) {
+			return word[:i], op, word[i+len(op):], true
+		}
+	}
+
+	return "", "", "", false
+}
+
+// checkSelector checks that the field exists and supports the operator.
+func checkSelector(t filterToken) error {
+	if _, ok := filterNumbers[t.field]; ok {
+		if t.op == ":" || t.op == "~" {
+			return t.errorf("%v is a number, use %v<, >, <=, >= or =", t.field, t.field)
+		}
+
+		if _, err := strconv.ParseFloat(t.value, 64); err != nil {
+			return t.errorf("invalid number %q for %v", t.value, t.field)
+		}
+
+		return nil
+	}
+
+	if _, ok := filterFields[t.field]; ok {
+		if t.op != ":" && t.op != "~" && t.op != "=" {
+			return t.errorf("%v is not a number, use %v:, ~ or =", t.field, t.field)
+		}
+
+		return nil
+	}
+
+	return t.errorf("unknown field %q (fields: %v)", t.field,
+		strings.Join(append(sortedKeys(filterFields), sortedKeys(filterNumbers)...), ", "))
+}
+
+type filterParser struct {
+	tokens []filterToken
+	pos    int
+	syn    Synonyms
+}
+
+func (p *filterParser) peek() filterToken {
+	return p.tokens[p.pos]
+}
+
+func (p *filterParser) next() filterToken {
+	t := p.tokens[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+
+	return t
+}
+
+// or := and ('|' and)*
+func (p *filterParser) parseOr() (Filter, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.peek().kind == tokOr {
+		p.next()
+
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+
+		l := left
+		left = func(e *ResultEntry) bool { return l(e) || right(e) }
+	}
+
+	return left, nil
+}
+
+// and := unary (['&' | ','] unary)*
+func (p *filterParser) parseAnd() (Filter, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		switch p.peek().kind {
+		case tokEOF, tokOr, tokClose:
+			return left, nil
+
+		case tokAnd:
+			p.next()
+		}
+
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+
+		l := left
+		left = func(e *ResultEntry) bool { return l(e) && right(e) }
+	}
+}
+
+// unary := ('-' | '!' | '^') unary | '(' or ')' | term
+func (p *filterParser) parseUnary() (Filter, error) {
+	t := p.next()
+
+	switch t.kind {
+	case tokNot:
+		f, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+
+		return func(e *ResultEntry) bool { return !f(e) }, nil
+
+	case tokOpen:
+		f, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+
+		if c := p.next(); c.kind != tokClose {
+			return nil, c.errorf("expected ) to close the ( at position %v, found %q", t.pos+1, c.text)
+		}
+
+		return f, nil
+
+	case tokWord, tokPhrase:
+		return p.titleTerm(t.text), nil
+
+	case tokField:
+		return p.fieldTerm(t)
+
+	default:
+		return nil, t.errorf("expected a term, found %q", t.text)
+	}
+}
+
+// titleTerm matches the titles containing the term, or one of its synonyms.
+func (p *filterParser) titleTerm(term string) Filter {
+	term = strings.ToLower(term)
+
+	terms := []string{term}
+	if p.syn != nil {
+		terms = p.syn.Lookup(term)
+	}
+
+	return func(e *ResultEntry) bool {
+		title := strings.ToLower(e.Title)
+
+		for _, t := range terms {
+			if strings.Contains(title, strings.ToLower(t)) {
+				return true
+			}
+		}
+
+		return false
+	}
+}
+
+func (p *filterParser) fieldTerm(t filterToken) (Filter, error) {
+	if number, ok := filterNumbers[t.field]; ok {
+		v, _ := strconv.ParseFloat(t.value, 64) // checked by checkSelector
+
+		compare := map[string]func(a float64) bool{
+			"<":  func(a float64) bool { return a < v },
+			"<=": func(a float64) bool { return a <= v },
+			">":  func(a float64) bool { return a > v },
+			">=": func(a float64) bool { return a >= v },
+			"=":  func(a float64) bool { return a == v },
+		}[t.op]
+
+		return func(e *ResultEntry) bool { return compare(number(e)) }, nil
+	}
+
+	field := filterFields[t.field]
+	value := strings.ToLower(t.value)
+
+	switch t.op {
+	case "~":
+		if _, err := regexp.Compile(t.value); err != nil {
+			return nil, t.errorf("invalid regular expression %q: %v", t.value, strings.TrimPrefix(err.Error(), "error parsing regexp: "))
+		}
+
+		re := regexp.MustCompile("(?i)" + t.value)
+
+		return func(e *ResultEntry) bool { return re.MatchString(field(e)) }, nil
+
+	case "=":
+		return func(e *ResultEntry) bool { return strings.ToLower(field(e)) == value }, nil
+
+	default:
+		return func(e *ResultEntry) bool { return strings.Contains(strings.ToLower(field(e)), value) }, nil
+	}
+}
@@ -0,0 +1,137 @@
+package searchcraigs
+
+import (
+	"strings"
+	"testing"
+)
+
+var filterEntries = []ResultEntry{
+	{Title: "Road bike 54cm", PriceValue: 450, Neighborhood: "(oakland)"},
+	{Title: "Carbon road bike", PriceValue: 1200, Neighborhood: "(berkeley)"},
+	{Title: "Gravel bike", PriceValue: 700, Neighborhood: "(oakland)", Distance: 3.5},
+	{Title: "Road frame", PriceValue: 300, Neighborhood: "(san jose)", Distance: 40},
+	{Title: "Kids bike \"like new\"", PriceValue: 50, Neighborhood: "(oakland)"},
+}
+
+// titlesMatching returns the titles of the filterEntries matching the filter
+func titlesMatching(t *testing.T, expr string) string {
+	t.Helper()
+
+	f, err := ParseFilter(expr)
+	if err != nil {
+		t.Fatalf("ParseFilter(%q): %v", expr, err)
+	}
+
+	var titles []string
+	for _, e := range f.Apply(filterEntries) {
+		titles = append(titles, e.Title)
+	}
+
+	return strings.Join(titles, "; ")
+}
+
+func TestParseFilter(t *testing.T) {
+	tests := []struct {
+		name, filter, want string
+	}{
+		{"empty", "", "Road bike 54cm; Carbon road bike; Gravel bike; Road frame; Kids bike \"like new\""},
+		{"word", "gravel", "Gravel bike"},
+		{"case insensitive", "ROAD", "Road bike 54cm; Carbon road bike; Road frame"},
+		{"and (space)", "road bike", "Road bike 54cm; Carbon road bike"},
+		{"and (&)", "road&bike", "Road bike 54cm; Carbon road bike"},
+		{"and (,)", "road,bike", "Road bike 54cm; Carbon road bike"},
+		{"or", "gravel|frame", "Gravel bike; Road frame"},
+
+		// and binds tighter than or
+		{"precedence", "road bike|gravel", "Road bike 54cm; Carbon road bike; Gravel bike"},
+		{"precedence right", "gravel|road bike", "Road bike 54cm; Carbon road bike; Gravel bike"},
+		{"parentheses", "(road|gravel) bike", "Road bike 54cm; Carbon road bike; Gravel bike"},
+		{"parentheses and", "road (bike|frame) -carbon", "Road bike 54cm; Road frame"},
+
+		{"negation", "bike -carbon", "Road bike 54cm; Gravel bike; Kids bike \"like new\""},
+		{"negation !", "bike !carbon", "Road bike 54cm; Gravel bike; Kids bike \"like new\""},
+		{"negation ^", "bike ^carbon", "Road bike 54cm; Gravel bike; Kids bike \"like new\""},
+		{"negated group", "-(road|gravel)", "Kids bike \"like new\""},
+		{"double negation", "--gravel", "Gravel bike"},
+		{"negation binds to the term", "-road|gravel", "Gravel bike; Kids bike \"like new\""},
+
+		{"phrase", `"road bike"`, "Road bike 54cm; Carbon road bike"},
+		{"phrase order", `"bike road"`, ""},
+		{"phrase with space", `"bike 54"`, "Road bike 54cm"},
+		{"phrase with operators", `"(road|gravel)"`, ""},
+		{"escaped quote", `"\"like new\""`, "Kids bike \"like new\""},
+		{"negated phrase", `bike -"road bike"`, "Gravel bike; Kids bike \"like new\""},
+
+		{"price", "price<500", "Road bike 54cm; Road frame; Kids bike \"like new\""},
+		{"price <=", "price<=450", "Road bike 54cm; Road frame; Kids bike \"like new\""},
+		{"price >", "price>700", "Carbon road bike"},
+		{"price >=", "price>=700", "Carbon road bike; Gravel bike"},
+		{"price =", "price=300", "Road frame"},
+		{"price range", "price>=300 price<=700", "Road bike 54cm; Gravel bike; Road frame"},
+		{"distance", "distance>0 distance<10", "Gravel bike"},
+		{"hood", "hood:oakland", "Road bike 54cm; Gravel bike; Kids bike \"like new\""},
+		{"hood phrase", `hood:"san jose"`, "Road frame"},
+		{"hood equal", `hood="(berkeley)"`, "Carbon road bike"},
+		{"hood not equal", `hood=berkeley`, ""},
+		{"title regexp", `title~"road (bike|frame)"`, "Road bike 54cm; Carbon road bike; Road frame"},
+		{"title regexp anchored", `title~^road`, "Road bike 54cm; Road frame"},
+
+		{"everything", `road bike -carbon price<800 hood:oakland`, "Road bike 54cm"},
+		{"fields or", `price<100|hood:berkeley`, "Carbon road bike; Kids bike \"like new\""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := titlesMatching(t, tt.filter); got != tt.want {
+				t.Errorf("%v:\n got %v\nwant %v", tt.filter, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseFilterErrors(t *testing.T) {
+	tests := []struct {
+		filter, want string
+	}{
+		{`"road bike`, "unterminated quote at position 1"},
+		{`bike "road`, "unterminated quote at position 6"},
+		{`(road|gravel`, `expected ) to close the ( at position 1, found "end of filter" at position 13`},
+		{`road)`, `unexpected ")" at position 5`},
+		{`road|`, `expected a term, found "end of filter" at position 6`},
+		{`|road`, `expected a term, found "|" at position 1`},
+		{`road -`, `expected a term, found "end of filter" at position 7`},
+		{`()`, `expected a term, found ")" at position 2`},
+		{`bike price<abc`, `invalid number "abc" for price at position 6`},
+		{`price:500`, `price is a number, use price<, >, <=, >= or = at position 1`},
+		{`hood<5`, `hood is not a number, use hood:, ~ or = at position 1`},
+		{`road color:red`, `unknown field "color"`},
+		{`hood:`, `missing value in "hood:" at position 1`},
+		{`bike title~"road (bike"`, `invalid regular expression "road (bike": missing closing ): ` + "`road (bike`" + ` at position 6`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.filter, func(t *testing.T) {
+			_, err := ParseFilter(tt.filter)
+			if err == nil {
+				t.Fatalf("ParseFilter(%q) should fail", tt.filter)
+			}
+
+			if !strings.HasPrefix(err.Error(), "invalid filter: ") || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("ParseFilter(%q):\n got %v\nwant %v", tt.filter, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyFilterSynonyms(t *testing.T) {
+	syn := Synonyms{"bicycle": {"bicycle", "bike"}}
+
+	got, err := applyFilter("bicycle -road", syn, filterEntries)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(got) != 2 || got[0].Title != "Gravel bike" {
+		t.Errorf("got %v", got)
+	}
+}
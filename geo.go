@@ -0,0 +1,70 @@
+package searchcraigs
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const milesPerKm = 0.621371
+
+// ErrNoLocation is returned by Search for a search distance without a postal code or location.
+var ErrNoLocation = errors.New("search distance requires a postal code or a location")
+
+// checkLocation checks the geographic search parameters: a search distance needs a postal code
+// or lat/lon (craigslist ignores it otherwise), and lat/lon must be valid coordinates.
+func checkLocation(params map[string]interface{}) error {
+	lat, hasLat := params["lat"].(float64)
+	lon, _ := params["lon"].(float64)
+
+	if hasLat && (lat < -90 || lat > 90 || lon < -180 || lon > 180) {
+		return fmt.Errorf("invalid location %v,%v", lat, lon)
+	}
+
+	if _, ok := params["search_distance"]; ok && !hasLat && params["postal_code"] == nil {
+		return ErrNoLocation
+	}
+
+	return nil
+}
+
+// ParseLatLon parses a location as "lat,lon" (i.e. "37.77,-122.42").
+func ParseLatLon(s string) (lat, lon float64, err error) {
+	slat, slon, ok := strings.Cut(s, ",")
+	if ok {
+		if lat, err = strconv.ParseFloat(strings.TrimSpace(slat), 64); err == nil {
+			lon, err = strconv.ParseFloat(strings.TrimSpace(slon), 64)
+		}
+	}
+
+	if !ok || err != nil || lat < -90 || lat > 90 || lon < -180 || lon > 180 {
+		return 0, 0, fmt.Errorf("invalid location %q (should be lat,lon)", s)
+	}
+
+	return lat, lon, nil
+}
+
+// parseDistance returns the distance in miles for strings like "2.3mi" or "3.7km".
+func parseDistance(s string) (float64, bool) {
+	s = strings.ToLower(strings.TrimSpace(s))
+
+	scale := 1.0
+
+	switch {
+	case strings.HasSuffix(s, "mi"):
+		s = strings.TrimSuffix(s, "mi")
+	case strings.HasSuffix(s, "km"):
+		s = strings.TrimSuffix(s, "km")
+		scale = milesPerKm
+	default:
+		return 0, false
+	}
+
+	d, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil || d < 0 {
+		return 0, false
+	}
+
+	return d * scale, true
+}
@@ -0,0 +1,185 @@
+package searchcraigs
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestLocationParams(t *testing.T) {
+	tests := []struct {
+		name    string
+		options []SearchOption
+		want    string
+	}{
+		{"location", []SearchOption{Location(37.77, -122.42)}, "lat=37.77&lon=-122.42"},
+		{"location and distance", []SearchOption{Location(37.77, -122.42), SearchDistance(5)},
+			"lat=37.77&lon=-122.42&search_distance=5"},
+		{"postal code and distance", []SearchOption{PostalCode("94110"), SearchDistance(3)},
+			"postal_code=94110&search_distance=3"},
+		{"postal code", []SearchOption{PostalCode("94110")}, "postal_code=94110"},
+		{"no distance", []SearchOption{PostalCode("94110"), SearchDistance(0)}, "postal_code=94110"},
+		{"with query", []SearchOption{Query("bike"), Location(37.7749, -122.4194), SearchDistance(10)},
+			"lat=37.7749&lon=-122.4194&query=bike&search_distance=10"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := testSearchURL(t, tt.options...).RawQuery; got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckLocation(t *testing.T) {
+	tests := []struct {
+		name    string
+		options []SearchOption
+		err     error
+		invalid bool
+	}{
+		{"none", nil, nil, false},
+		{"postal code", []SearchOption{PostalCode("94110"), SearchDistance(5)}, nil, false},
+		{"location", []SearchOption{Location(37.77, -122.42), SearchDistance(5)}, nil, false},
+		{"location without distance", []SearchOption{Location(37.77, -122.42)}, nil, false},
+		{"distance only", []SearchOption{SearchDistance(5)}, ErrNoLocation, false},
+		{"empty postal code", []SearchOption{PostalCode(""), SearchDistance(5)}, ErrNoLocation, false},
+		{"invalid latitude", []SearchOption{Location(91, 0), SearchDistance(5)}, nil, true},
+		{"invalid longitude", []SearchOption{Location(0, -181)}, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params := map[string]interface{}{}
+			for _, opt := range tt.options {
+				opt(params)
+			}
+
+			err := checkLocation(params)
+
+			switch {
+			case tt.err != nil:
+				if !errors.Is(err, tt.err) {
+					t.Errorf("got %v, want %v", err, tt.err)
+				}
+			case tt.invalid:
+				if err == nil {
+					t.Error("no error for an invalid location")
+				}
+			case err != nil:
+				t.Errorf("unexpected error %v", err)
+			}
+		})
+	}
+}
+
+// Search returns the error before sending any request
+func TestSearchNoLocation(t *testing.T) {
+	c, err := New(SFBay)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := c.Search(Query("bike"), SearchDistance(5)); !errors.Is(err, ErrNoLocation) {
+		t.Errorf("got %v, want %v", err, ErrNoLocation)
+	}
+}
+
+func TestParseLatLon(t *testing.T) {
+	tests := []struct {
+		s        string
+		lat, lon float64
+		ok       bool
+	}{
+		{"37.77,-122.42", 37.77, -122.42, true},
+		{" 37.77 , -122.42 ", 37.77, -122.42, true},
+		{"-33.87,151.21", -33.87, 151.21, true},
+		{"37.77", 0, 0, false},
+		{"37.77;-122.42", 0, 0, false},
+		{"north,west", 0, 0, false},
+		{"91,0", 0, 0, false},
+		{"0,181", 0, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			lat, lon, err := ParseLatLon(tt.s)
+			if (err == nil) != tt.ok || lat != tt.lat || lon != tt.lon {
+				t.Errorf("got %v, %v, %v, want %v, %v (ok %v)", lat, lon, err, tt.lat, tt.lon, tt.ok)
+			}
+		})
+	}
+}
+
+func TestParseDistance(t *testing.T) {
+	tests := []struct {
+		s    string
+		want float64
+		ok   bool
+	}{
+		{"2.3mi", 2.3, true},
+		{" 2.3 MI ", 2.3, true},
+		{"0mi", 0, true},
+		{"10km", 10 * milesPerKm, true},
+		{"map", 0, false},
+		{"mi", 0, false},
+		{"-1mi", 0, false},
+		{"oakland", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			got, ok := parseDistance(tt.s)
+			if ok != tt.ok || math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("got %v, %v, want %v, %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+// the distances in the results of a search with a search distance
+func TestParseResultDistances(t *testing.T) {
+	tests := []struct {
+		fixture string
+		want    map[string]float64 // title -> distance
+	}{
+		{"search-distance.html", map[string]float64{
+			"Fixie":       0.8, // distance span
+			"Road bike":   2.3, // part of the meta line
+			"Cargo bike":  8 * milesPerKm,
+			"Bike lights": 0,
+		}},
+		{"search-distance-legacy.html", map[string]float64{
+			"Fixie":     1.2,
+			"Road bike": 0, // "map"
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			var results SearchResults
+			parseResults(loadFixture(t, tt.fixture), "sfbay", "US", &results)
+
+			if len(results.Entries) != len(tt.want) {
+				t.Fatalf("%v entries, want %v", len(results.Entries), len(tt.want))
+			}
+
+			for _, e := range results.Entries {
+				want, ok := tt.want[e.Title]
+				if !ok {
+					t.Errorf("unexpected entry %q", e.Title)
+					continue
+				}
+
+				if math.Abs(e.Distance-want) > 1e-9 {
+					t.Errorf("%v: distance %v, want %v", e.Title, e.Distance, want)
+				}
+
+				if e.Neighborhood == "" || e.Meta != "" {
+					t.Errorf("%v: neighborhood %q, meta %q", e.Title, e.Neighborhood, e.Meta)
+				}
+			}
+		})
+	}
+}
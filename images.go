@@ -0,0 +1,155 @@
+package searchcraigs
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gobs/httpclient"
+)
+
+const (
+	defaultMaxEmbedSize = 200 * 1024       // max size of an embedded image
+	embedConcurrency    = 4                // images downloaded at the same time
+	embedTimeout        = 10 * time.Second // timeout for each image
+)
+
+// EmbedImages replaces the entry thumbnails with data: URIs, so that a saved page
+// doesn't depend on the craigslist image URLs (that expire). See EmbedImagesContext.
+// The images are downloaded with the client options (proxy, user agent, headers, rate limit).
+func (c *ClClient) EmbedImages(entries []ResultEntry) error {
+	return c.EmbedImagesContext(context.Background(), entries, defaultMaxEmbedSize)
+}
+
+// EmbedImagesContext is like EmbedImages, with images larger than maxSize bytes left as they are.
+// Images that can't be downloaded are also left as they are (the remote URL), the error
+// reports how many failed. The images in the DetailsCache (see WithDetailsCache) are not downloaded again.
+func (c *ClClient) EmbedImagesContext(ctx context.Context, entries []ResultEntry, maxSize int) error {
+	var urls []string
+	embedded := map[string]string{} // image URL -> data URI ("" if not embedded)
+	cached := 0
+
+	for _, e := range entries {
+		if _, ok := embedded[e.Image]; ok || !strings.HasPrefix(e.Image, "http") {
+			continue
+		}
+
+		embedded[e.Image] = ""
+
+		if c.detailsCache != nil {
+			if img, ok := c.detailsCache.Image(e.Image); ok && len(img.Data) <= dataURISize(maxSize) {
+				embedded[e.Image] = img.Data
+				cached++
+				continue
+			}
+		}
+
+		urls = append(urls, e.Image)
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	var failed []error
+
+	sem := make(chan struct{}, embedConcurrency)
+
+	for _, u := range urls {
+		wg.Add(1)
+
+		go func(u string) {
+			defer wg.Done()
+
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			data, err := c.fetchDataURI(ctx, u, maxSize)
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			if err != nil {
+				failed = append(failed, err)
+				return
+			}
+
+			embedded[u] = data
+
+			// the images too large are not cached, they could fit a larger maxSize
+			if c.detailsCache != nil && data != "" {
+				// recorded now, so that an interrupted run doesn't download it again
+				if err := c.detailsCache.AddImage(u, data); err != nil {
+					c.debug("cannot record the image", "error", err)
+				}
+			}
+		}(u)
+	}
+
+	wg.Wait()
+
+	if cached > 0 {
+		c.debug("cached images", "count", cached)
+	}
+
+	for i, e := range entries {
+		if data := embedded[e.Image]; data != "" {
+			entries[i].Image = data
+		}
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("could not embed %v of %v images (first error: %w)", len(failed), len(urls), failed[0])
+	}
+
+	return nil
+}
+
+// dataURISize returns the max length of the data: URI of an image of maxSize bytes
+// (base64 and the prefix), to check the cached images against a smaller -max-embed-size.
+func dataURISize(maxSize int) int {
+	return len("data:image/jpeg;base64,") + base64.StdEncoding.EncodedLen(maxSize)
+}
+
+// fetchDataURI downloads the image at url as a data: URI, with a timeout of embedTimeout
+// from when the request is sent (not counting the rate limiter wait).
+// It returns "" with no error for images larger than maxSize.
+func (c *ClClient) fetchDataURI(ctx context.Context, url string, maxSize int) (string, error) {
+	resp, err := c.sendTimeout(ctx, embedTimeout, httpclient.URLString(url), httpclient.Accept("image/*"))
+	if err != nil {
+		return "", err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%v: %v", url, resp.Status)
+	}
+
+	if resp.ContentLength > int64(maxSize) {
+		return "", nil
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, int64(maxSize)+1))
+	if err != nil {
+		return "", fmt.Errorf("%v: %w", url, err)
+	}
+
+	if len(data) > maxSize {
+		return "", nil
+	}
+
+	mimeType := http.DetectContentType(data)
+	if !strings.HasPrefix(mimeType, "image/") {
+		return "", fmt.Errorf("%v: not an image (%v)", url, mimeType)
+	}
+
+	return fmt.Sprintf("data:%v;base64,%v", mimeType, base64.StdEncoding.EncodeToString(data)), nil
+}
@@ -0,0 +1,136 @@
+package searchcraigs
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gobs/httpclient"
+)
+
+func testPNG(t *testing.T, size int) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, size, size))
+	for i := range img.Pix {
+		img.Pix[i] = byte(i * 7)
+	}
+
+	img.Set(0, 0, color.White)
+
+	var b bytes.Buffer
+	if err := png.Encode(&b, img); err != nil {
+		t.Fatal(err)
+	}
+
+	return b.Bytes()
+}
+
+func TestEmbedImages(t *testing.T) {
+	small, large := testPNG(t, 4), testPNG(t, 64)
+	const images = "https://images.craigslist.org"
+
+	s := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/small.png":
+			w.Write(small)
+		case "/large.png":
+			w.Write(large)
+		case "/text.png":
+			w.Write([]byte("not an image"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+
+	c := s.client(t, WithUserAgent("test-agent"))
+
+	entries := []ResultEntry{
+		{Image: images + "/small.png"},
+		{Image: images + "/large.png"},
+		{Image: images + "/missing.png"},
+		{Image: images + "/text.png"},
+		{Image: images + "/small.png"},
+		{},
+	}
+
+	err := c.EmbedImagesContext(context.Background(), entries, len(small)+10)
+	if err == nil || !strings.Contains(err.Error(), "2 of 4") {
+		t.Errorf("got error %v, want 2 of 4 failed", err)
+	}
+
+	want := "data:image/png;base64," + base64.StdEncoding.EncodeToString(small)
+
+	if entries[0].Image != want || entries[4].Image != want {
+		t.Errorf("small image not embedded: %.40v", entries[0].Image)
+	}
+
+	for i, e := range entries[1:4] {
+		if !strings.HasPrefix(e.Image, images) {
+			t.Errorf("entry %v: got %.40v, want the remote URL", i+1, e.Image)
+		}
+	}
+
+	requests := s.received()
+	if len(requests) != 4 {
+		t.Errorf("%v requests, want 4 (one per image)", len(requests))
+	}
+
+	for _, r := range requests {
+		if r.userAgent != "test-agent" {
+			t.Errorf("user agent %q, the images should be downloaded with the client", r.userAgent)
+		}
+	}
+}
+
+// the per-image timeout starts when the request is sent, not while waiting for the rate limiter
+func TestSendTimeoutRateLimit(t *testing.T) {
+	small := testPNG(t, 4)
+
+	s := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow.png" {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(time.Second):
+			}
+		}
+
+		w.Write(small)
+	}))
+
+	const timeout = 50 * time.Millisecond
+
+	c := s.client(t)
+	c.limiter = &rateLimiter{interval: 2 * timeout}
+
+	for i := range 3 {
+		res, err := c.sendTimeout(context.Background(), timeout, httpclient.URLString("https://images.craigslist.org/small.png"))
+		if err != nil {
+			t.Fatalf("request %v: %v", i, err)
+		}
+
+		data, err := io.ReadAll(res.Body)
+		res.Body.Close()
+
+		if err != nil || !bytes.Equal(data, small) {
+			t.Errorf("request %v: %v bytes, %v", i, len(data), err)
+		}
+	}
+
+	res, err := c.sendTimeout(context.Background(), timeout, httpclient.URLString("https://images.craigslist.org/slow.png"))
+	if err == nil {
+		_, err = io.ReadAll(res.Body)
+		res.Body.Close()
+	}
+
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("slow image: got %v, want context.DeadlineExceeded", err)
+	}
+}
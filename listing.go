@@ -0,0 +1,241 @@
+package searchcraigs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/gobs/httpclient"
+)
+
+// maximum number of listings fetched at the same time
+const maxListingWorkers = 2
+
+// ErrPostingGone is returned by GetListing when the posting was deleted or expired.
+var ErrPostingGone = errors.New("posting has been deleted or expired")
+
+// Listing is the content of a posting page.
+type Listing struct {
+	Href        string
+	Title       string
+	Price       string
+	Description string
+	Images      []string
+	Attributes  []ListingAttribute
+	Latitude    float64 `json:",omitempty"`
+	Longitude   float64 `json:",omitempty"`
+	Posted      time.Time
+	Updated     time.Time
+	SellerHref  string `json:",omitempty"` // "more ads by this user" page, mostly for dealers
+}
+
+// ListingAttribute is one of the attributes (condition, make/model, odometer, etc.) of a listing.
+// Some attributes (i.e. the make/model of a car) have no name.
+type ListingAttribute struct {
+	Name  string `json:",omitempty"`
+	Value string
+}
+
+// GetListing fetches and parses the posting page at href.
+func (c *ClClient) GetListing(href string) (*Listing, error) {
+	return c.GetListingContext(context.Background(), href)
+}
+
+// GetListingContext is like GetListing, but the request is cancelled when ctx is done.
+func (c *ClClient) GetListingContext(ctx context.Context, href string) (*Listing, error) {
+	res, err := c.send(ctx, httpclient.URLString(href), httpclient.Accept("*/*"))
+	if err == nil && (res.StatusCode == http.StatusNotFound || res.StatusCode == http.StatusGone) {
+		res.Body.Close()
+		return nil, ErrPostingGone
+	}
+
+	res, err = httpclient.CheckStatus(res, err)
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err := c.readDocument(res)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseListing(href, c.regionCountry(regionOf(href)), doc)
+}
+
+func parseListing(href, country string, doc *goquery.Document) (*Listing, error) {
+	if doc.Find("#postingbody").Length() == 0 {
+		text := doc.Find("body").Text()
+		if doc.Find(".removed").Length() > 0 ||
+			strings.Contains(text, "has been deleted") ||
+			strings.Contains(text, "has expired") ||
+			strings.Contains(text, "has been flagged for removal") {
+			return nil, ErrPostingGone
+		}
+
+		return nil, fmt.Errorf("%v: no posting body", href)
+	}
+
+	listing := Listing{
+		Href:  href,
+		Title: strings.TrimSpace(doc.Find("#titletextonly").First().Text()),
+		Price: strings.TrimSpace(doc.Find(".postingtitletext .price").First().Text()),
+	}
+
+	body := doc.Find("#postingbody").First().Clone()
+	body.Find(".print-information, .print-qrcode-container").Remove()
+	listing.Description = strings.TrimSpace(body.Text())
+
+	doc.Find("#thumbs a").Each(func(i int, s *goquery.Selection) {
+		if src, ok := s.Attr("href"); ok {
+			listing.Images = append(listing.Images, src)
+		}
+	})
+
+	if len(listing.Images) == 0 {
+		doc.Find(".gallery img, .swipe img").Each(func(i int, s *goquery.Selection) {
+			if src, ok := s.Attr("src"); ok {
+				listing.Images = append(listing.Images, src)
+			}
+		})
+	}
+
+	doc.Find(".attrgroup span, .attrgroup .attr").Each(func(i int, s *goquery.Selection) {
+		if s.Parent().HasClass("attr") { // .labl/.valu spans of the newer markup
+			return
+		}
+
+		text := strings.Join(strings.Fields(s.Text()), " ")
+		if text == "" {
+			return
+		}
+
+		if name, value, ok := strings.Cut(text, ":"); ok {
+			listing.Attributes = append(listing.Attributes,
+				ListingAttribute{Name: strings.TrimSpace(name), Value: strings.TrimSpace(value)})
+		} else {
+			listing.Attributes = append(listing.Attributes, ListingAttribute{Value: text})
+		}
+	})
+
+	doc.Find("a").EachWithBreak(func(i int, s *goquery.Selection) bool {
+		if !strings.Contains(strings.ToLower(s.Text()), "more ads by this user") {
+			return true
+		}
+
+		if link, ok := s.Attr("href"); ok {
+			listing.SellerHref, _ = resolveURL(href, link)
+		}
+
+		return false
+	})
+
+	if m := doc.Find("#map").First(); m.Length() > 0 {
+		lat, _ := m.Attr("data-latitude")
+		lon, _ := m.Attr("data-longitude")
+		listing.Latitude, _ = strconv.ParseFloat(lat, 64)
+		listing.Longitude, _ = strconv.ParseFloat(lon, 64)
+	}
+
+	doc.Find(".postinginfos .postinginfo").Each(func(i int, s *goquery.Selection) {
+		tm := s.Find("time")
+		if tm.Length() == 0 {
+			return
+		}
+
+		datetime, _ := tm.Attr("datetime")
+
+		t, err := time.Parse("2006-01-02T15:04:05-0700", datetime)
+		if err != nil {
+			// some pages only have the date as text, day first outside the US
+			var ok bool
+			if t, ok = parseLocalDate(tm.Text(), country); !ok {
+				return
+			}
+		}
+
+		switch text := strings.ToLower(s.Text()); {
+		case strings.Contains(text, "posted"):
+			listing.Posted = t
+		case strings.Contains(text, "updated"):
+			listing.Updated = t
+		}
+	})
+
+	return &listing, nil
+}
+
+// GetDetails fetches the listing for the first n entries (all if n <= 0), a few at a time,
+// and stores it in the entry Details.
+// Entries that already have Details or are in the DetailsCache (see WithDetailsCache) and deleted
+// or expired postings are skipped. Other errors are returned, joined.
+func (c *ClClient) GetDetails(entries []ResultEntry, n int) error {
+	return c.GetDetailsContext(context.Background(), entries, n)
+}
+
+// GetDetailsContext is like GetDetails, but stops fetching (returning ctx.Err()) when ctx is done.
+func (c *ClClient) GetDetailsContext(ctx context.Context, entries []ResultEntry, n int) error {
+	if n <= 0 || n > len(entries) {
+		n = len(entries)
+	}
+
+	if c.detailsCache != nil {
+		c.detailsCache.Apply(entries[:n])
+	}
+
+	jobs := make(chan int)
+	errs := make([]error, n)
+
+	var wg sync.WaitGroup
+
+	for w := 0; w < maxListingWorkers; w++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for i := range jobs {
+				listing, err := c.GetListingContext(ctx, entries[i].Href)
+				if err == nil {
+					entries[i].Details = listing
+
+					if c.detailsCache != nil {
+						// recorded now, so that an interrupted run doesn't fetch it again
+						if err := c.detailsCache.AddListing(entries[i].Href, listing); err != nil {
+							c.debug("cannot record the listing", "error", err)
+						}
+					}
+				} else if !errors.Is(err, ErrPostingGone) {
+					errs[i] = fmt.Errorf("%v: %w", entries[i].Href, err)
+				}
+			}
+		}()
+	}
+
+loop:
+	for i := 0; i < n; i++ {
+		if entries[i].Details != nil {
+			continue
+		}
+
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			break loop
+		}
+	}
+
+	close(jobs)
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return errors.Join(errs...)
+}
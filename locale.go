@@ -0,0 +1,190 @@
+package searchcraigs
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// regionCountries is the country of the regions outside the US (ISO 3166 codes), used when
+// the region list (see ListRegions) is not available. Regions not listed here are in the US.
+var regionCountries = map[Region]string{
+	"toronto":   "CA",
+	"vancouver": "CA",
+	"montreal":  "CA",
+	"calgary":   "CA",
+	"edmonton":  "CA",
+	"ottawa":    "CA",
+	"winnipeg":  "CA",
+	"victoria":  "CA",
+	"halifax":   "CA",
+	"quebec":    "CA",
+	"londonon":  "CA",
+
+	"london":     "GB",
+	"manchester": "GB",
+	"birmingham": "GB",
+	"edinburgh":  "GB",
+	"glasgow":    "GB",
+	"bristol":    "GB",
+	"liverpool":  "GB",
+	"leeds":      "GB",
+
+	"dublin":    "IE",
+	"paris":     "FR",
+	"berlin":    "DE",
+	"madrid":    "ES",
+	"barcelona": "ES",
+	"rome":      "IT",
+	"milan":     "IT",
+	"amsterdam": "NL",
+}
+
+// countryCurrencies is the currency (ISO 4217 code) of the prices in each country
+var countryCurrencies = map[string]string{
+	"US": "USD",
+	"CA": "CAD",
+	"GB": "GBP",
+	"IE": "EUR",
+	"FR": "EUR",
+	"DE": "EUR",
+	"ES": "EUR",
+	"IT": "EUR",
+	"NL": "EUR",
+}
+
+// currencySymbols are the price prefixes (and suffixes) recognized, longest first.
+// A plain $ is the dollar of the region's country.
+var currencySymbols = []struct {
+	symbol   string
+	currency string
+}{
+	{"CA$", "CAD"},
+	{"US$", "USD"},
+	{"C$", "CAD"},
+	{"£", "GBP"},
+	{"€", "EUR"},
+	{"$", ""},
+}
+
+// countryOf returns the country of the region in regionCountries (US if unknown).
+// The clients use the country in the region list instead, see ClClient.regionCountry.
+func countryOf(region Region) string {
+	if c, ok := regionCountries[region]; ok {
+		return c
+	}
+
+	return "US"
+}
+
+// regionOf returns the region of a craigslist URL (i.e. toronto for https://toronto.craigslist.org/...).
+func regionOf(href string) Region {
+	u, err := url.Parse(href)
+	if err != nil {
+		return ""
+	}
+
+	region, _, _ := strings.Cut(u.Hostname(), ".")
+	return Region(region)
+}
+
+// priceCurrency returns the currency of a price like "CA$1,200" or "£50".
+// Prices without a symbol (or with a plain $) are in the currency of the region's country.
+func priceCurrency(price string, country string) string {
+	price = strings.TrimSpace(price)
+
+	for _, cs := range currencySymbols {
+		if cs.currency != "" && (strings.HasPrefix(price, cs.symbol) || strings.HasSuffix(price, cs.symbol)) {
+			return cs.currency
+		}
+	}
+
+	return countryCurrencies[country]
+}
+
+// decimalSeparator returns the decimal separator used in the prices in currency
+// (the thousands separator is the other one of "." and ",").
+func decimalSeparator(currency string) byte {
+	if currency == "EUR" {
+		return ','
+	}
+
+	return '.'
+}
+
+// currencySymbol returns the symbol used to print amounts in currency.
+func currencySymbol(currency string) string {
+	switch currency {
+	case "", "USD":
+		return "$"
+	case "CAD":
+		return "CA$"
+	case "GBP":
+		return "£"
+	case "EUR":
+		return "€"
+	}
+
+	return currency + " "
+}
+
+// parseLocalDate parses numeric dates like 20/08/2021 or 08/20/2021 (with an optional hh:mm time),
+// as found in the detail pages. When both orders are possible the country decides:
+// month first in the US, day first everywhere else.
+func parseLocalDate(s, country string) (time.Time, bool) {
+	date, clock, _ := strings.Cut(strings.TrimSpace(s), " ")
+
+	parts := strings.FieldsFunc(date, func(r rune) bool { return r == '/' || r == '.' || r == '-' })
+	if len(parts) != 3 {
+		return time.Time{}, false
+	}
+
+	var n [3]int
+	for i, p := range parts {
+		v, err := strconv.Atoi(p)
+		if err != nil {
+			return time.Time{}, false
+		}
+
+		n[i] = v
+	}
+
+	day, month, year := n[0], n[1], n[2]
+
+	switch {
+	case n[0] > 31: // yyyy-mm-dd
+		day, year = n[2], n[0]
+	case day > 12: // unambiguous, day first
+	case month > 12: // unambiguous, month first
+		day, month = month, day
+	case country == "US":
+		day, month = month, day
+	}
+
+	if year < 100 {
+		year += 2000
+	}
+
+	var hour, min int
+	if clock != "" {
+		t, err := time.Parse("15:04", strings.TrimSpace(clock))
+		if err != nil {
+			return time.Time{}, false
+		}
+
+		hour, min = t.Hour(), t.Minute()
+	}
+
+	if month < 1 || month > 12 || day < 1 || day > 31 {
+		return time.Time{}, false
+	}
+
+	// time.Date normalizes impossible dates (31/02 is 03/03), that are not dates in this order
+	t := time.Date(year, time.Month(month), day, hour, min, 0, 0, time.Local)
+	if t.Day() != day || t.Month() != time.Month(month) {
+		return time.Time{}, false
+	}
+
+	return t, true
+}
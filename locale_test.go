@@ -0,0 +1,189 @@
+package searchcraigs
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParsePrice(t *testing.T) {
+	tests := []struct {
+		price    string
+		currency string
+		want     int
+	}{
+		{"$1,250", "USD", 1250},
+		{"$1,250.99", "USD", 1250},
+		{"$12.5", "USD", 12},
+		{"$1,234,567", "USD", 1234567},
+		{"CA$1,200", "CAD", 1200},
+		{"C$2,450.50", "CAD", 2450},
+		{"£50", "GBP", 50},
+		{"£12.50", "GBP", 12},
+		{"£1,200", "GBP", 1200},
+		{"€1.200", "EUR", 1200},
+		{"€1,5", "EUR", 1},
+		{"€1,50", "EUR", 1},
+		{"1.234,50 €", "EUR", 1234},
+		{"1.234.567 €", "EUR", 1234567},
+		{"1 200 €", "EUR", 1200},
+		{"1 200,00 €", "EUR", 1200},
+		{"€1,2345", "EUR", 1},
+		{"$1.2345", "USD", 1},
+		{"$1,2345", "USD", 12345},
+		{"", "USD", 0},
+		{"free", "USD", 0},
+		{"$", "USD", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.price, func(t *testing.T) {
+			if got := parsePrice(tt.price, tt.currency); got != tt.want {
+				t.Errorf("parsePrice(%q, %v) = %v, want %v", tt.price, tt.currency, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPriceCurrency(t *testing.T) {
+	tests := []struct {
+		price  string
+		region Region
+		want   string
+	}{
+		{"$1,250", SFBay, "USD"},
+		{"$1,250", "toronto", "CAD"},
+		{"1,250", "toronto", "CAD"},
+		{"CA$1,200", SFBay, "CAD"},
+		{"C$1,200", "toronto", "CAD"},
+		{"US$1,200", "toronto", "USD"},
+		{"£50", "london", "GBP"},
+		{"€1.200", "london", "EUR"},
+		{"1.234,50 €", "london", "EUR"},
+		{"1,200", "london", "GBP"},
+		{"$50", "paris", "EUR"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.price+" "+string(tt.region), func(t *testing.T) {
+			if got := priceCurrency(tt.price, countryOf(tt.region)); got != tt.want {
+				t.Errorf("priceCurrency(%q, %v) = %v, want %v", tt.price, tt.region, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseLocalDate(t *testing.T) {
+	tests := []struct {
+		date    string
+		country string
+		want    time.Time
+		ok      bool
+	}{
+		{"08/09/2024", "US", time.Date(2024, 8, 9, 0, 0, 0, 0, time.Local), true},
+		{"08/09/2024", "GB", time.Date(2024, 9, 8, 0, 0, 0, 0, time.Local), true},
+		{"08/09/2024", "CA", time.Date(2024, 9, 8, 0, 0, 0, 0, time.Local), true},
+		{"20/08/2024", "US", time.Date(2024, 8, 20, 0, 0, 0, 0, time.Local), true},
+		{"08/20/2024", "GB", time.Date(2024, 8, 20, 0, 0, 0, 0, time.Local), true},
+		{"20.08.24 13:45", "DE", time.Date(2024, 8, 20, 13, 45, 0, 0, time.Local), true},
+		{"2024-08-20", "GB", time.Date(2024, 8, 20, 0, 0, 0, 0, time.Local), true},
+		{"20/20/2024", "GB", time.Time{}, false},
+		{"31/02/2024", "GB", time.Time{}, false},
+		{"02/31/2024", "US", time.Time{}, false},
+		{"02/30/2024", "GB", time.Time{}, false},
+		{"31/04/2024", "CA", time.Time{}, false},
+		{"29/02/2023", "GB", time.Time{}, false},
+		{"29/02/2024", "GB", time.Date(2024, 2, 29, 0, 0, 0, 0, time.Local), true},
+		{"2024-02-30", "GB", time.Time{}, false},
+		{"20/08", "GB", time.Time{}, false},
+		{"20/08/2024 1pm", "GB", time.Time{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.date+" "+tt.country, func(t *testing.T) {
+			got, ok := parseLocalDate(tt.date, tt.country)
+			if ok != tt.ok || !got.Equal(tt.want) {
+				t.Errorf("parseLocalDate(%q, %v) = %v, %v, want %v, %v", tt.date, tt.country, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+// the prices in the toronto and london search pages
+func TestParseRegionFixtures(t *testing.T) {
+	type price struct {
+		Price    string
+		Value    int
+		Currency string
+	}
+
+	tests := []struct {
+		fixture string
+		region  string
+		want    []price
+		stats   PriceStats
+	}{
+		{
+			fixture: "search-toronto.html",
+			region:  "toronto",
+			want: []price{
+				{"CA$1,200", 1200, "CAD"},
+				{"$85", 85, "CAD"},
+				{"C$2,450.50", 2450, "CAD"},
+				{"", 0, "CAD"},
+			},
+			stats: PriceStats{Count: 4, Priced: 3, Currency: "CAD", Min: 85, Max: 2450, Median: 1200, Mean: 1245},
+		},
+		{
+			fixture: "search-london.html",
+			region:  "london",
+			want: []price{
+				{"£1,200", 1200, "GBP"},
+				{"£12.50", 12, "GBP"},
+				{"€1.200", 1200, "EUR"},
+				{"1.234,50 €", 1234, "EUR"},
+				{"£8", 8, "GBP"},
+			},
+			stats: PriceStats{Count: 5, Currencies: []string{"EUR", "GBP"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.region, func(t *testing.T) {
+			var results SearchResults
+			parseResults(loadFixture(t, tt.fixture), tt.region, countryOf(Region(tt.region)), &results)
+
+			var got []price
+			for _, e := range results.Entries {
+				got = append(got, price{e.Price, e.PriceValue, e.Currency})
+
+				if e.Region != tt.region {
+					t.Errorf("%v: region %q, want %q", e.Title, e.Region, tt.region)
+				}
+
+				if e.Posted.IsZero() {
+					t.Errorf("%v: no posting date (%q)", e.Title, e.Datetime)
+				}
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("prices:\n got  %v\n want %v", got, tt.want)
+			}
+
+			if stats := ComputePriceStats(results.Entries, ""); !reflect.DeepEqual(stats, tt.stats) {
+				t.Errorf("stats:\n got  %+v\n want %+v", stats, tt.stats)
+			}
+		})
+	}
+}
+
+// with mixed currencies the stats are only computed with an assumed currency
+func TestPriceStatsAssumeCurrency(t *testing.T) {
+	var results SearchResults
+	parseResults(loadFixture(t, "search-london.html"), "london", "GB", &results)
+
+	want := PriceStats{Count: 5, Priced: 5, Currency: "GBP", Min: 8, Max: 1234, Median: 1200, Mean: 730.8}
+	if stats := ComputePriceStats(results.Entries, "GBP"); !reflect.DeepEqual(stats, want) {
+		t.Errorf("got  %+v\nwant %+v", stats, want)
+	}
+}
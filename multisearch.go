@@ -0,0 +1,118 @@
+package searchcraigs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// maximum number of regions searched at the same time
+const maxRegionWorkers = 4
+
+// MultiSearch runs the same search in all the specified regions.
+func (c *ClClient) MultiSearch(regions []Region, options ...SearchOption) (*SearchResults, error) {
+	return c.MultiSearchAllContext(context.Background(), 1, regions, options...)
+}
+
+// MultiSearchAll runs the same search in all the specified regions, concurrently,
+// fetching up to maxPages pages per region.
+//
+// The entries are merged (removing duplicates across regions if requested)
+// and sorted by date, most recent first.
+// If some of the searches fail the results from the other regions are still returned,
+// together with an error for each failed region.
+func (c *ClClient) MultiSearchAll(maxPages int, regions []Region, options ...SearchOption) (*SearchResults, error) {
+	return c.MultiSearchAllContext(context.Background(), maxPages, regions, options...)
+}
+
+// MultiSearchAllContext is like MultiSearchAll, but all the searches are cancelled when ctx is done.
+func (c *ClClient) MultiSearchAllContext(ctx context.Context, maxPages int, regions []Region, options ...SearchOption) (*SearchResults, error) {
+	type regionResults struct {
+		res *SearchResults
+		err error
+	}
+
+	all := make([]regionResults, len(regions))
+	jobs := make(chan int)
+
+	var wg sync.WaitGroup
+
+	for w := 0; w < maxRegionWorkers && w < len(regions); w++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for i := range jobs {
+				opts := append([]SearchOption{WithRegion(regions[i])}, options...)
+				res, err := c.SearchAllContext(ctx, maxPages, opts...)
+				if err != nil {
+					err = fmt.Errorf("%v: %w", regions[i], err)
+				}
+
+				all[i] = regionResults{res: res, err: err}
+			}
+		}()
+	}
+
+	for i := range regions {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			all[i].err = fmt.Errorf("%v: %w", regions[i], ctx.Err())
+		}
+	}
+
+	close(jobs)
+	wg.Wait()
+
+	params := map[string]interface{}{}
+	for _, opt := range options {
+		opt(params)
+	}
+
+	results := SearchResults{Title: "Results"}
+	if q, ok := params["query"]; ok {
+		results.Title = q.(string)
+	}
+
+	if params["bundleDuplicates"] != nil {
+		results.seen = map[uint64]bool{}
+	}
+
+	var names []string
+	var errs []error
+
+	for i, r := range all {
+		if r.err != nil {
+			errs = append(errs, r.err)
+		}
+
+		if r.res == nil {
+			continue
+		}
+
+		if results.Url == "" {
+			results.Url = r.res.Url
+		}
+
+		names = append(names, string(regions[i]))
+		results.TotalCount += r.res.TotalCount
+
+		for _, entry := range r.res.Entries {
+			results.add(entry)
+		}
+	}
+
+	results.Subtitle = "Regions: " + strings.Join(names, ", ")
+
+	SortEntries(results.Entries, Date)
+
+	if len(names) == 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	return &results, errors.Join(errs...)
+}
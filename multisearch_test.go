@@ -0,0 +1,72 @@
+package searchcraigs
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// the results of the regions that didn't fail are merged, without duplicates and most recent first
+func TestMultiSearchPartial(t *testing.T) {
+	pages := map[string]testResponse{
+		"sfbay.craigslist.org": searchPageRows("",
+			testRow{"road bike", "2026-05-01 10:00"}, testRow{"bmx", "2026-05-03 10:00"}, testRow{"tandem", "2026-05-02 10:00"}),
+		"seattle.craigslist.org": {status: http.StatusInternalServerError, body: "error"},
+		"portland.craigslist.org": searchPageRows("",
+			testRow{"road bike", "2026-05-01 10:00"}, testRow{"fixie", "2026-05-02 10:00"}, testRow{"cruiser", "2026-05-04 10:00"}),
+	}
+
+	s := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page, ok := pages[r.Host]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+
+		responses(page)(w, r)
+	}))
+
+	res, err := s.client(t).MultiSearch([]Region{SFBay, "seattle", "portland"}, Query("bike"), Dedup(true))
+	if err == nil || !strings.Contains(err.Error(), "seattle") {
+		t.Errorf("got error %v, want an error for seattle", err)
+	}
+
+	if err != nil && strings.Contains(err.Error(), "sfbay") {
+		t.Errorf("error for sfbay: %v", err)
+	}
+
+	if res == nil {
+		t.Fatal("no results")
+	}
+
+	var titles []string
+	for _, e := range res.Entries {
+		titles = append(titles, e.Title+" "+string(e.Region))
+	}
+
+	// the road bike is in sfbay and portland, the tandem and the fixie have the same date
+	want := []string{"cruiser portland", "bmx sfbay", "tandem sfbay", "fixie portland", "road bike sfbay"}
+	if !slices.Equal(titles, want) {
+		t.Errorf("entries %q, want %q", titles, want)
+	}
+
+	if res.Subtitle != "Regions: sfbay, portland" {
+		t.Errorf("subtitle %q", res.Subtitle)
+	}
+}
+
+func TestMultiSearchAllFailed(t *testing.T) {
+	s := newTestServer(t, responses(testResponse{status: http.StatusInternalServerError}))
+
+	res, err := s.client(t).MultiSearch([]Region{SFBay, "seattle"}, Query("bike"))
+	if res != nil || err == nil {
+		t.Fatalf("got %v, %v, want no results and an error", res, err)
+	}
+
+	for _, region := range []string{"sfbay", "seattle"} {
+		if !strings.Contains(err.Error(), region) {
+			t.Errorf("error %q without %v", err, region)
+		}
+	}
+}
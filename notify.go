@@ -0,0 +1,269 @@
+package searchcraigs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os/exec"
+	"runtime"
+	"strings"
+	"text/template"
+	"time"
+)
+
+const (
+	maxNotifications = 10               // entries notified in each watch cycle, the others are summarized
+	notifyTimeout    = 10 * time.Second // timeout for each notification
+)
+
+// delay before retrying a failed notification
+var notifyRetryDelay = 5 * time.Second
+
+// A Notifier returns the notifications for the new entries found by -watch, with the results title.
+type Notifier func(title string, entries []ResultEntry) ([]Notification, error)
+
+// Notification is a desktop notification or a webhook request.
+type Notification struct {
+	Title   string
+	Message string // the notification text, or the webhook payload
+
+	send func(ctx context.Context) error
+}
+
+// Send shows the notification, or sends the webhook request.
+func (n Notification) Send(ctx context.Context) error {
+	return n.send(ctx)
+}
+
+// NotifyData is what's passed to the -notify-template templates.
+type NotifyData struct {
+	Title   string        // the results title (see -title)
+	Count   int           // number of new entries
+	Entries []ResultEntry // the first new entries (up to maxNotifications)
+	More    int           // new entries not in Entries
+}
+
+// webhookPayload is the default webhook payload.
+type webhookPayload struct {
+	Title   string        `json:"title"`
+	Count   int           `json:"count"`
+	Entries []ResultEntry `json:"entries"`
+	More    int           `json:"more"` // new entries not in Entries
+}
+
+// NewNotifier returns the notifier for target: "desktop" for the desktop notifications,
+// or a webhook URL. The webhook gets a JSON object with the title, the count of new entries,
+// the first ones (up to maxNotifications) as entries and the count of the others as more,
+// or the payload built from the NotifyData by the text template payload
+// (i.e. `{"text": {{ json .Title }}}`) if not empty.
+func NewNotifier(target, payload string) (Notifier, error) {
+	if target == "desktop" {
+		if payload != "" {
+			return nil, fmt.Errorf("a notification template can only be used with a webhook")
+		}
+
+		return desktopNotifier, nil
+	}
+
+	if !strings.HasPrefix(target, "http://") && !strings.HasPrefix(target, "https://") {
+		return nil, fmt.Errorf("invalid notification target %q (should be desktop or a webhook URL)", target)
+	}
+
+	var t *template.Template
+	if payload != "" {
+		var err error
+
+		if t, err = template.New("notify").Funcs(templateFuncs).Option("missingkey=error").Parse(payload); err != nil {
+			return nil, err
+		}
+	}
+
+	return func(title string, entries []ResultEntry) ([]Notification, error) {
+		var body []byte
+		var err error
+
+		data := newNotifyData(title, entries)
+
+		if t == nil {
+			body, err = json.Marshal(webhookPayload{Title: data.Title, Count: data.Count, Entries: data.Entries, More: data.More})
+		} else {
+			var b bytes.Buffer
+			err = t.Execute(&b, data)
+			body = b.Bytes()
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		return []Notification{{
+			Title:   target,
+			Message: string(body),
+			send:    func(ctx context.Context) error { return postWebhook(ctx, target, body) },
+		}}, nil
+	}, nil
+}
+
+func newNotifyData(title string, entries []ResultEntry) NotifyData {
+	data := NotifyData{Title: title, Count: len(entries), Entries: entries}
+
+	if len(entries) > maxNotifications {
+		data.Entries = entries[:maxNotifications]
+		data.More = len(entries) - maxNotifications
+	}
+
+	return data
+}
+
+func postWebhook(ctx context.Context, url string, body []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, notifyTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+
+	resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook %v: %v", url, resp.Status)
+	}
+
+	return nil
+}
+
+// desktopNotifier returns a notification for each entry (up to maxNotifications) and one for the others.
+func desktopNotifier(title string, entries []ResultEntry) ([]Notification, error) {
+	data := newNotifyData(title, entries)
+
+	var notifications []Notification
+
+	for _, e := range data.Entries {
+		var info []string
+		if e.HasPrice() && e.Price != "" {
+			info = append(info, e.Price)
+		}
+
+		if hood := strings.TrimSpace(e.Neighborhood); hood != "" {
+			info = append(info, hood)
+		}
+
+		notifications = append(notifications, desktopNotification(e.Title, strings.TrimSpace(strings.Join(info, ", ")+"\n"+e.Href)))
+	}
+
+	if data.More > 0 {
+		notifications = append(notifications, desktopNotification(title, fmt.Sprintf("+%v more new listings", data.More)))
+	}
+
+	return notifications, nil
+}
+
+func desktopNotification(title, message string) Notification {
+	return Notification{
+		Title:   title,
+		Message: message,
+		send:    func(ctx context.Context) error { return notifyDesktop(ctx, title, message) },
+	}
+}
+
+// notifyDesktop shows a desktop notification.
+func notifyDesktop(ctx context.Context, title, message string) error {
+	ctx, cancel := context.WithTimeout(ctx, notifyTimeout)
+	defer cancel()
+
+	var cmd *exec.Cmd
+
+	switch runtime.GOOS {
+	case "linux":
+		cmd = exec.CommandContext(ctx, "notify-send", "--app-name=searchcraigs", title, message)
+	case "darwin":
+		script := fmt.Sprintf("display notification %v with title %v", appleScriptString(message), appleScriptString(title))
+		cmd = exec.CommandContext(ctx, "osascript", "-e", script)
+	case "windows":
+		cmd = exec.CommandContext(ctx, "powershell", "-NoProfile", "-Command", fmt.Sprintf(windowsToast,
+			powershellString(title), powershellString(message)))
+	default:
+		return fmt.Errorf("unsupported platform")
+	}
+
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%v: %w %s", cmd.Path, err, bytes.TrimSpace(out))
+	}
+
+	return nil
+}
+
+// shows a toast notification with a title (%[1]v) and a message (%[2]v), as PowerShell strings
+const windowsToast = `$t = [Windows.UI.Notifications.ToastNotificationManager, Windows.UI.Notifications, ContentType = WindowsRuntime]::GetTemplateContent([Windows.UI.Notifications.ToastTemplateType]::ToastText02)
+$x = $t.GetElementsByTagName('text')
+$x.Item(0).AppendChild($t.CreateTextNode(%[1]v)) > $null
+$x.Item(1).AppendChild($t.CreateTextNode(%[2]v)) > $null
+[Windows.UI.Notifications.ToastNotificationManager]::CreateToastNotifier('searchcraigs').Show([Windows.UI.Notifications.ToastNotification]::new($t))`
+
+func appleScriptString(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+func powershellString(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// DryRun returns a notifier that logs the notifications of notify instead of sending them (for -simulate).
+func DryRun(notify Notifier) Notifier {
+	return func(title string, entries []ResultEntry) ([]Notification, error) {
+		notifications, err := notify(title, entries)
+
+		for i, n := range notifications {
+			notifications[i].send = func(ctx context.Context) error {
+				log.Printf("notification (dry run): %v: %v", n.Title, n.Message)
+				return nil
+			}
+		}
+
+		return notifications, err
+	}
+}
+
+// notifyNew sends the notifications for the new entries, retrying once the ones that fail.
+// Failures are only logged, so that they don't stop -watch.
+func notifyNew(ctx context.Context, notify Notifier, title string, entries []ResultEntry) {
+	if len(entries) == 0 {
+		return
+	}
+
+	notifications, err := notify(title, entries)
+	if err != nil {
+		log.Printf("ERROR: notification failed: %v", err)
+		return
+	}
+
+	for _, n := range notifications {
+		err := n.Send(ctx)
+		if err == nil {
+			continue
+		}
+
+		log.Printf("WARNING: notification failed, retrying in %v: %v", notifyRetryDelay, err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(notifyRetryDelay):
+		}
+
+		if err := n.Send(ctx); err != nil {
+			log.Printf("ERROR: notification failed: %v", err)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package searchcraigs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func notifyEntries(n int) []ResultEntry {
+	var entries []ResultEntry
+	for i := 0; i < n; i++ {
+		entries = append(entries, ResultEntry{Title: fmt.Sprintf("bike %v", i), Price: "$10", Href: fmt.Sprintf("https://sfbay.craigslist.org/%v.html", i)})
+	}
+
+	return entries
+}
+
+func TestNotifyWebhook(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		entries  int
+		want     string
+	}{
+		{"template", `{"text": {{ json (printf "%v: %v new" .Title .Count) }}, "more": {{ .More }}}`, 12, `{"text": "bikes: 12 new", "more": 2}`},
+		{"template quotes", `{{ json (index .Entries 0).Title }}`, 1, `"bike 0"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t, responses(testResponse{status: http.StatusOK}))
+
+			notifier, err := NewNotifier(s.URL, tt.template)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			notifyNew(context.Background(), notifier, "bikes", notifyEntries(tt.entries))
+
+			if got := s.received()[0].body; got != tt.want {
+				t.Errorf("payload %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNotifyWebhookDefault(t *testing.T) {
+	s := newTestServer(t, responses(testResponse{status: http.StatusOK}))
+
+	notifier, err := NewNotifier(s.URL, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	notifyNew(context.Background(), notifier, "bikes", notifyEntries(25))
+
+	var got webhookPayload
+	if err := json.Unmarshal([]byte(s.received()[0].body), &got); err != nil {
+		t.Fatal(err)
+	}
+
+	if got.Title != "bikes" || got.Count != 25 || got.More != 25-maxNotifications {
+		t.Errorf("payload title %q, count %v, more %v", got.Title, got.Count, got.More)
+	}
+
+	if len(got.Entries) != maxNotifications {
+		t.Fatalf("got %v entries, want %v", len(got.Entries), maxNotifications)
+	}
+
+	if got.Entries[0].Title != "bike 0" || got.Entries[0].Href != "https://sfbay.craigslist.org/0.html" {
+		t.Errorf("first entry %+v", got.Entries[0])
+	}
+}
+
+func TestNewNotifierErrors(t *testing.T) {
+	for _, target := range []string{"", "foo", "ftp://example.com"} {
+		if _, err := NewNotifier(target, ""); err == nil {
+			t.Errorf("NewNotifier(%q) should fail", target)
+		}
+	}
+
+	if _, err := NewNotifier("desktop", "{{ .Title }}"); err == nil {
+		t.Error("desktop with a template should fail")
+	}
+
+	if _, err := NewNotifier("https://example.com/hook", "{{ .Title "); err == nil {
+		t.Error("an invalid template should fail")
+	}
+}
+
+func TestDesktopNotifier(t *testing.T) {
+	notifications, _ := desktopNotifier("bikes", notifyEntries(12))
+
+	if len(notifications) != maxNotifications+1 {
+		t.Fatalf("got %v notifications, want %v", len(notifications), maxNotifications+1)
+	}
+
+	if n := notifications[0]; n.Title != "bike 0" || n.Message != "$10\nhttps://sfbay.craigslist.org/0.html" {
+		t.Errorf("first notification %q %q", n.Title, n.Message)
+	}
+
+	if n := notifications[maxNotifications]; n.Title != "bikes" || n.Message != "+2 more new listings" {
+		t.Errorf("last notification %q %q", n.Title, n.Message)
+	}
+}
+
+// the retry only sends again the notification that failed
+func TestNotifyRetry(t *testing.T) {
+	defer func(d time.Duration) { notifyRetryDelay = d }(notifyRetryDelay)
+	notifyRetryDelay = time.Millisecond
+
+	sent := map[string]int{}
+
+	notifier := func(title string, entries []ResultEntry) ([]Notification, error) {
+		var notifications []Notification
+
+		for _, e := range entries {
+			title := e.Title
+			notifications = append(notifications, Notification{Title: title, send: func(ctx context.Context) error {
+				sent[title]++
+				if title == "bike 1" && sent[title] == 1 {
+					return errors.New("failed")
+				}
+
+				return nil
+			}})
+		}
+
+		return notifications, nil
+	}
+
+	notifyNew(context.Background(), notifier, "bikes", notifyEntries(3))
+
+	want := map[string]int{"bike 0": 1, "bike 1": 2, "bike 2": 1}
+	for k, v := range want {
+		if sent[k] != v {
+			t.Errorf("%v sent %v times, want %v", k, sent[k], v)
+		}
+	}
+}
+
+func TestDryRun(t *testing.T) {
+	s := newTestServer(t, responses(testResponse{status: http.StatusOK}))
+
+	notifier, err := NewNotifier(s.URL, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	notifyNew(context.Background(), DryRun(notifier), "bikes", notifyEntries(3))
+
+	if len(s.received()) != 0 {
+		t.Error("the dry run sent the webhook request")
+	}
+}
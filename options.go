@@ -0,0 +1,290 @@
+package searchcraigs
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Where an option is applied
+const (
+	appliedRemote = "craigslist" // sent to craigslist as a search parameter
+	appliedLocal  = "local"      // applied to the returned results
+	appliedClient = "client"     // changes how the requests are sent
+	appliedOutput = "output"     // changes the output
+	appliedRun    = "run"        // changes what the program does (modes, state files, diagnostics)
+)
+
+// optionInfo documents a command line option, and the rules checked by validateOptions.
+// The library options without a flag have an empty Name.
+type optionInfo struct {
+	Name      string
+	Search    string // the SearchOption used by the flag (i.e. MinPrice), or the library option
+	Applied   string
+	Values    []string // valid values (if not empty)
+	AnyValue  bool     // Values are only the known ones, other values are accepted
+	List      bool     // the value is a comma separated list
+	Requires  []string // options that must be set too
+	Conflicts []string // options that can't be set together
+	Note      string
+}
+
+var (
+	sortValues      = []string{string(PriceAsc), string(PriceDesc), string(Date), string(Relevance)}
+	localSortValues = []string{"price", string(PriceAsc), string(PriceDesc), string(Date)}
+	outputFormats   = []string{"html", "json", "rss", "atom", "csv", "tsv"}
+)
+
+// optionRegistry has an entry for each command line option, used by "help options"
+// and to validate the options. Options missing here are reported by validateOptions.
+var optionRegistry = []optionInfo{
+	// search parameters
+	{Name: "region", Search: "WithRegion", Applied: appliedRemote, List: true, Note: "multiple regions are searched concurrently and the results merged"},
+	{Name: "subregion", Search: "WithSubregion", Applied: appliedRemote},
+	{Name: "cat", Search: "WithCategory", Applied: appliedRemote, Values: sortedKeys(categoryNames), AnyValue: true, Note: "craigslist category codes (i.e. sss) are also accepted"},
+	{Name: "by", Search: "By", Applied: appliedRemote, Values: []string{"all", "owner", "dealer"}, Conflicts: []string{"seller"}, Note: "uses the owner/dealer variant of the category if there is one (i.e. cars, cta -> cto/ctd), the purveyor parameter otherwise"},
+	{Name: "seller", Search: "SellerType", Applied: appliedRemote, Values: []string{"all", "owner", "dealer"}, Note: "same as -by"},
+	{Name: "crypto", Search: "CryptoAccepted", Applied: appliedRemote},
+	{Name: "delivery", Search: "DeliveryAvailable", Applied: appliedRemote},
+	{Name: "bedrooms", Search: "Bedrooms", Applied: appliedRemote, Note: "only for the housing categories (housing, apartments, rooms, sublets)"},
+	{Name: "sqft", Search: "SquareFeet", Applied: appliedRemote, Note: "only for the housing categories (housing, apartments, rooms, sublets)"},
+	{Name: "dedup", Search: "Dedup", Applied: appliedRemote, Note: "craigslist bundles duplicates in a page, searchcraigs also removes them across pages and regions"},
+	{Name: "exclude", Search: "QueryExclude", Applied: appliedRemote, List: true, Note: "added to the query as -word (or -\"a phrase\"), expanded with -synonyms"},
+	{Name: "pictures", Search: "Pictures", Applied: appliedRemote},
+	{Name: "sort", Search: "Sort", Applied: appliedRemote, Values: sortValues, Note: "with date, -watch doesn't fetch pages older than the previous search"},
+	{Name: "titles", Search: "TitleOnly", Applied: appliedRemote},
+	{Name: "today", Search: "Today", Applied: appliedRemote},
+	{Name: "min", Search: "MinPrice", Applied: appliedRemote},
+	{Name: "max", Search: "MaxPrice", Applied: appliedRemote},
+	{Name: "nearby", Search: "Nearby", Applied: appliedRemote},
+	{Name: "near", Search: "Location", Applied: appliedRemote, Note: "the results have the distance from the location (see -fields distance)"},
+	{Name: "distance", Search: "SearchDistance", Applied: appliedRemote, Requires: []string{"near"}},
+	{Name: "pages", Applied: appliedRemote, Note: "counts toward -max-requests"},
+	{Name: "limit", Search: "MaxEntries", Applied: appliedLocal, Note: "without local filters or sorting (-filter, -min-local, -max-local, -no-nearby, -dedupfile, -localsort) stops fetching pages when there are enough results"},
+	{Name: "skip", Applied: appliedLocal},
+
+	// library search options without a flag
+	{Search: "Query", Applied: appliedRemote, Note: "the query arguments on the command line"},
+	{Search: "QueryAll", Applied: appliedRemote, Note: "see NewQuery to build more complex queries"},
+	{Search: "QueryAny", Applied: appliedRemote},
+	{Search: "QueryPhrase", Applied: appliedRemote},
+	{Search: "Bathrooms", Applied: appliedRemote, Note: "only for the housing categories"},
+	{Search: "CatsOK", Applied: appliedRemote, Note: "only for the housing categories"},
+	{Search: "DogsOK", Applied: appliedRemote, Note: "only for the housing categories"},
+	{Search: "AvailableWithin", Applied: appliedRemote, Values: []string{"0", "1", "2"}, Note: "only for the housing categories; 1 is within 30 days, 2 after 30 days"},
+	{Search: "PaidOnly", Applied: appliedRemote, Note: "only for the gigs categories"},
+	{Search: "PostalCode", Applied: appliedRemote, Note: "with SearchDistance, like Location"},
+	{Search: "NearbyResults", Applied: appliedRemote, Note: "NearbyResults(false) asks craigslist not to add the nearby results, -no-nearby removes them (FilterNearby)"},
+	{Search: "StopWhen", Applied: appliedLocal, Note: "only for SearchAll; -watch uses it with -sort date to skip the pages older than the previous search"},
+
+	{Name: "config", Applied: appliedRun},
+	{Name: "saved", Applied: appliedRun, Note: "sets -region, -subregion, -cat, -filter, -min, -max, -sort, -title and the query, unless they are on the command line"},
+	{Name: "list-saved", Applied: appliedRun},
+	{Name: "list-regions", Applied: appliedRun, Note: "the list is downloaded from craigslist and cached for a week in the data directory; it's also used to check -region"},
+	{Name: "list-subregions", Applied: appliedRun, Note: "one request per region"},
+
+	// local processing
+	{Name: "filter", Applied: appliedLocal, Note: "also searches in titles only (as -titles); the field selectors (price<500, hood:oakland) are only applied locally"},
+	{Name: "min-local", Applied: appliedLocal},
+	{Name: "max-local", Applied: appliedLocal},
+	{Name: "localsort", Applied: appliedLocal, Values: localSortValues},
+	{Name: "synonyms", Applied: appliedLocal, Note: "expands the query sent to craigslist, and the -filter terms"},
+	{Name: "synonyms-file", Applied: appliedLocal},
+	{Name: "dedupfile", Applied: appliedLocal},
+	{Name: "dedupfile-ttl", Applied: appliedLocal, Requires: []string{"dedupfile"}},
+	{Name: "dedupfile-href", Applied: appliedLocal, Requires: []string{"dedupfile"}},
+	{Name: "no-nearby", Applied: appliedLocal, Conflicts: []string{"nearby"}, Note: "before -dedupfile, so the removed results are not stored"},
+	{Name: "no-auto-state", Applied: appliedLocal, Note: "-watch and -simulate never use the automatic state"},
+	{Name: "state-expire", Applied: appliedLocal, Note: "applies to the -watch state, to the automatic state and to the -serve users"},
+	{Name: "details", Applied: appliedLocal, Note: "one request per listing, counts toward -max-requests"},
+	{Name: "seller-listings", Applied: appliedLocal, Requires: []string{"details"}, Note: "only for listings with a \"more ads by this user\" link (mostly dealers), one request per seller"},
+	{Name: "max-per-seller", Applied: appliedLocal, Requires: []string{"details"}, Note: "the seller is known only for the -details results with a \"more ads by this user\" link, applied after -limit"},
+	{Name: "details-cache", Applied: appliedLocal, Note: "used by -details and -embed-images, each listing or image is recorded as soon as it's fetched"},
+	{Name: "details-cache-ttl", Applied: appliedLocal, Requires: []string{"details-cache"}},
+	{Name: "db", Applied: appliedRun, Note: "stores the results after the local filters; a listing gets a new price entry only when its price changes"},
+	{Name: "db-history", Applied: appliedRun, Requires: []string{"db"}, Note: "doesn't search"},
+	{Name: "category-breakdown", Applied: appliedLocal},
+	{Name: "wayback", Applied: appliedLocal, Conflicts: []string{"watch"}, Note: "searches the Wayback Machine, counts toward -max-requests"},
+	{Name: "wayback-from", Applied: appliedLocal, Requires: []string{"wayback"}},
+	{Name: "wayback-to", Applied: appliedLocal, Requires: []string{"wayback"}},
+	{Name: "wayback-max", Applied: appliedLocal, Requires: []string{"wayback"}},
+	{Name: "assume-currency", Applied: appliedLocal, Requires: []string{"wayback"}},
+
+	// output
+	{Name: "html", Applied: appliedOutput, Note: "ignored if -format is set"},
+	{Name: "format", Applied: appliedOutput, Values: outputFormats, Note: "overrides -html and -browse"},
+	{Name: "fields", Applied: appliedOutput, Values: sortedKeys(csvFields), List: true, Requires: []string{"format"}, Note: "only for -format csv or tsv"},
+	{Name: "browse", Applied: appliedOutput},
+	{Name: "serve", Applied: appliedOutput, Conflicts: []string{"watch"}, Note: "the entries seen by each ?user= expire after -state-expire, the favorites are kept"},
+	{Name: "serve-idle", Applied: appliedOutput, Requires: []string{"serve"}},
+	{Name: "title", Applied: appliedOutput, Note: "the data is Label (the -saved name or the query), Query, Count, NewCount and MinPrice; also used by the feeds and the -notify notifications"},
+	{Name: "template", Applied: appliedOutput, Conflicts: []string{"layout"}},
+	{Name: "layout", Applied: appliedOutput, Values: sortedKeys(layouts)},
+	{Name: "print-friendly", Applied: appliedOutput},
+	{Name: "embed-images", Applied: appliedOutput, Note: "only for the HTML page (also with -serve), ignored with -print-friendly; the images are downloaded with -proxy, -useragent, -header and -rate"},
+	{Name: "max-embed-size", Applied: appliedOutput, Requires: []string{"embed-images"}},
+
+	// requests
+	{Name: "rate", Applied: appliedClient, Note: "applies to all the requests, including -details and retries; -debug logs when a request is delayed"},
+	{Name: "retries", Applied: appliedClient},
+	{Name: "backoff", Applied: appliedClient},
+	{Name: "timeout", Applied: appliedClient},
+	{Name: "proxy", Applied: appliedClient},
+	{Name: "useragent", Applied: appliedClient},
+	{Name: "header", Applied: appliedClient},
+	{Name: "max-requests", Applied: appliedClient},
+	{Name: "yes", Applied: appliedClient},
+
+	// modes and diagnostics
+	{Name: "watch", Applied: appliedRun},
+	{Name: "interval", Applied: appliedRun, Requires: []string{"watch"}},
+	{Name: "notify", Applied: appliedRun, Note: "without -watch, the entries not seen by the previous runs (all of them with -no-auto-state); up to 10 entries per search (desktop shows a \"+N more\" notification for the others); failures are retried once and logged; with -simulate the notifications are only logged"},
+	{Name: "notify-template", Applied: appliedRun, Requires: []string{"notify"}, Note: "a text/template with Title, Count, Entries (up to 10) and More, and the json function"},
+	{Name: "state", Applied: appliedRun, Requires: []string{"watch"}},
+	{Name: "simulate", Applied: appliedRun, Note: "no requests are sent, all the search parameters are ignored"},
+	{Name: "seed", Applied: appliedRun, Requires: []string{"simulate"}},
+	{Name: "profile", Applied: appliedRun},
+	{Name: "profile-mem", Applied: appliedRun},
+	{Name: "stats", Applied: appliedRun},
+	{Name: "debug", Applied: appliedRun, Note: "also logs as -v"},
+	{Name: "v", Applied: appliedRun},
+	{Name: "dump-html", Applied: appliedRun, Note: "search and listing pages, not the Wayback Machine ones"},
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+	return keys
+}
+
+func lookupOption(name string) (optionInfo, bool) {
+	for _, o := range optionRegistry {
+		if o.Name != "" && o.Name == name {
+			return o, true
+		}
+	}
+
+	return optionInfo{}, false
+}
+
+// validateOptions checks the options set in fs against the registry:
+// the values, the required options and the conflicts.
+func validateOptions(fs *flag.FlagSet) error {
+	set := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+
+	var err error
+
+	fs.VisitAll(func(f *flag.Flag) {
+		if err != nil {
+			return
+		}
+
+		o, ok := lookupOption(f.Name)
+		if !ok {
+			err = fmt.Errorf("option -%v is not in the option registry", f.Name)
+			return
+		}
+
+		if !set[f.Name] {
+			return
+		}
+
+		if len(o.Values) > 0 && !o.AnyValue {
+			values := []string{f.Value.String()}
+			if o.List {
+				values = strings.Split(f.Value.String(), ",")
+			}
+
+			for _, v := range values {
+				if v = strings.TrimSpace(v); v != "" && !contains(o.Values, v) {
+					err = fmt.Errorf("invalid -%v %q (valid values: %v)", f.Name, v, strings.Join(o.Values, ", "))
+					return
+				}
+			}
+		}
+
+		for _, r := range o.Requires {
+			if !set[r] {
+				err = fmt.Errorf("-%v requires -%v", f.Name, r)
+				return
+			}
+		}
+
+		for _, c := range o.Conflicts {
+			if set[c] {
+				err = fmt.Errorf("-%v cannot be used with -%v", f.Name, c)
+				return
+			}
+		}
+	})
+
+	return err
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+
+	return false
+}
+
+// printOptions prints the option reference ("searchcraigs help options"): the flags,
+// then the library options without a flag.
+func printOptions(w io.Writer, fs *flag.FlagSet) {
+	for _, o := range optionRegistry {
+		f := fs.Lookup(o.Name)
+		if f == nil {
+			continue
+		}
+
+		fmt.Fprintf(w, "-%v (%v)\n", o.Name, o.Applied)
+		fmt.Fprintf(w, "    %v\n", f.Usage)
+		printOptionInfo(w, o)
+	}
+
+	fmt.Fprintln(w, "\nLibrary search options without a flag:")
+
+	for _, o := range optionRegistry {
+		if o.Name == "" {
+			fmt.Fprintf(w, "%v (%v)\n", o.Search, o.Applied)
+			printOptionInfo(w, o)
+		}
+	}
+}
+
+func printOptionInfo(w io.Writer, o optionInfo) {
+	if o.Name != "" && o.Search != "" {
+		fmt.Fprintf(w, "    search option: %v\n", o.Search)
+	}
+
+	if len(o.Values) > 0 {
+		kind := "values"
+		if o.List {
+			kind = "comma separated list of"
+		}
+
+		fmt.Fprintf(w, "    %v: %v\n", kind, strings.Join(o.Values, ", "))
+	}
+
+	if len(o.Requires) > 0 {
+		fmt.Fprintf(w, "    requires: -%v\n", strings.Join(o.Requires, ", -"))
+	}
+
+	if len(o.Conflicts) > 0 {
+		fmt.Fprintf(w, "    conflicts with: -%v\n", strings.Join(o.Conflicts, ", -"))
+	}
+
+	if o.Note != "" {
+		fmt.Fprintf(w, "    note: %v\n", o.Note)
+	}
+}
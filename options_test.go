@@ -0,0 +1,62 @@
+package searchcraigs
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// every exported function returning a SearchOption is in the option registry, and the other way around
+func TestOptionRegistrySearchOptions(t *testing.T) {
+	files, err := filepath.Glob("*.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	funcs := map[string]bool{}
+	fset := token.NewFileSet()
+
+	for _, path := range files {
+		if strings.HasSuffix(path, "_test.go") {
+			continue
+		}
+
+		f, err := parser.ParseFile(fset, path, nil, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, d := range f.Decls {
+			fn, ok := d.(*ast.FuncDecl)
+			if !ok || fn.Recv != nil || !fn.Name.IsExported() || fn.Type.Results == nil || len(fn.Type.Results.List) != 1 {
+				continue
+			}
+
+			if id, ok := fn.Type.Results.List[0].Type.(*ast.Ident); ok && id.Name == "SearchOption" {
+				funcs[fn.Name.Name] = true
+			}
+		}
+	}
+
+	registered := map[string]bool{}
+	for _, o := range optionRegistry {
+		if o.Search == "" {
+			continue
+		}
+
+		registered[o.Search] = true
+
+		if !funcs[o.Search] {
+			t.Errorf("-%v: %v is not a SearchOption", o.Name, o.Search)
+		}
+	}
+
+	for name := range funcs {
+		if !registered[name] {
+			t.Errorf("%v is not in the option registry", name)
+		}
+	}
+}
@@ -0,0 +1,241 @@
+package searchcraigs
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// A resultLayout describes how to find and parse the result rows for one version of the craigslist search page.
+type resultLayout struct {
+	name  string
+	rows  string
+	parse func(s *goquery.Selection) ResultEntry
+}
+
+// The search page layouts we know about, newest first.
+// The first layout that finds some rows wins.
+var resultLayouts = []resultLayout{
+	{name: "gallery", rows: "li.cl-search-result", parse: parseSearchResult},
+	{name: "static", rows: "li.cl-static-search-result", parse: parseStaticResult},
+	{name: "legacy", rows: ".rows li.result-row", parse: parseResultRow},
+}
+
+// parseResults adds the entries in doc to results, returning the number of result rows in the page
+// (including duplicates). The prices and dates are read with the conventions of the region's country.
+func parseResults(doc *goquery.Document, region, country string, results *SearchResults) int {
+	var rows *goquery.Selection
+	var layout resultLayout
+
+	for _, layout = range resultLayouts {
+		if rows = doc.Find(layout.rows); rows.Length() > 0 {
+			break
+		}
+	}
+
+	rows.Each(func(i int, s *goquery.Selection) {
+		entry := layout.parse(s)
+		entry.Region = region
+		entry.Currency = priceCurrency(entry.Price, country)
+		entry.PriceValue = parsePrice(entry.Price, entry.Currency)
+		entry.Posted = parseDatetime(entry.Datetime, country)
+		entry.EntryCategory = categoryFor(entry.Href)
+
+		results.add(entry)
+	})
+
+	if n, err := strconv.Atoi(strings.TrimSpace(doc.Find(".totalcount").First().Text())); err == nil && results.TotalCount == 0 {
+		results.TotalCount = n
+	}
+
+	results.Prev, _ = doc.Find(".buttons .prev").Attr("href")
+	results.Next, _ = doc.Find(".buttons .next").Attr("href")
+
+	return rows.Length()
+}
+
+// parseResultRow parses the original (pre-2023) result rows.
+func parseResultRow(s *goquery.Selection) ResultEntry {
+	title := s.Find(".result-heading a").First().Text()
+	href, _ := s.Find(".result-heading a").First().Attr("href")
+	iids, _ := s.Find("a.result-image").Attr("data-ids")
+	datetime, _ := s.Find(".result-info .result-date").First().Attr("datetime")
+	hood := s.Find(".result-meta .result-hood").First().Text()
+	nearby := s.Find(".result-meta .nearby").First()
+	loc, _ := nearby.Attr("title")
+	ldesc := nearby.Text()
+	price := s.Find(".result-meta .result-price").First().Text()
+	housing := s.Find(".result-meta .housing").First().Text()
+	distance, _ := parseDistance(s.Find(".result-meta .maptag").First().Text()) // "map" if not a distance search
+
+	// whatever is left in the meta row (i.e. the service area or event date)
+	meta := s.Find(".result-meta").First().Clone()
+	meta.Find(".result-price, .result-hood, .housing, .nearby, .result-tags, .banish, .unbanish, .maptag").Remove()
+
+	return ResultEntry{
+		Title:        title,
+		Href:         href,
+		Image:        imageFromIds(iids),
+		Datetime:     datetime,
+		NearbyLoc:    loc,
+		NearbyDesc:   strings.TrimSpace(ldesc),
+		Neighborhood: strings.TrimSpace(hood),
+		Price:        price,
+		Meta:         strings.Join(strings.Fields(meta.Text()), " "),
+		Extras:       housingExtras(housing),
+		Distance:     distance,
+	}
+}
+
+// parseSearchResult parses the result cards of the current (javascript) search page,
+// in list or gallery mode.
+func parseSearchResult(s *goquery.Selection) ResultEntry {
+	a := s.Find("a.posting-title, a.titlestring").First()
+	title := strings.TrimSpace(a.Find(".label").First().Text())
+	if title == "" {
+		title = strings.TrimSpace(a.Text())
+	}
+	if title == "" {
+		title, _ = s.Attr("title")
+	}
+
+	href, _ := a.Attr("href")
+	if href == "" {
+		href, _ = s.Find("a").First().Attr("href")
+	}
+
+	image, _ := s.Find(".cl-gallery img, img").First().Attr("src")
+	if image == "" {
+		iids, _ := s.Find("[data-ids]").First().Attr("data-ids")
+		image = imageFromIds(iids)
+	}
+
+	// the meta line is something like "9/14 · oakland", with the full date in the title.
+	// Services and community listings may have more (service area, event date) in the middle.
+	meta := s.Find(".meta").First().Clone()
+	datetime, _ := meta.Find("[title]").First().Attr("title")
+	if t, err := time.Parse("Mon Jan 2 2006 15:04:05 GMT-0700", datetime); err == nil {
+		datetime = t.Format("2006-01-02 15:04") // same format as the legacy layout
+	}
+
+	// housing results have the bedrooms and square feet in the meta line, in their own span or as parts
+	housing := []string{s.Find(".housing-meta, .housing").First().Text()}
+	meta.Find(".housing-meta, .housing").Remove()
+
+	// distance searches have the distance in its own span, or as a part of the meta line ("2.3mi")
+	distance, _ := parseDistance(meta.Find(".distance").First().Text())
+	meta.Find(".distance").Remove()
+
+	hood := ""
+	other := []string{}
+	if parts := strings.Split(meta.Text(), "·"); len(parts) > 1 {
+		hood = parts[len(parts)-1]
+
+		for _, p := range parts[1 : len(parts)-1] {
+			if p = strings.TrimSpace(p); isHousingExtra(p) {
+				housing = append(housing, p)
+			} else if d, ok := parseDistance(p); ok {
+				distance = d
+			} else if p != "" {
+				other = append(other, p)
+			}
+		}
+	}
+
+	return ResultEntry{
+		Title:        title,
+		Href:         href,
+		Image:        image,
+		Datetime:     datetime,
+		Neighborhood: strings.TrimSpace(hood),
+		Price:        strings.TrimSpace(s.Find(".priceinfo, .price").First().Text()),
+		Meta:         strings.Join(other, " · "),
+		Extras:       housingExtras(strings.Join(housing, " ")),
+		Distance:     distance,
+	}
+}
+
+// parseStaticResult parses the results of the static (no javascript) search page.
+// These don't include images or dates.
+func parseStaticResult(s *goquery.Selection) ResultEntry {
+	title := strings.TrimSpace(s.Find(".title").First().Text())
+	if title == "" {
+		title, _ = s.Attr("title")
+	}
+
+	href, _ := s.Find("a").First().Attr("href")
+
+	return ResultEntry{
+		Title:        title,
+		Href:         href,
+		Neighborhood: strings.TrimSpace(s.Find(".location").First().Text()),
+		Price:        strings.TrimSpace(s.Find(".price").First().Text()),
+	}
+}
+
+// housingExtras normalizes the housing details of a result ("\n  2br -\n  850ft2 - ") to "2br 850ft2".
+func housingExtras(s string) string {
+	var parts []string
+
+	for _, f := range strings.Fields(strings.ReplaceAll(s, "²", "2")) {
+		if f = strings.Trim(f, "-"); f != "" {
+			parts = append(parts, f)
+		}
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// isHousingExtra returns true for the bedrooms ("2br") and square feet ("850ft2") parts of a meta line.
+func isHousingExtra(s string) bool {
+	unit := strings.TrimLeft(strings.ToLower(s), "0123456789,")
+	if len(unit) == len(s) {
+		return false
+	}
+
+	unit = strings.TrimSpace(unit)
+	return unit == "br" || strings.HasPrefix(unit, "ft")
+}
+
+// imageFromIds returns the thumbnail URL for the first image in a data-ids attribute
+// ("1:00x0x_abcdef,1:00y0y_ghijk").
+func imageFromIds(iids string) string {
+	if iids == "" {
+		return ""
+	}
+
+	id := strings.Split(iids, ",")[0]
+	if _, after, ok := strings.Cut(id, ":"); ok {
+		id = after
+	}
+
+	return fmt.Sprintf("https://images.craigslist.org/%v_300x300.jpg", id)
+}
+
+// blockedMarkers are found in the pages craigslist returns (with status 200) instead of the results
+// when it blocks an IP or wants a captcha solved
+var blockedMarkers = []string{
+	"this ip has been automatically blocked",
+	"your ip has been blocked",
+	"please complete the security check",
+}
+
+// isBlocked returns true if doc is a block or captcha page instead of a search page.
+func isBlocked(doc *goquery.Document) bool {
+	if doc.Find(".h-captcha, .g-recaptcha, iframe[src*=captcha]").Length() > 0 {
+		return true
+	}
+
+	text := strings.ToLower(doc.Find("body").Text())
+
+	for _, m := range blockedMarkers {
+		if strings.Contains(text, m) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,255 @@
+package searchcraigs
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// loadFixture parses the search page in testdata/name
+func loadFixture(t testing.TB, name string) *goquery.Document {
+	t.Helper()
+
+	f, err := os.Open(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer f.Close()
+
+	doc, err := goquery.NewDocumentFromReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return doc
+}
+
+// posted returns the local time for the Posted field of the test entries
+func posted(year int, month time.Month, day, hour, min int) time.Time {
+	return time.Date(year, month, day, hour, min, 0, 0, time.Local)
+}
+
+func TestParseResults(t *testing.T) {
+	tests := []struct {
+		layout  string
+		fixture string
+		total   int
+		next    string
+		want    []ResultEntry
+	}{
+		{
+			layout:  "gallery",
+			fixture: "search-gallery.html",
+			want: []ResultEntry{
+				{
+					Title:         "Standing desk, electric",
+					Href:          "https://sfbay.craigslist.org/eby/fuo/d/oakland-standing-desk-electric/7780000001.html",
+					Image:         "https://images.craigslist.org/00K0K_1aBcDeFgHiJ_0CI0t2_600x450.jpg",
+					Datetime:      "2024-09-16 09:30",
+					Neighborhood:  "oakland",
+					Price:         "$1,250",
+					PriceValue:    1250,
+					Posted:        posted(2024, time.September, 16, 9, 30),
+					EntryCategory: "fuo",
+				},
+				{
+					Title:         "Sunny 2br near the lake",
+					Href:          "https://sfbay.craigslist.org/eby/apa/d/oakland-sunny-2br-near-the-lake/7780000002.html",
+					Image:         "https://images.craigslist.org/00a0a_abcXYZ_300x300.jpg",
+					Datetime:      "2024-09-15 18:05",
+					Neighborhood:  "grand lake",
+					Price:         "$2,800",
+					PriceValue:    2800,
+					Posted:        posted(2024, time.September, 15, 18, 5),
+					EntryCategory: "apa",
+					Extras:        "2br 850ft2",
+				},
+				{
+					Title:         "3br house with yard",
+					Href:          "https://sfbay.craigslist.org/eby/apa/d/berkeley-3br-house-with-yard/7780000003.html",
+					Datetime:      "2024-09-14 12:00",
+					Neighborhood:  "berkeley",
+					Price:         "$4,100",
+					PriceValue:    4100,
+					Posted:        posted(2024, time.September, 14, 12, 0),
+					EntryCategory: "apa",
+					Extras:        "3br 1200ft2",
+				},
+				{
+					Title:         "Desk assembly, same day",
+					Href:          "https://sfbay.craigslist.org/eby/lbs/d/richmond-desk-assembly-same-day/7780000004.html",
+					Datetime:      "2024-09-13 07:45",
+					Neighborhood:  "richmond",
+					Posted:        posted(2024, time.September, 13, 7, 45),
+					EntryCategory: "lbs",
+					Meta:          "serving the east bay",
+				},
+			},
+		},
+		{
+			layout:  "static",
+			fixture: "search-static.html",
+			want: []ResultEntry{
+				{
+					Title:         "Standing desk, electric",
+					Href:          "https://sfbay.craigslist.org/eby/fuo/d/oakland-standing-desk-electric/7780000001.html",
+					Neighborhood:  "oakland",
+					Price:         "$1,250",
+					PriceValue:    1250,
+					EntryCategory: "fuo",
+				},
+				{
+					Title:         "Oak writing desk", // from the title attribute
+					Href:          "https://sfbay.craigslist.org/sfc/fuo/d/san-francisco-oak-writing-desk/7780000005.html",
+					Neighborhood:  "inner sunset / UCSF",
+					Price:         "$300",
+					PriceValue:    300,
+					EntryCategory: "fuo",
+				},
+				{
+					Title:         "Free desk chair",
+					Href:          "https://sfbay.craigslist.org/pen/zip/d/palo-alto-free-desk-chair/7780000006.html",
+					Neighborhood:  "palo alto",
+					Price:         "$0",
+					EntryCategory: "zip",
+				},
+			},
+		},
+		{
+			layout:  "legacy",
+			fixture: "search-legacy.html",
+			total:   245,
+			next:    "/search/sss?query=desk&s=120",
+			want: []ResultEntry{
+				{
+					Title:         "Standing desk",
+					Href:          "https://sfbay.craigslist.org/eby/fuo/d/oakland-standing-desk/7380000001.html",
+					Image:         "https://images.craigslist.org/00K0K_1aBcDeFgHiJ_300x300.jpg",
+					Datetime:      "2021-08-20 13:45",
+					Neighborhood:  "(oakland)",
+					Price:         "$450",
+					PriceValue:    450,
+					Posted:        posted(2021, time.August, 20, 13, 45),
+					EntryCategory: "fuo",
+				},
+				{
+					Title:         "Studio with view",
+					Href:          "https://sfbay.craigslist.org/sfc/apa/d/san-francisco-studio-with-view/7380000002.html",
+					Datetime:      "2021-08-19 08:10",
+					Neighborhood:  "(nob hill)",
+					Price:         "$2,100",
+					PriceValue:    2100,
+					Posted:        posted(2021, time.August, 19, 8, 10),
+					EntryCategory: "apa",
+					Extras:        "1br 450ft2",
+				},
+				{
+					Title:         "Computer desk",
+					Href:          "https://sacramento.craigslist.org/fuo/d/davis-computer-desk/7380000003.html",
+					Image:         "https://images.craigslist.org/00M0M_3cDeFgHiJkL_300x300.jpg",
+					Datetime:      "2021-08-18 19:00",
+					NearbyLoc:     "sacramento",
+					NearbyDesc:    "(sac > davis)",
+					Price:         "$60",
+					PriceValue:    60,
+					Posted:        posted(2021, time.August, 18, 19, 0),
+					EntryCategory: "fuo",
+				},
+				{
+					Title:         "Furniture assembly",
+					Href:          "https://sfbay.craigslist.org/sby/lbs/d/san-jose-furniture-assembly/7380000004.html",
+					Datetime:      "2021-08-18 07:30",
+					Neighborhood:  "(san jose)",
+					Posted:        posted(2021, time.August, 18, 7, 30),
+					EntryCategory: "lbs",
+					Meta:          "serving the south bay",
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.layout, func(t *testing.T) {
+			var results SearchResults
+
+			if n := parseResults(loadFixture(t, tt.fixture), "sfbay", "US", &results); n != len(tt.want) {
+				t.Errorf("%v result rows, want %v", n, len(tt.want))
+			}
+
+			if results.TotalCount != tt.total {
+				t.Errorf("total count %v, want %v", results.TotalCount, tt.total)
+			}
+
+			if results.Next != tt.next {
+				t.Errorf("next page %q, want %q", results.Next, tt.next)
+			}
+
+			if len(results.Entries) != len(tt.want) {
+				t.Fatalf("%v entries, want %v", len(results.Entries), len(tt.want))
+			}
+
+			for i, want := range tt.want {
+				want.Region, want.Currency = "sfbay", "USD"
+
+				if got := results.Entries[i]; !reflect.DeepEqual(got, want) {
+					t.Errorf("entry %v:\n got  %+v\n want %+v", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+// the gallery cards are used when the page also has the static results (for browsers without javascript),
+// and duplicate rows are only added once
+func TestParseResultsLayoutOrder(t *testing.T) {
+	gallery, err := loadFixture(t, "search-gallery.html").Find("ol").Html()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	static, err := loadFixture(t, "search-static.html").Find("ol").Html()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	page := "<html><body><ol>" + static + "</ol><ol>" + gallery + gallery + "</ol></body></html>"
+
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(page))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results := SearchResults{seen: map[uint64]bool{}}
+
+	if n := parseResults(doc, "sfbay", "US", &results); n != 8 {
+		t.Errorf("%v result rows, want 8", n)
+	}
+
+	if len(results.Entries) != 4 {
+		t.Fatalf("%v entries, want 4", len(results.Entries))
+	}
+
+	if results.Entries[0].Image == "" || results.Entries[0].Datetime == "" {
+		t.Errorf("the first entry is not from the gallery cards: %+v", results.Entries[0])
+	}
+}
+
+// a page without results (or with an unknown layout) has no entries
+func TestParseResultsEmpty(t *testing.T) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(`<html><body><div class="cl-results-page"><p>no results</p></div></body></html>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var results SearchResults
+
+	if n := parseResults(doc, "sfbay", "US", &results); n != 0 || len(results.Entries) != 0 {
+		t.Errorf("%v rows, %v entries, want none", n, len(results.Entries))
+	}
+}
@@ -0,0 +1,210 @@
+package searchcraigs
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// The price history database (-db). Each listing is stored once, keyed by href,
+// with a sighting for the first run that found it and for each run that found it at a different price.
+
+const priceDBSchema = `
+CREATE TABLE IF NOT EXISTS listings (
+	href         TEXT PRIMARY KEY,
+	title        TEXT NOT NULL,
+	price        INTEGER NOT NULL,
+	currency     TEXT NOT NULL,
+	neighborhood TEXT NOT NULL,
+	image        TEXT NOT NULL,
+	first_seen   TEXT NOT NULL,
+	last_seen    TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS sightings (
+	href  TEXT NOT NULL REFERENCES listings(href),
+	seen  TEXT NOT NULL,
+	price INTEGER NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS sightings_href ON sightings(href, seen);
+`
+
+// PriceDB is a SQLite database with the listings returned by the searches and their price changes.
+type PriceDB struct {
+	db *sql.DB
+}
+
+// Sighting is the price of a listing in a run.
+type Sighting struct {
+	Seen  time.Time
+	Price int
+}
+
+// PriceHistory is a listing with its price changes.
+type PriceHistory struct {
+	Href      string
+	Title     string
+	Currency  string
+	FirstSeen time.Time
+	LastSeen  time.Time
+	Sightings []Sighting // oldest first
+}
+
+// OpenPriceDB opens (or creates) the database at path.
+func OpenPriceDB(path string) (*PriceDB, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := db.Exec(priceDBSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("%v: %w", path, err)
+	}
+
+	return &PriceDB{db: db}, nil
+}
+
+// Close closes the database.
+func (p *PriceDB) Close() error {
+	return p.db.Close()
+}
+
+// Record adds the entries seen at the given time: new listings are added, the known ones
+// are updated (last_seen, title...) and get a new sighting if the price changed.
+// Archived entries are skipped. It returns the number of new listings and of price changes.
+func (p *PriceDB) Record(entries []ResultEntry, seen time.Time) (added, changed int, err error) {
+	tx, err := p.db.Begin()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	defer tx.Rollback() // no-op after Commit
+
+	ts := seen.UTC().Format(time.RFC3339)
+
+	for _, e := range entries {
+		if e.Href == "" || e.Archived {
+			continue
+		}
+
+		var price int
+
+		err := tx.QueryRow(`SELECT price FROM listings WHERE href = ?`, e.Href).Scan(&price)
+
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			_, err = tx.Exec(`INSERT INTO listings (href, title, price, currency, neighborhood, image, first_seen, last_seen)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+				e.Href, e.Title, e.PriceValue, e.Currency, e.Neighborhood, e.Image, ts, ts)
+			added++
+
+		case err == nil:
+			_, err = tx.Exec(`UPDATE listings SET title = ?, price = ?, currency = ?, neighborhood = ?, image = ?, last_seen = ?
+				WHERE href = ?`,
+				e.Title, e.PriceValue, e.Currency, e.Neighborhood, e.Image, ts, e.Href)
+
+			if price == e.PriceValue {
+				continue
+			}
+
+			changed++
+		}
+
+		if err != nil {
+			return 0, 0, err
+		}
+
+		if _, err := tx.Exec(`INSERT INTO sightings (href, seen, price) VALUES (?, ?, ?)`, e.Href, ts, e.PriceValue); err != nil {
+			return 0, 0, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, 0, err
+	}
+
+	return added, changed, nil
+}
+
+// History returns the price history of the listings with an href containing match, most recently seen first.
+func (p *PriceDB) History(match string) ([]PriceHistory, error) {
+	rows, err := p.db.Query(`SELECT l.href, l.title, l.currency, l.first_seen, l.last_seen, s.seen, s.price
+		FROM listings l JOIN sightings s ON s.href = l.href
+		WHERE instr(l.href, ?) > 0
+		ORDER BY l.last_seen DESC, l.href, s.seen`, match)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var history []PriceHistory
+
+	for rows.Next() {
+		var h PriceHistory
+		var first, last, seen string
+		var s Sighting
+
+		if err := rows.Scan(&h.Href, &h.Title, &h.Currency, &first, &last, &seen, &s.Price); err != nil {
+			return nil, err
+		}
+
+		s.Seen, _ = time.Parse(time.RFC3339, seen)
+
+		if n := len(history); n > 0 && history[n-1].Href == h.Href {
+			history[n-1].Sightings = append(history[n-1].Sightings, s)
+			continue
+		}
+
+		h.FirstSeen, _ = time.Parse(time.RFC3339, first)
+		h.LastSeen, _ = time.Parse(time.RFC3339, last)
+		h.Sightings = []Sighting{s}
+
+		history = append(history, h)
+	}
+
+	return history, rows.Err()
+}
+
+// Print writes the price changes of the listing.
+func (h PriceHistory) Print(w io.Writer) {
+	fmt.Fprintf(w, "%v\n    %v\n", h.Title, h.Href)
+	fmt.Fprintf(w, "    first seen %v, last seen %v\n", h.FirstSeen.Local().Format("2006-01-02 15:04"), h.LastSeen.Local().Format("2006-01-02 15:04"))
+
+	for _, s := range h.Sightings {
+		fmt.Fprintf(w, "    %v  %v%v\n", s.Seen.Local().Format("2006-01-02 15:04"), currencySymbol(h.Currency), s.Price)
+	}
+}
+
+// printPriceHistory prints the price history of the listings in the database at path
+// with an href containing match (-db-history).
+func printPriceHistory(path, match string) error {
+	db, err := OpenPriceDB(path)
+	if err != nil {
+		return err
+	}
+
+	defer db.Close()
+
+	history, err := db.History(match)
+	if err != nil {
+		return err
+	}
+
+	if len(history) == 0 {
+		return fmt.Errorf("no listings matching %q in %v", match, path)
+	}
+
+	for _, h := range history {
+		h.Print(os.Stdout)
+	}
+
+	return nil
+}
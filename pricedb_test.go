@@ -0,0 +1,130 @@
+package searchcraigs
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func openTestPriceDB(t *testing.T, path string) *PriceDB {
+	t.Helper()
+
+	db, err := OpenPriceDB(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestPriceDB(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "prices.db")
+	db := openTestPriceDB(t, path)
+
+	day1 := time.Date(2024, 9, 1, 10, 0, 0, 0, time.UTC)
+	day2 := day1.Add(24 * time.Hour)
+	day3 := day2.Add(24 * time.Hour)
+
+	desk := ResultEntry{Title: "desk", Href: "https://sfbay.craigslist.org/eby/fuo/d/desk/1.html", PriceValue: 100, Currency: "USD"}
+	chair := ResultEntry{Title: "chair", Href: "https://sfbay.craigslist.org/eby/fuo/d/chair/2.html", PriceValue: 40, Currency: "USD"}
+	archived := ResultEntry{Title: "lamp", Href: "https://sfbay.craigslist.org/eby/fuo/d/lamp/3.html", PriceValue: 10, Archived: true}
+
+	runs := []struct {
+		seen    time.Time
+		entries []ResultEntry
+		added   int
+		changed int
+	}{
+		{day1, []ResultEntry{desk, chair, archived, {Title: "no link"}}, 2, 0},
+		{day2, []ResultEntry{desk, chair}, 0, 0},
+		{day3, []ResultEntry{withPrice(desk, 80), chair}, 0, 1},
+	}
+
+	for i, run := range runs {
+		added, changed, err := db.Record(run.entries, run.seen)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if added != run.added || changed != run.changed {
+			t.Errorf("run %v: %v added, %v changed, want %v, %v", i+1, added, changed, run.added, run.changed)
+		}
+	}
+
+	history, err := db.History("sfbay.craigslist.org")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []PriceHistory{
+		{
+			Href: chair.Href, Title: "chair", Currency: "USD", FirstSeen: day1, LastSeen: day3,
+			Sightings: []Sighting{{day1, 40}},
+		},
+		{
+			Href: desk.Href, Title: "desk", Currency: "USD", FirstSeen: day1, LastSeen: day3,
+			Sightings: []Sighting{{day1, 100}, {day3, 80}},
+		},
+	}
+
+	if !reflect.DeepEqual(history, want) {
+		t.Errorf("history:\n got  %+v\n want %+v", history, want)
+	}
+
+	// the database is persistent
+	db.Close()
+	db = openTestPriceDB(t, path)
+
+	history, err = db.History("/desk/")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(history) != 1 || len(history[0].Sightings) != 2 || history[0].Sightings[1].Price != 80 {
+		t.Errorf("history after reopening: %+v", history)
+	}
+
+	if history, err := db.History("lamp"); err != nil || len(history) != 0 {
+		t.Errorf("archived entries recorded: %+v, %v", history, err)
+	}
+}
+
+func withPrice(e ResultEntry, price int) ResultEntry {
+	e.PriceValue = price
+	return e
+}
+
+func TestPriceHistoryPrint(t *testing.T) {
+	seen := time.Date(2024, 9, 1, 10, 0, 0, 0, time.Local)
+
+	h := PriceHistory{
+		Href: "https://london.craigslist.org/bik/d/bike/1.html", Title: "bike", Currency: "GBP",
+		FirstSeen: seen, LastSeen: seen.Add(time.Hour),
+		Sightings: []Sighting{{seen, 100}, {seen.Add(time.Hour), 90}},
+	}
+
+	var b strings.Builder
+	h.Print(&b)
+
+	want := `bike
+    https://london.craigslist.org/bik/d/bike/1.html
+    first seen 2024-09-01 10:00, last seen 2024-09-01 11:00
+    2024-09-01 10:00  £100
+    2024-09-01 11:00  £90
+`
+
+	if b.String() != want {
+		t.Errorf("got\n%v\nwant\n%v", b.String(), want)
+	}
+}
+
+func TestPrintPriceHistoryNoMatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "prices.db")
+
+	if err := printPriceHistory(path, "nothing"); err == nil || !strings.Contains(err.Error(), "no listings matching") {
+		t.Errorf("got %v, want no listings matching", err)
+	}
+}
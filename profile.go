@@ -0,0 +1,98 @@
+package searchcraigs
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"runtime"
+	"runtime/pprof"
+	"text/tabwriter"
+	"time"
+)
+
+// Stats records how long each stage of the pipeline takes.
+// A nil *Stats records nothing.
+type Stats struct {
+	names     []string
+	durations map[string]time.Duration
+	counts    map[string]int
+}
+
+func NewStats() *Stats {
+	return &Stats{durations: map[string]time.Duration{}, counts: map[string]int{}}
+}
+
+// Since adds the time since start to the stage total
+func (s *Stats) Since(stage string, start time.Time) {
+	if s == nil {
+		return
+	}
+
+	if _, ok := s.durations[stage]; !ok {
+		s.names = append(s.names, stage)
+	}
+
+	s.durations[stage] += time.Since(start)
+	s.counts[stage]++
+}
+
+func (s *Stats) Print(w io.Writer) {
+	if s == nil {
+		return
+	}
+
+	var total time.Duration
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "STAGE\tRUNS\tTIME")
+
+	for _, name := range s.names {
+		fmt.Fprintf(tw, "%v\t%v\t%v\n", name, s.counts[name], s.durations[name].Round(time.Microsecond))
+		total += s.durations[name]
+	}
+
+	fmt.Fprintf(tw, "total\t\t%v\n", total.Round(time.Microsecond))
+	tw.Flush()
+}
+
+// startProfiling starts CPU profiling to cpuFile (if not empty) and returns a function
+// that stops it and writes the heap profile to memFile (if not empty).
+func startProfiling(cpuFile, memFile string) func() {
+	var cpu *os.File
+
+	if cpuFile != "" {
+		f, err := os.Create(cpuFile)
+		if err != nil {
+			log.Fatal("cannot create CPU profile: ", err)
+		}
+
+		if err := pprof.StartCPUProfile(f); err != nil {
+			log.Fatal("cannot start CPU profile: ", err)
+		}
+
+		cpu = f
+	}
+
+	return func() {
+		if cpu != nil {
+			pprof.StopCPUProfile()
+			cpu.Close()
+		}
+
+		if memFile != "" {
+			f, err := os.Create(memFile)
+			if err != nil {
+				log.Println("cannot create memory profile: ", err)
+				return
+			}
+
+			runtime.GC() // get up-to-date statistics
+			if err := pprof.WriteHeapProfile(f); err != nil {
+				log.Println("cannot write memory profile: ", err)
+			}
+
+			f.Close()
+		}
+	}
+}
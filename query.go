@@ -0,0 +1,130 @@
+package searchcraigs
+
+import (
+	"strings"
+)
+
+// QueryBuilder composes a craigslist query with the search operators:
+// -word excludes a word, "exact phrase" matches a phrase and (a|b) matches any of the terms.
+// The terms are quoted as needed, so they can contain spaces and operator characters.
+//
+//	NewQuery("road bike").Any("shimano", "campagnolo").Exclude("carbon").String()
+//	// road bike (shimano|campagnolo) -carbon
+type QueryBuilder struct {
+	parts []string
+}
+
+// NewQuery returns a QueryBuilder starting with a query as entered by the user (not quoted).
+func NewQuery(raw string) *QueryBuilder {
+	q := &QueryBuilder{}
+
+	if raw = strings.TrimSpace(raw); raw != "" {
+		q.parts = append(q.parts, raw)
+	}
+
+	return q
+}
+
+// All adds terms that must all match.
+func (q *QueryBuilder) All(terms ...string) *QueryBuilder {
+	for _, t := range terms {
+		if t = quoteTerm(t); t != "" {
+			q.parts = append(q.parts, t)
+		}
+	}
+
+	return q
+}
+
+// Any adds a group of terms where any can match.
+func (q *QueryBuilder) Any(terms ...string) *QueryBuilder {
+	var group []string
+
+	for _, t := range terms {
+		if t = quoteTerm(t); t != "" {
+			group = append(group, t)
+		}
+	}
+
+	switch len(group) {
+	case 0:
+	case 1:
+		q.parts = append(q.parts, group[0])
+	default:
+		q.parts = append(q.parts, "("+strings.Join(group, "|")+")")
+	}
+
+	return q
+}
+
+// Exclude adds terms that must not match.
+func (q *QueryBuilder) Exclude(terms ...string) *QueryBuilder {
+	for _, t := range terms {
+		if t = quoteTerm(t); t != "" {
+			q.parts = append(q.parts, "-"+t)
+		}
+	}
+
+	return q
+}
+
+// Phrase adds an exact phrase.
+func (q *QueryBuilder) Phrase(s string) *QueryBuilder {
+	if s = cleanTerm(s); s != "" {
+		q.parts = append(q.parts, `"`+s+`"`)
+	}
+
+	return q
+}
+
+// String returns the query.
+func (q *QueryBuilder) String() string {
+	return strings.Join(q.parts, " ")
+}
+
+// cleanTerm removes the quotes (that can't be escaped in a craigslist query) and the extra spaces.
+func cleanTerm(t string) string {
+	return strings.Join(strings.Fields(strings.ReplaceAll(t, `"`, " ")), " ")
+}
+
+// quoteTerm quotes a term if it has spaces or operator characters.
+func quoteTerm(t string) string {
+	t = cleanTerm(t)
+
+	if t != "" && (strings.ContainsAny(t, " |()") || strings.HasPrefix(t, "-")) {
+		return `"` + t + `"`
+	}
+
+	return t
+}
+
+// QueryAll adds terms that must all match to the query (use after Query).
+func QueryAll(terms ...string) SearchOption {
+	return addToQuery(func(q *QueryBuilder) { q.All(terms...) })
+}
+
+// QueryAny adds a group of terms where any can match to the query (use after Query).
+func QueryAny(terms ...string) SearchOption {
+	return addToQuery(func(q *QueryBuilder) { q.Any(terms...) })
+}
+
+// QueryExclude adds terms that must not match to the query (use after Query).
+func QueryExclude(terms ...string) SearchOption {
+	return addToQuery(func(q *QueryBuilder) { q.Exclude(terms...) })
+}
+
+// QueryPhrase adds an exact phrase to the query (use after Query).
+func QueryPhrase(s string) SearchOption {
+	return addToQuery(func(q *QueryBuilder) { q.Phrase(s) })
+}
+
+func addToQuery(add func(q *QueryBuilder)) SearchOption {
+	return func(params map[string]interface{}) {
+		current, _ := params["query"].(string)
+
+		q := NewQuery(current)
+		add(q)
+
+		params["query"] = q.String()
+	}
+}
@@ -0,0 +1,96 @@
+package searchcraigs
+
+import (
+	"net/url"
+	"testing"
+)
+
+// testSearchURL returns the search page URL for the options, with a client for sfbay.
+func testSearchURL(t *testing.T, options ...SearchOption) *url.URL {
+	t.Helper()
+
+	c, err := New(SFBay)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	params := map[string]interface{}{}
+	for _, opt := range options {
+		opt(params)
+	}
+
+	_, uri := c.searchURL(params)
+
+	u, err := url.Parse(uri)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return u
+}
+
+func TestQueryBuilder(t *testing.T) {
+	tests := []struct {
+		name  string
+		query *QueryBuilder
+		want  string
+	}{
+		{"raw", NewQuery("  road bike "), "road bike"},
+		{"all", NewQuery("").All("road", "bike"), "road bike"},
+		{"all with spaces", NewQuery("bike").All("disc brakes"), `bike "disc brakes"`},
+		{"any", NewQuery("bike").Any("shimano", "campagnolo"), "bike (shimano|campagnolo)"},
+		{"any with one term", NewQuery("bike").Any("shimano", " "), "bike shimano"},
+		{"any with spaces", NewQuery("").Any("road bike", "gravel"), `("road bike"|gravel)`},
+		{"exclude", NewQuery("bike").Exclude("carbon", "electric"), "bike -carbon -electric"},
+		{"exclude phrase", NewQuery("bike").Exclude("kids bike"), `bike -"kids bike"`},
+		{"phrase", NewQuery("").Phrase("road  bike"), `"road bike"`},
+		{"quotes removed", NewQuery("").Phrase(`the "best" bike`).Exclude(`"e-bike"`), `"the best bike" -e-bike`},
+		{"operators quoted", NewQuery("").All("a|b", "-x").Any("(c)"), `"a|b" "-x" "(c)"`},
+		{"everything", NewQuery("road bike").Any("shimano", "campagnolo").Exclude("carbon").Phrase("54 cm"),
+			`road bike (shimano|campagnolo) -carbon "54 cm"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.query.String(); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// the query parameter sent to craigslist, with the operators escaped
+func TestQueryParam(t *testing.T) {
+	tests := []struct {
+		name    string
+		options []SearchOption
+		want    string // query= in the URL, as encoded
+	}{
+		{"plain", []SearchOption{Query("road bike")}, "query=road+bike"},
+		{"any", []SearchOption{Query("bike"), QueryAny("shimano", "campagnolo")}, "query=bike+%28shimano%7Ccampagnolo%29"},
+		{"exclude", []SearchOption{Query("bike"), QueryExclude("carbon", "kids bike")}, "query=bike+-carbon+-%22kids+bike%22"},
+		{"phrase", []SearchOption{QueryPhrase("road bike")}, "query=%22road+bike%22"},
+		{"all", []SearchOption{QueryAll("a&b", "c=d")}, "query=a%26b+c%3Dd"},
+		{"raw operators", []SearchOption{Query(`(road|gravel) "54 cm" -carbon`)}, "query=%28road%7Cgravel%29+%2254+cm%22+-carbon"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := testSearchURL(t, tt.options...)
+
+			if u.RawQuery != tt.want {
+				t.Errorf("got %v, want %v", u.RawQuery, tt.want)
+			}
+
+			// and it decodes back to the query
+			params := map[string]interface{}{}
+			for _, opt := range tt.options {
+				opt(params)
+			}
+
+			if got := u.Query().Get("query"); got != params["query"] {
+				t.Errorf("decoded %q, want %q", got, params["query"])
+			}
+		})
+	}
+}
@@ -0,0 +1,323 @@
+package searchcraigs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/gobs/httpclient"
+)
+
+// the craigslist reference list of the sites (areas), with their subareas
+const regionsuri = "https://reference.craigslist.org/Areas"
+
+// ErrUnknownRegion is returned by CheckRegion for the regions not in the region list.
+var ErrUnknownRegion = errors.New("unknown region")
+
+// default time the downloaded region list is kept in the cache file
+const defaultRegionsTTL = 7 * 24 * time.Hour
+
+// RegionInfo is a craigslist region (site).
+type RegionInfo struct {
+	Code       Region          // the hostname, i.e. sfbay
+	Name       string          // i.e. SF bay area
+	Country    string          `json:",omitempty"`
+	SubRegions []SubRegionInfo `json:",omitempty"`
+}
+
+// SubRegionInfo is a subregion (subarea) of a region.
+type SubRegionInfo struct {
+	Code SubRegion // i.e. eby
+	Name string    // i.e. east bay area
+}
+
+// cachedRegions is the content of the region cache file (see WithRegionCache).
+type cachedRegions struct {
+	Fetched time.Time
+	Regions []RegionInfo
+}
+
+// WithRegionCache stores the region list downloaded by ListRegions in the file at path,
+// and uses it for ttl (the default is a week if ttl is 0) instead of downloading it again.
+func WithRegionCache(path string, ttl time.Duration) ClientOption {
+	return func(c *ClClient) error {
+		if ttl <= 0 {
+			ttl = defaultRegionsTTL
+		}
+
+		c.regionsPath, c.regionsTTL = path, ttl
+		return nil
+	}
+}
+
+// WithRegionCheck makes Search check the region with CheckRegion before sending the request.
+// The first check downloads the region list, one more request (see WithRegionCache).
+func WithRegionCheck() ClientOption {
+	return func(c *ClClient) error {
+		c.checkRegions = true
+		return nil
+	}
+}
+
+// ListRegions returns the craigslist regions, sorted by code. The list is downloaded once
+// per client, or once per TTL with WithRegionCache. If the download fails, the following
+// calls return the same error.
+func (c *ClClient) ListRegions() ([]RegionInfo, error) {
+	return c.ListRegionsContext(context.Background())
+}
+
+// ListRegionsContext is like ListRegions, but the request is cancelled when ctx is done.
+func (c *ClClient) ListRegionsContext(ctx context.Context) ([]RegionInfo, error) {
+	c.regionsMu.Lock()
+	defer c.regionsMu.Unlock()
+
+	if c.regions != nil || c.regionsErr != nil {
+		return c.regions, c.regionsErr
+	}
+
+	if cached, ok := c.loadRegionCache(); ok {
+		c.regions = cached
+		return c.regions, nil
+	}
+
+	regions, err := c.fetchRegions(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			c.regionsErr = err
+		}
+
+		return nil, err
+	}
+
+	if c.regionsPath != "" {
+		if data, err := json.Marshal(cachedRegions{Fetched: time.Now(), Regions: regions}); err == nil {
+			if err := writeFileAtomic(c.regionsPath, data); err != nil {
+				c.debug("cannot write the region cache", "error", err)
+			}
+		}
+	}
+
+	c.regions = regions
+	return c.regions, nil
+}
+
+// loadRegionCache returns the regions in the cache file, if there is one and it's not expired.
+func (c *ClClient) loadRegionCache() ([]RegionInfo, bool) {
+	if c.regionsPath == "" {
+		return nil, false
+	}
+
+	regions, err := readRegionCache(c.regionsPath, c.regionsTTL)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) && !errors.Is(err, errRegionCacheExpired) {
+			c.debug("invalid region cache", "path", c.regionsPath, "error", err)
+		}
+
+		return nil, false
+	}
+
+	return regions, true
+}
+
+var errRegionCacheExpired = errors.New("region cache expired")
+
+// readRegionCache returns the regions in the cache file at path, unless they are older than ttl
+// (the default if ttl is 0).
+func readRegionCache(path string, ttl time.Duration) ([]RegionInfo, error) {
+	if ttl <= 0 {
+		ttl = defaultRegionsTTL
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var cached cachedRegions
+	if err := json.Unmarshal(data, &cached); err != nil {
+		return nil, err
+	}
+
+	if len(cached.Regions) == 0 {
+		return nil, errors.New("no regions")
+	}
+
+	if time.Since(cached.Fetched) > ttl {
+		return nil, errRegionCacheExpired
+	}
+
+	return cached.Regions, nil
+}
+
+func (c *ClClient) fetchRegions(ctx context.Context) ([]RegionInfo, error) {
+	res, err := httpclient.CheckStatus(c.send(ctx, httpclient.URLString(regionsuri), httpclient.Accept("application/json")))
+	if err != nil {
+		return nil, fmt.Errorf("cannot get the region list: %w", err)
+	}
+
+	defer res.Body.Close()
+
+	var areas []struct {
+		Hostname    string
+		Description string
+		Country     string
+		SubAreas    []struct {
+			Abbreviation string
+			Description  string
+		}
+	}
+
+	if err := json.NewDecoder(res.Body).Decode(&areas); err != nil {
+		return nil, fmt.Errorf("cannot parse the region list: %w", err)
+	}
+
+	regions := make([]RegionInfo, 0, len(areas))
+
+	for _, a := range areas {
+		if a.Hostname == "" {
+			continue
+		}
+
+		r := RegionInfo{Code: Region(a.Hostname), Name: a.Description, Country: a.Country}
+		for _, s := range a.SubAreas {
+			r.SubRegions = append(r.SubRegions, SubRegionInfo{Code: SubRegion(s.Abbreviation), Name: s.Description})
+		}
+
+		regions = append(regions, r)
+	}
+
+	sort.Slice(regions, func(i, j int) bool { return regions[i].Code < regions[j].Code })
+
+	if len(regions) == 0 {
+		return nil, errors.New("the region list is empty")
+	}
+
+	return regions, nil
+}
+
+// ListSubRegions returns the subregions of region, from the subarea selector of its search page,
+// or from the region list if the page doesn't have one. Regions without subregions return an empty list.
+func (c *ClClient) ListSubRegions(region Region) ([]SubRegionInfo, error) {
+	return c.ListSubRegionsContext(context.Background(), region)
+}
+
+// ListSubRegionsContext is like ListSubRegions, but the request is cancelled when ctx is done.
+func (c *ClClient) ListSubRegionsContext(ctx context.Context, region Region) ([]SubRegionInfo, error) {
+	res, err := httpclient.CheckStatus(c.send(ctx, httpclient.URLString(fmt.Sprintf(searchuri, region)+string(ForSale))))
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err := c.readDocument(res)
+	if err != nil {
+		return nil, err
+	}
+
+	var subregions []SubRegionInfo
+
+	doc.Find("#subArea option, select[name=subarea] option").Each(func(_ int, s *goquery.Selection) {
+		if code, _ := s.Attr("value"); code != "" {
+			subregions = append(subregions, SubRegionInfo{Code: SubRegion(code), Name: strings.TrimSpace(s.Text())})
+		}
+	})
+
+	if len(subregions) > 0 {
+		return subregions, nil
+	}
+
+	regions, err := c.ListRegionsContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, r := range regions {
+		if r.Code == region {
+			return r.SubRegions, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// regionCountry returns the country of the region in the region list, if it was already
+// downloaded or is in the cache, or countryOf (the built-in table) when it's not available.
+func (c *ClClient) regionCountry(region Region) string {
+	c.regionsMu.Lock()
+	if c.regions == nil && c.regionsErr == nil {
+		if cached, ok := c.loadRegionCache(); ok {
+			c.regions = cached
+		}
+	}
+
+	regions := c.regions
+	c.regionsMu.Unlock()
+
+	for _, r := range regions {
+		if r.Code == region && r.Country != "" {
+			return r.Country
+		}
+	}
+
+	return countryOf(region)
+}
+
+// CheckRegion returns an error if region is not in the region list (see ListRegions),
+// suggesting the closest region code. With WithRegionCheck, Search calls it before sending the request.
+func (c *ClClient) CheckRegion(ctx context.Context, region Region) error {
+	regions, err := c.ListRegionsContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	best, bestDist := Region(""), -1
+
+	for _, r := range regions {
+		if r.Code == region {
+			return nil
+		}
+
+		if d := editDistance(string(region), string(r.Code)); bestDist < 0 || d < bestDist {
+			best, bestDist = r.Code, d
+		}
+	}
+
+	// only suggest codes that look like a typo, not any region
+	if bestDist >= 0 && bestDist <= len(region)/2 {
+		return fmt.Errorf("%w %q, did you mean %q?", ErrUnknownRegion, region, best)
+	}
+
+	return fmt.Errorf("%w %q", ErrUnknownRegion, region)
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+
+		prev, cur = cur, prev
+	}
+
+	return prev[len(b)]
+}
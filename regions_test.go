@@ -0,0 +1,163 @@
+package searchcraigs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"testing"
+)
+
+const testAreas = `[
+  {"Abbreviation": "sfo", "Hostname": "sfbay", "Description": "SF bay area", "Country": "US",
+   "SubAreas": [{"Abbreviation": "eby", "Description": "east bay area"}, {"Abbreviation": "sfc", "Description": "city of san francisco"}]},
+  {"Abbreviation": "sea", "Hostname": "seattle", "Description": "seattle-tacoma", "Country": "US"},
+  {"Abbreviation": "tor", "Hostname": "toronto", "Description": "toronto", "Country": "CA"}
+]`
+
+// areasServer serves testAreas as the region list.
+func areasServer(t *testing.T) *testServer {
+	return newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Host+r.URL.Path != "reference.craigslist.org/Areas" {
+			http.NotFound(w, r)
+			return
+		}
+
+		fmt.Fprint(w, testAreas)
+	}))
+}
+
+func TestListRegions(t *testing.T) {
+	s := areasServer(t)
+	cache := filepath.Join(t.TempDir(), "regions.json")
+
+	for i := 0; i < 2; i++ {
+		c := s.client(t, WithRegionCache(cache, 0))
+
+		regions, err := c.ListRegions()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(regions) != 3 || regions[0].Code != "seattle" || regions[1].Code != "sfbay" || regions[1].Name != "SF bay area" {
+			t.Fatalf("regions %+v", regions)
+		}
+
+		if sub := regions[1].SubRegions; len(sub) != 2 || sub[0] != (SubRegionInfo{Code: "eby", Name: "east bay area"}) {
+			t.Errorf("subregions %+v", sub)
+		}
+	}
+
+	if n := len(s.received()); n != 1 {
+		t.Errorf("%v requests, the second client should use the cache", n)
+	}
+}
+
+func TestCheckRegion(t *testing.T) {
+	c := &ClClient{regions: []RegionInfo{{Code: "sfbay"}, {Code: "seattle"}, {Code: "losangeles"}}}
+
+	tests := []struct {
+		region Region
+		want   string
+	}{
+		{"sfbay", ""},
+		{"sfbayy", `unknown region "sfbayy", did you mean "sfbay"?`},
+		{"seatle", `unknown region "seatle", did you mean "seattle"?`},
+		{"losangels", `unknown region "losangels", did you mean "losangeles"?`},
+		{"xyz", `unknown region "xyz"`},
+	}
+
+	for _, tt := range tests {
+		err := c.CheckRegion(context.Background(), tt.region)
+
+		if tt.want == "" {
+			if err != nil {
+				t.Errorf("%v: %v", tt.region, err)
+			}
+
+			continue
+		}
+
+		if !errors.Is(err, ErrUnknownRegion) || err.Error() != tt.want {
+			t.Errorf("%v: got %v, want %v", tt.region, err, tt.want)
+		}
+	}
+}
+
+func TestRegionCountry(t *testing.T) {
+	c := &ClClient{regions: []RegionInfo{{Code: "toronto", Country: "CA"}, {Code: "auckland", Country: "NZ"}, {Code: "sfbay"}}}
+	offline := &ClClient{regionsErr: errors.New("offline")}
+
+	tests := []struct {
+		client *ClClient
+		region Region
+		want   string
+	}{
+		{c, "toronto", "CA"},
+		{c, "auckland", "NZ"},
+		{c, "sfbay", "US"},
+		{c, "london", "GB"},
+		{offline, "toronto", "CA"},
+		{offline, "auckland", "US"},
+		{offline, "london", "GB"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.client.regionCountry(tt.region); got != tt.want {
+			t.Errorf("%v: got %v, want %v", tt.region, got, tt.want)
+		}
+	}
+}
+
+func TestSearchUnknownRegion(t *testing.T) {
+	s := areasServer(t)
+	c := s.client(t, WithRegionCheck())
+
+	if _, err := c.Search(WithRegion("sfbayy"), Query("bike")); !errors.Is(err, ErrUnknownRegion) {
+		t.Errorf("got %v, want ErrUnknownRegion", err)
+	}
+
+	if _, err := c.Search(WithRegion("seatle"), Query("bike")); !errors.Is(err, ErrUnknownRegion) {
+		t.Errorf("got %v, want ErrUnknownRegion", err)
+	}
+
+	if n := len(s.received()); n != 1 {
+		t.Errorf("%v requests for the region list, want 1", n)
+	}
+}
+
+// without WithRegionCheck, Search doesn't download the region list
+func TestSearchNoRegionCheck(t *testing.T) {
+	s := areasServer(t)
+
+	if _, err := s.client(t).Search(WithRegion("sfbayy"), Query("bike")); errors.Is(err, ErrUnknownRegion) {
+		t.Errorf("got %v, the region shouldn't be checked", err)
+	}
+
+	for _, r := range s.received() {
+		if r.url == "reference.craigslist.org/Areas" {
+			t.Error("the region list was downloaded")
+		}
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"sfbay", "sfbay", 0},
+		{"sfbay", "sfbayy", 1},
+		{"seatle", "seattle", 1},
+		{"kitten", "sitting", 3},
+		{"abc", "", 3},
+	}
+
+	for _, tt := range tests {
+		if got := editDistance(tt.a, tt.b); got != tt.want {
+			t.Errorf("editDistance(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
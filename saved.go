@@ -0,0 +1,159 @@
+package searchcraigs
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// SavedSearch is a named search in the config file, i.e.
+//
+//	{ "roadbike": { "region": "sfbay", "category": "bikes", "query": "road bike", "max": 1000, "sort": "date" } }
+type SavedSearch struct {
+	Region    string `json:"region,omitempty" flag:"region"`
+	Subregion string `json:"subregion,omitempty" flag:"subregion"`
+	Category  string `json:"category,omitempty" flag:"cat"`
+	Query     string `json:"query,omitempty"`
+	Filter    string `json:"filter,omitempty" flag:"filter"`
+	MinPrice  int    `json:"min,omitempty" flag:"min"`
+	MaxPrice  int    `json:"max,omitempty" flag:"max"`
+	Sort      string `json:"sort,omitempty" flag:"sort"`
+	Title     string `json:"title,omitempty" flag:"title"` // title template, see ParseTitleTemplate
+}
+
+// SavedSearches maps the search names to the searches.
+type SavedSearches map[string]SavedSearch
+
+// DefaultConfig returns the default config file, ~/.searchcraigs.json.
+func DefaultConfig() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ".searchcraigs.json"
+	}
+
+	return filepath.Join(home, ".searchcraigs.json")
+}
+
+// LoadSavedSearches reads the saved searches in the config file at path.
+// Unknown keys in the searches are returned as warnings.
+func LoadSavedSearches(path string) (SavedSearches, []string, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil, fmt.Errorf("config file %v not found", path)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var raw map[string]map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, nil, fmt.Errorf("%v: %w", path, err)
+	}
+
+	known := map[string]bool{}
+	t := reflect.TypeOf(SavedSearch{})
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		known[name] = true
+	}
+
+	var warnings []string
+
+	for name, search := range raw {
+		for k := range search {
+			if !known[k] {
+				warnings = append(warnings, fmt.Sprintf("%v: unknown key %q in saved search %q", path, k, name))
+			}
+		}
+	}
+
+	sort.Strings(warnings)
+
+	var searches SavedSearches
+	if err := json.Unmarshal(data, &searches); err != nil {
+		return nil, nil, fmt.Errorf("%v: %w", path, err)
+	}
+
+	for name, s := range searches {
+		if _, err := ParseFilter(s.Filter); err != nil {
+			return nil, nil, fmt.Errorf("%v: saved search %q: %w", path, name, err)
+		}
+
+		if s.Title == "" {
+			continue
+		}
+
+		if _, err := ParseTitleTemplate(s.Title); err != nil {
+			return nil, nil, fmt.Errorf("%v: saved search %q: %w", path, name, err)
+		}
+	}
+
+	return searches, warnings, nil
+}
+
+// Names returns the names of the saved searches, sorted.
+func (s SavedSearches) Names() []string {
+	return sortedKeys(s)
+}
+
+// Get returns the saved search with the given name.
+func (s SavedSearches) Get(name string) (SavedSearch, error) {
+	search, ok := s[name]
+	if !ok {
+		return SavedSearch{}, fmt.Errorf("no saved search %q (available: %v)", name, strings.Join(s.Names(), ", "))
+	}
+
+	return search, nil
+}
+
+// Apply sets the flags in fs from the saved search, except the ones already set
+// (so that command line flags override the saved values). The query is not a flag, see Query.
+func (s SavedSearch) Apply(fs *flag.FlagSet) error {
+	set := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+
+	v := reflect.ValueOf(s)
+	t := v.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		name := t.Field(i).Tag.Get("flag")
+		if name == "" || set[name] || v.Field(i).IsZero() {
+			continue
+		}
+
+		var value string
+		switch f := v.Field(i).Interface().(type) {
+		case string:
+			value = f
+		case int:
+			value = strconv.Itoa(f)
+		}
+
+		if err := fs.Set(name, value); err != nil {
+			return fmt.Errorf("saved search %v: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+// Options returns the search options for the saved search (Filter is applied locally, see ParseFilter).
+func (s SavedSearch) Options() []SearchOption {
+	return []SearchOption{
+		WithRegion(Region(s.Region)),
+		WithSubregion(SubRegion(s.Subregion)),
+		WithCategory(mapCategory(s.Category)),
+		Query(s.Query),
+		TitleOnly(s.Filter != ""),
+		MinPrice(s.MinPrice),
+		MaxPrice(s.MaxPrice),
+		Sort(SortType(s.Sort)),
+	}
+}
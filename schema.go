@@ -0,0 +1,116 @@
+package searchcraigs
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// SchemaVersion is the version of the output schema (the JSON output, and the SearchResults and
+// ResultEntry fields). It must be incremented for any change in the output:
+// adding, removing or renaming fields, or changing what the values mean.
+// The schema hash (see OutputSchema) changes when the fields change, as a reminder.
+const SchemaVersion = 7
+
+// Schema describes the JSON output.
+type Schema struct {
+	SchemaVersion int           `json:"schema_version"`
+	Hash          string        `json:"hash"`
+	Fields        []SchemaField `json:"fields"`
+}
+
+type SchemaField struct {
+	Name        string        `json:"name"`
+	Type        string        `json:"type"`
+	Description string        `json:"description,omitempty"`
+	Optional    bool          `json:"optional,omitempty"`
+	Items       string        `json:"items,omitempty"` // type of the array elements
+	Fields      []SchemaField `json:"fields,omitempty"`
+}
+
+// OutputSchema returns the schema of the JSON output, generated from the SearchResults struct.
+func OutputSchema() Schema {
+	fields := schemaFields(reflect.TypeOf(SearchResults{}))
+
+	data, _ := json.Marshal(fields)
+
+	return Schema{
+		SchemaVersion: SchemaVersion,
+		Hash:          fmt.Sprintf("%x", sha256.Sum256(data)),
+		Fields:        fields,
+	}
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func schemaType(t reflect.Type) (string, []SchemaField) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	if t == timeType {
+		return "date-time", nil
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return "string", nil
+
+	case reflect.Bool:
+		return "boolean", nil
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer", nil
+
+	case reflect.Float32, reflect.Float64:
+		return "number", nil
+
+	case reflect.Slice, reflect.Array:
+		return "array", nil
+
+	case reflect.Map:
+		return "object", nil
+
+	case reflect.Struct:
+		return "object", schemaFields(t)
+	}
+
+	return t.Kind().String(), nil
+}
+
+func schemaFields(t reflect.Type) (fields []SchemaField) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+
+		name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+
+		sf := SchemaField{
+			Name:        name,
+			Description: f.Tag.Get("desc"),
+			Optional:    f.Type.Kind() == reflect.Pointer || strings.Contains(opts, "omitempty"),
+		}
+
+		sf.Type, sf.Fields = schemaType(f.Type)
+
+		if sf.Type == "array" {
+			sf.Items, sf.Fields = schemaType(f.Type.Elem())
+		}
+
+		fields = append(fields, sf)
+	}
+
+	return
+}
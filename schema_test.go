@@ -0,0 +1,89 @@
+package searchcraigs
+
+import (
+	"bytes"
+	"encoding/csv"
+	"reflect"
+	"slices"
+	"strconv"
+	"testing"
+)
+
+// The hash of the output schema for SchemaVersion schemaHashVersion.
+// When the test fails because the schema changed, increment SchemaVersion
+// and update both constants.
+const (
+	schemaHash        = "33c8450d5df6d9fe33496a34063d0a1aa2b3bd951e64c4425009f5959048c727"
+	schemaHashVersion = 7
+)
+
+func TestSchemaVersion(t *testing.T) {
+	schema := OutputSchema()
+
+	switch {
+	case schema.Hash == schemaHash && SchemaVersion == schemaHashVersion:
+		// no changes
+
+	case schema.Hash == schemaHash:
+		t.Errorf("SchemaVersion changed to %v but the schema didn't: set schemaHashVersion to %v", SchemaVersion, SchemaVersion)
+
+	case SchemaVersion == schemaHashVersion:
+		t.Errorf("the output schema changed (hash %v) without incrementing SchemaVersion (%v): increment it and update schemaHash", schema.Hash, SchemaVersion)
+
+	default:
+		t.Errorf("the output schema changed for version %v: set schemaHash to %q and schemaHashVersion to %v",
+			SchemaVersion, schema.Hash, SchemaVersion)
+	}
+
+	if schema.SchemaVersion != SchemaVersion {
+		t.Errorf("schema_version %v, want %v", schema.SchemaVersion, SchemaVersion)
+	}
+}
+
+func TestCSVSchemaVersion(t *testing.T) {
+	entries := []ResultEntry{{Title: "desk", Price: "$50"}, {Title: "chair"}}
+
+	tests := []struct {
+		name   string
+		fields []string
+		header []string
+	}{
+		{"default", nil, append(DefaultFields[:len(DefaultFields):len(DefaultFields)], "schema_version")},
+		{"fields", []string{"title", "price"}, []string{"title", "price", "schema_version"}},
+		{"selected", []string{"schema_version", "title"}, []string{"schema_version", "title"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b bytes.Buffer
+			if err := WriteCSV(&b, entries, tt.fields); err != nil {
+				t.Fatal(err)
+			}
+
+			rows, err := csv.NewReader(&b).ReadAll()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(rows) != len(entries)+1 {
+				t.Fatalf("%v rows, want %v", len(rows), len(entries)+1)
+			}
+
+			if got := rows[0]; !reflect.DeepEqual(got, tt.header) {
+				t.Errorf("header %v, want %v", got, tt.header)
+			}
+
+			col := slices.Index(rows[0], "schema_version")
+
+			for _, row := range rows[1:] {
+				if row[col] != strconv.Itoa(SchemaVersion) {
+					t.Errorf("schema_version %q, want %v", row[col], SchemaVersion)
+				}
+			}
+		})
+	}
+
+	if len(DefaultFields) != 7 || DefaultFields[len(DefaultFields)-1] != "image" {
+		t.Errorf("WriteCSV changed DefaultFields: %v", DefaultFields)
+	}
+}
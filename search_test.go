@@ -0,0 +1,351 @@
+package searchcraigs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testServer stands in for craigslist (and the other sites) in the tests: the clients returned
+// by client send all their requests to it, whatever the URL. It records the requests received.
+type testServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	requests []testRequest
+}
+
+// testRequest is a request received by a testServer.
+type testRequest struct {
+	time      time.Time
+	method    string
+	url       string // host and path, i.e. sfbay.craigslist.org/search/sss?query=bike
+	userAgent string
+	header    http.Header
+	body      string
+}
+
+func newTestServer(t testing.TB, handler http.Handler) *testServer {
+	s := unstartedTestServer(handler)
+	s.Start()
+
+	t.Cleanup(s.Close)
+	return s
+}
+
+// newTLSTestServer is newTestServer serving HTTPS, for the requests going through a proxy.
+func newTLSTestServer(t testing.TB, handler http.Handler) *testServer {
+	s := unstartedTestServer(handler)
+	s.StartTLS()
+
+	t.Cleanup(s.Close)
+	return s
+}
+
+func unstartedTestServer(handler http.Handler) *testServer {
+	s := &testServer{}
+
+	s.Server = httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		s.mu.Lock()
+		s.requests = append(s.requests, testRequest{time: time.Now(), method: r.Method, url: r.Host + r.URL.RequestURI(),
+			userAgent: r.UserAgent(), header: r.Header.Clone(), body: string(body)})
+		s.mu.Unlock()
+
+		handler.ServeHTTP(w, r)
+	}))
+
+	return s
+}
+
+// client returns a client for sfbay sending all its requests (http and https) to s.
+func (s *testServer) client(t testing.TB, options ...ClientOption) *ClClient {
+	t.Helper()
+
+	c, err := New(SFBay, options...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tr, ok := c.h.GetTransport().(*http.Transport)
+	if !ok {
+		t.Fatalf("cannot redirect the requests of %T", c.h.GetTransport())
+	}
+
+	dial := func(ctx context.Context, network, _ string) (net.Conn, error) {
+		return (&net.Dialer{}).DialContext(ctx, network, s.Listener.Addr().String())
+	}
+
+	tr.Proxy, tr.DialContext, tr.DialTLSContext = nil, dial, dial
+	return c
+}
+
+// received returns the requests received so far.
+func (s *testServer) received() []testRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]testRequest(nil), s.requests...)
+}
+
+// testResponse is a response served by responses.
+type testResponse struct {
+	status int
+	body   string
+	header map[string]string
+}
+
+// responses returns a handler serving the responses in order, repeating the last one.
+func responses(list ...testResponse) http.HandlerFunc {
+	var mu sync.Mutex
+	n := 0
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		res := list[min(n, len(list)-1)]
+		n++
+		mu.Unlock()
+
+		for k, v := range res.header {
+			w.Header().Set(k, v)
+		}
+
+		w.WriteHeader(res.status)
+		w.Write([]byte(res.body))
+	}
+}
+
+// the exact search URL (category path and parameters) for the seller, crypto and delivery options
+func TestSearchURLSellerOptions(t *testing.T) {
+	const base = "https://sfbay.craigslist.org/search/"
+
+	tests := []struct {
+		name    string
+		options []SearchOption
+		want    string
+	}{
+		{"default", nil, base + "sss"},
+		{"all", []SearchOption{SellerType("all")}, base + "sss"},
+		{"empty", []SearchOption{SellerType("")}, base + "sss"},
+		{"cars owner", []SearchOption{WithCategory(Cars), SellerType("owner")}, base + "cto"},
+		{"cars dealer", []SearchOption{WithCategory(Cars), SellerType("dealer")}, base + "ctd"},
+		{"cars all", []SearchOption{WithCategory(Cars), SellerType("all")}, base + "cta"},
+		{"bikes owner", []SearchOption{WithCategory(Bikes), By("owner")}, base + "bik"},
+		{"services owner", []SearchOption{WithCategory(Services), SellerType("owner")}, base + "bbb?purveyor=owner"},
+		{"unknown category dealer", []SearchOption{WithCategory("xyz"), SellerType("dealer")}, base + "xyz?purveyor=dealer"},
+		{"crypto", []SearchOption{CryptoAccepted(true)}, base + "sss?crypto_currency=1"},
+		{"no crypto", []SearchOption{CryptoAccepted(false)}, base + "sss"},
+		{"delivery", []SearchOption{DeliveryAvailable(true)}, base + "sss?delivery_available=1"},
+		{"no delivery", []SearchOption{DeliveryAvailable(false)}, base + "sss"},
+		{"crypto and delivery", []SearchOption{CryptoAccepted(true), DeliveryAvailable(true)},
+			base + "sss?crypto_currency=1&delivery_available=1"},
+		{"cars owner crypto delivery", []SearchOption{WithCategory(Cars), SellerType("owner"), CryptoAccepted(true), DeliveryAvailable(true)},
+			base + "cto?crypto_currency=1&delivery_available=1"},
+		{"services dealer delivery", []SearchOption{WithCategory(Services), SellerType("dealer"), DeliveryAvailable(true)},
+			base + "bbb?delivery_available=1&purveyor=dealer"},
+		{"subregion and query", []SearchOption{WithSubregion(EastBay), WithCategory(Cars), SellerType("owner"), Query("civic"), MinPrice(1000), MaxPrice(5000)},
+			base + "eby/cto?max_price=5000&min_price=1000&query=civic"},
+		{"other region", []SearchOption{WithRegion("seattle"), Query("bike")}, "https://seattle.craigslist.org/search/sss?query=bike"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := testSearchURL(t, tt.options...).String(); got != tt.want {
+				t.Errorf("got  %v\nwant %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// searchPage returns a (legacy) search page with a result row for each title, and a link to the next page if next is not empty.
+func searchPage(next string, titles ...string) testResponse {
+	rows := make([]testRow, len(titles))
+	for i, title := range titles {
+		rows[i] = testRow{title: title}
+	}
+
+	return searchPageRows(next, rows...)
+}
+
+// testRow is a result row of a page returned by searchPageRows.
+type testRow struct {
+	title    string
+	datetime string // i.e. 2026-05-01 12:00
+}
+
+// searchPageRows is searchPage with the posting time of the rows.
+func searchPageRows(next string, rows ...testRow) testResponse {
+	var b strings.Builder
+
+	b.WriteString(`<html><body><div class="search-legend"><span class="buttons">`)
+	if next != "" {
+		fmt.Fprintf(&b, `<a href="%v" class="button next">next &gt; </a>`, html.EscapeString(next))
+	}
+	b.WriteString(`</span></div><ul class="rows">`)
+
+	for _, row := range rows {
+		fmt.Fprintf(&b, `<li class="result-row"><div class="result-info"><time class="result-date" datetime="%v"></time>`+
+			`<h3 class="result-heading"><a href="https://sfbay.craigslist.org/sfc/bik/d/%v.html">%v</a></h3>`+
+			`<span class="result-meta"><span class="result-price">$100</span></span></div></li>`,
+			row.datetime, strings.ReplaceAll(row.title, " ", "-"), row.title)
+	}
+
+	b.WriteString(`</ul></body></html>`)
+	return testResponse{status: http.StatusOK, body: b.String()}
+}
+
+// SearchAll follows the Next links (relative or absolute) until there are no more pages, a page has no rows
+// or maxPages pages are fetched, removing the duplicates across pages
+func TestSearchAllPages(t *testing.T) {
+	tests := []struct {
+		name     string
+		maxPages int
+		pages    []testResponse
+		want     []string // titles
+		urls     []string // pages requested
+		next     bool     // more pages after the last one fetched
+	}{
+		{
+			"relative", 5,
+			[]testResponse{searchPage("/search/sss?query=bike&s=120", "bike 1", "bike 2"), searchPage("/search/sss?query=bike&s=240", "bike 3"), searchPage("", "bike 4")},
+			[]string{"bike 1", "bike 2", "bike 3", "bike 4"},
+			[]string{"/search/sss?query=bike", "/search/sss?query=bike&s=120", "/search/sss?query=bike&s=240"},
+			false,
+		},
+		{
+			"absolute", 5,
+			[]testResponse{searchPage("https://sfbay.craigslist.org/search/sss?query=bike&s=120", "bike 1"), searchPage("", "bike 2")},
+			[]string{"bike 1", "bike 2"},
+			[]string{"/search/sss?query=bike", "/search/sss?query=bike&s=120"},
+			false,
+		},
+		{
+			"max pages", 2,
+			[]testResponse{searchPage("?query=bike&s=120", "bike 1"), searchPage("?query=bike&s=240", "bike 2"), searchPage("", "bike 3")},
+			[]string{"bike 1", "bike 2"},
+			[]string{"/search/sss?query=bike", "/search/sss?query=bike&s=120"},
+			true,
+		},
+		{
+			// the next link is still there, but it would return the same empty page
+			"empty page", 10,
+			[]testResponse{searchPage("?query=bike&s=120", "bike 1"), searchPage("?query=bike&s=240")},
+			[]string{"bike 1"},
+			[]string{"/search/sss?query=bike", "/search/sss?query=bike&s=120"},
+			false,
+		},
+		{
+			// the second page has only duplicates, but it's not empty
+			"duplicates", 5,
+			[]testResponse{searchPage("?query=bike&s=120", "bike 1", "bike 2"), searchPage("?query=bike&s=240", "bike 2", "bike 1"), searchPage("", "bike 2", "bike 3")},
+			[]string{"bike 1", "bike 2", "bike 3"},
+			[]string{"/search/sss?query=bike", "/search/sss?query=bike&s=120", "/search/sss?query=bike&s=240"},
+			false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t, responses(tt.pages...))
+
+			res, err := s.client(t).SearchAll(tt.maxPages, Query("bike"), Dedup(true))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var titles []string
+			for _, e := range res.Entries {
+				titles = append(titles, e.Title)
+			}
+
+			if !slices.Equal(titles, tt.want) {
+				t.Errorf("entries %q, want %q", titles, tt.want)
+			}
+
+			var urls []string
+			for _, r := range s.received() {
+				urls = append(urls, strings.TrimPrefix(strings.Replace(r.url, "bundleDuplicates=1&", "", 1), "sfbay.craigslist.org"))
+			}
+
+			if !slices.Equal(urls, tt.urls) {
+				t.Errorf("requests %q, want %q", urls, tt.urls)
+			}
+
+			if (res.Next != "") != tt.next {
+				t.Errorf("Next %q", res.Next)
+			}
+		})
+	}
+}
+
+func TestSearchNextNoMorePages(t *testing.T) {
+	s := newTestServer(t, responses(searchPage("")))
+
+	if _, err := s.client(t).SearchNext(&SearchResults{Url: "https://sfbay.craigslist.org/search/sss"}); !errors.Is(err, ErrNoMorePages) {
+		t.Errorf("got %v, want ErrNoMorePages", err)
+	}
+
+	if n := len(s.received()); n != 0 {
+		t.Errorf("%v requests, want none", n)
+	}
+}
+
+func TestOlderThan(t *testing.T) {
+	watermark := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	before, after := watermark.Add(-time.Hour), watermark.Add(time.Hour)
+
+	tests := []struct {
+		name      string
+		watermark time.Time
+		posted    []time.Time
+		want      bool
+	}{
+		{"all older", watermark, []time.Time{before, before.Add(-time.Hour)}, true},
+		{"one newer", watermark, []time.Time{before, after}, false},
+		{"same time", watermark, []time.Time{watermark}, false},
+		{"missing date", watermark, []time.Time{before, {}}, false},
+		{"no dates", watermark, []time.Time{{}, {}}, false},
+		{"empty page", watermark, nil, false},
+		{"no watermark", time.Time{}, []time.Time{before}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page := &SearchResults{}
+			for _, p := range tt.posted {
+				page.Entries = append(page.Entries, ResultEntry{Posted: p})
+			}
+
+			if got := OlderThan(tt.watermark)(page); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// pages without dates don't stop the search, that fetches up to maxPages pages
+func TestSearchAllOlderThanNoDates(t *testing.T) {
+	s := newTestServer(t, responses(searchPage("?s=120", "bike 1"), searchPage("?s=240", "bike 2"), searchPage("?s=360", "bike 3")))
+
+	res, err := s.client(t).SearchAll(2, Query("bike"), StopWhen(OlderThan(time.Now())))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(res.Entries) != 2 || len(s.received()) != 2 {
+		t.Errorf("%v entries in %v pages, want 2 in 2", len(res.Entries), len(s.received()))
+	}
+}
@@ -1,23 +1,34 @@
-package main
+package searchcraigs
 
 import (
 	"bytes"
+	"context"
 	"encoding/base64"
+	"errors"
 	"flag"
 	"fmt"
-	"hash/maphash"
-	"html/template"
+	"hash/fnv"
+	"io"
 	"log"
+	"log/slog"
+	"net/http"
+	"net/url"
 	"os"
 	"os/exec"
-	"regexp"
+	"os/signal"
+	"path/filepath"
 	"runtime"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	texttemplate "text/template"
+	"time"
 
 	"golang.org/x/net/publicsuffix"
 	"net/http/cookiejar"
 
-	"github.com/PuerkitoBio/goquery"
 	"github.com/gobs/httpclient"
 	"github.com/gobs/simplejson"
 )
@@ -25,10 +36,31 @@ import (
 // https://{region}.craigslist.org/search[/area]/{category}?query={}&sort={}&hasPic=1&srchType=T&postedToday=1&bundleDuplicates=1&seach_distance={}&postal={}&min_price={}&max_price={}&crypto_currency=1&delivery_available=1
 
 type ClClient struct {
-	h *httpclient.HttpClient
+	h      *httpclient.HttpClient
+	region Region
+
+	retries int
+	backoff time.Duration
+	limiter *rateLimiter
+	verbose bool
+	logger  *slog.Logger
+
+	dumpPath string       // see WithDumpHTML
+	dumps    atomic.Int32 // pages dumped
+
+	regionsPath string // see WithRegionCache
+	regionsTTL  time.Duration
+	regionsMu   sync.Mutex
+	regions     []RegionInfo // see ListRegions
+	regionsErr  error        // the region list download failed, don't try again
+
+	checkRegions bool // see WithRegionCheck
+
+	detailsCache *DetailsCache // see WithDetailsCache
 }
 
-func New(region Region) *ClClient {
+// New returns a client for the region, configured with options.
+func New(region Region, options ...ClientOption) (*ClClient, error) {
 	uri := fmt.Sprintf(searchuri, region)
 	client := httpclient.NewHttpClient(uri)
 	client.UserAgent = `Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/92.0.4515.131 Safari/537.36`
@@ -36,11 +68,22 @@ func New(region Region) *ClClient {
 
 	jar, err := cookiejar.New(&cookiejar.Options{PublicSuffixList: publicsuffix.List})
 	if err != nil {
-		log.Fatal("cannot create cookiejar: ", err)
+		return nil, fmt.Errorf("cannot create cookiejar: %w", err)
 	}
 	client.SetCookieJar(jar)
 
-	return &ClClient{h: client}
+	c := &ClClient{h: client, region: region, backoff: defaultBackoff}
+	for _, opt := range options {
+		if err := opt(c); err != nil {
+			return nil, err
+		}
+	}
+
+	if c.verbose && c.logger == nil {
+		c.logger = slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	}
+
+	return c, nil
 }
 
 type Region string
@@ -72,76 +115,67 @@ const (
 	Computers   = Category("sya")
 	Electronics = Category("ela")
 	Free        = Category("zip")
-	Music       = Category("msa")
-	RVs         = Category("rva")
-	Sporting    = Category("sga")
-	Tools       = Category("tla")
-
-	pageTemplate = `<!DOCTYPE html>
-<html>
-  <head>
-    <title>{{ .Title }}</title>
-    <meta charset="UTF-8">
-    <link rel="stylesheet" href="https://cdnjs.cloudflare.com/ajax/libs/mini.css/3.0.1/mini-default.min.css">
-    <style>
-      .indent {
-        padding-left: 12px;
-      }
-    </style>
-  <head>
-  <body>
-    <h2>
-      <a href="{{ .Url }}">{{ .Title }}</a>
-      {{ if .Subtitle }}
-        <small>({{ .Subtitle }})</small>
-      {{ end }}
-    </h2>
-
-    <div class="container">
-    {{ range .Entries }}
-      <div class="row">
-        <div class="col-sm-2">
-          <a href="{{ .Href }}">
-          {{ if .Image }}
-            <img src="{{ .Image }}">
-          {{ else }}
-          <img src="data:image/png;base64,iVBORw0KGgoAAAANSUhEUgAAAOEAAADhCAMAAAAJbSJIAAAAaVBMVEX///9mZmZfX19nZ2dcXFxiYmKysrL29vbAwMD7+/tzc3NZWVmTk5NdXV34+PjOzs58fHzg4OCioqKGhobY2Nju7u6cnJypqamUlJTm5ubHx8eMjIxtbW2xsbGlpaW8vLyDg4NPT09JSUm0hXY6AAAOgklEQVR4nO1dCZurLA9VhFqhuFWtVrvM9/9/5JcEu8zMbcdt1HtfzvPM0oKYYyBsITqOhYWFhYWFhYWFhYWFhYWFxRvkx3O564ryfMyXFrgnkj3njImuYIzzfba00D0QlEq4AK8rMLNQZbC04F3hS+aSzN1B+Zn0lxa9GwKJAjMl687tsJYKH4qQf4cWSxSWF1Wvi6pCuZ7Lyl+SaVJkCjUY9b4u0nCd+hvMTSw8l/cnCBS564l4cnkmR648VxSDLi2E66n194sXaIV8mJg5h+p9mVie6QGKEHLgtWCEB6p/TtQg5VCLWMK1+0ml+Q3shcvSgdem7F9nWPzzDNPla2meRD8gi91RDN04++kWyW91KPm2Flzxn4DDyzEM3R/vwLmot9OzTGqu8f400fkBgxkWokPpKIHgvE4m5ZfX7YSvgwQwuBzaa29Up/Lb6WQ9oR63WtAslSnF2U9VSPPd0DlQUP/cChhXNNOCMazeTkWwUOahyUNU+cGPGHOrn0sP/Co6SFOl1EQDoJqshyqnrfjjkJh1El5PUdgO520s7jeh/X1UMdZVvRtf0gE06Knz+IImxxnmaS4/jC2GZuyDjePv4kKyjV0VaFCDm0kEmh4b1GIzsgzteqx21rIElh+30WPFMXBq5rl61PMPyCivZRXTr7E7VkX4+Ia6sTHP/whmhk/Wr45E7ppxlZYPilvueqMEjLHE8bJNg1jQMjp0XU9dBNgJd8T6nK+gFa7FzERgOdn1hAvq6tE5b6AlquHNKMJKupauvhAew1EaTrEfT71CEYeszhoc2GhjPB2uwuU4cIz0pwU5qKZseK+P62aTjPymAAijUVkb5rLzp69HyDhmRWJynLBCZX4E7ZAfH1+nbIypkcDwNIFwkyDHMRoX+Fs8ugsiPnQF2jBciyl9WgFQx+dv/yGGzoHmhIxfnkeRa2MY+rkfDh5lJUXjyvTz8sx6GFbHcy1dXOURrqzPx4Hd7Lensw6GQVQ0SrPHWqFgWjVFNMWUZQ0Mq1SY1bEvYEqk4wdMyzNMaqLneTBqZtqsNzIYP7fLk6MXdpdmWJmlZA8aoJblZhtlWRZtN6XUnJnVa1WP0+PCDE9cmKVWkUafJwB+BHXXJPJRg4pFGVaSm/ZW/3m1KDMV2OVyhBqXZBiR8RS8fL2/kJekZDHA/eaGBRmaQRa/vtdPdeXYHocv5y3HMFUdN09w2wcoDp3DLMaw4J7r6bjLBlgea8jLB+6yLMWQ9gJ4V6+Tkg9fn1+IoVlv777Zcab8g1YFl2GImx2e6qOTA67PD9qCWIRh6OG6bb92VWi8KPw541cswBB3E+C6vqtD7UW9pxtL6BCXbvurgxSv+vf8S+iwwVXp/lOGBB6MaP4GHeIt9RDLf9BDbzczQxxt91eFg8qH8Rvve+X8DPGOzyu2PYB7eYPuNy9D3O4a7CPs9t8nmZ0hbVcNU2G7IdvTnM7OcIfzvcFeXzhX7OkhMzfDQMGUafhmzhkmUqrf85mbIVXS4ctnSf9qOjdD3OtyB9/Pcdze+3lzM4zdcScm0Ie2327gzAxDHM+Mcf7Y4rim15B2ZoaVGjQkfQAHp6rX2uLMDNHQsDEuVD7ra2pmZngZ7bzR9D3cNTPD0yi3AUTc13VgZobjDy7tRc/DDDMzLEY74NR9D+hZhu+xTC3tN6iZmeEBGI60NKKnm5rtLd6jf4+vJ+jx9Zp7/P6Dri/oP+ybmSGqgA1dw0AcWd9KMPfsSU4we+on7xIz4DGu797qZ8AR97wR0ydox97KVzFCPsrtGh3Leb89ndlXE2vh9VrTD8MnZ0xc1+876ltmRbhzPQvoSOrddva72GB2hrSo23ng5ju57+fBrV7GA5aT59+3wElw141OUF8A1dRvGdKpmL5ObvMz9HGjs+MtgyAPfT/I22pKB376jvnmYxiAqPQP2kPdURPADy5rdXjSQ+zwfAyhshmGIbmz/TQ4NebFd/z8dmHFv5yk6IYFGDpH1SHyGuiOroKG2OaUGCWq/5h2JoZgLHwyGH4Iv66ubDR6fAV+W3UhDZ7AzaJg24MPPmYOMAOYG6cUsqH1gUe+TpiHIRAJc9ShT6rJoeOO+Yl4E8UwNHxCEp0eRkhN0Ec+RPDQSLdpciyrH8VZGBpxscKhdI4fRI3UsdoQc8MC+Rn6DikzpBTMTD/OxRVNLI4BldWL4jwMUaKQGILGwDQGGyUb1904YWBsJf6Evun5jF7xV2D+wiO5KOlKtQnwaYS5f2/Sa2FIukGRUWEkY5CivwL67pFqQ/9WNcObjaGH0uowLDwX6miBygR+/vpqqVFKeGeIn2uNQl/xu+8Mg2eGQbhDDbLSMTUaOKIGu5KctR2SiMaS+EHJgaJsjo4hbviF99ZoDAp8H0YC8rleHRiGptHCeDXvVlNns6W3xuXcLWeqpBQNK3LnwdBkRPUElCdw8l2DT0Kltwfk3xl20+Jc/WFIHWE7/mrVcFEecJTuwQ+op8QnENwbGY3y/AOTnutJdXHaWmuuDmGs09HaLHqiJGnowAhj6Z/XNZKUmQzNH9K7WtNlTwUFqTKHnpQ8fSWRnExkXVcMPohAWPpkVxJzcTuKdz1vs6SqqiTbHq4ub6Mp83jc6bWlGcK0to1aRdWVKwRnt+OIQsnh54EMlmfoONmO6T+FmhOa7caHDV4DQwxIuAfFiSeaAtS5nyQ04DoYAoJss4s9jdWUay8uN9lEcYtWw9DAz8HS5JPGLFoZw1+AZfgeq4r88QInNjz2NK1BizVG23vGWYxxjlhVBJ4XGCfjqqIovcC4KEp0OmDdge5zPeL0gwnrs/JA9+jBM+ZtA3Fvx4G5Ifts5v0BW959q2wR4IbcqKiC6Jm+aluDp6z6eb5/Be14rfeFDEWPnbwXwE1rb9gx8hmwxRPgg09ZtaBDvQM2vebAkWQbbSZSeoXPGsffFFpETxAT8Eqvb9qvrePP97hnzK5TlEWBs4U6r4ljfjbR6aZ5eRLuslCsu/0lWUNIYT+57M1apK6nCuF8aiM6MaW6vJ/hdyGUYm30qQnnrlW8AmqfIdTEgfCj+B5VrvNbDX8DrQxMxdMPJqtTzBRbHlorFp9+KbpxmETH7dI4RsmogaiFhYWFhYWFhYWFhYWFxX8AjxdQvnkV5ci3VPZCENLN/nDLT990F2mjeLvPsVcfL5wisw917SXlGGwUBiNOPtTXbdpCPb+xEKTtuC61YV77oqFavDqvnKlpVtK7CcTRCyFR38L6pOx5970WXU+eon+RYfaGoYzn20/cxvEGGX7byB7D0DyulwznfxNiMLUOzbb4neGxlrJ+3j6s0vMGf6eRc4plWTn+IZaFWb7MiljGuzZ3VMvrxbmkKe11bPcy/vxGkkfmQ2pS8jQ9B21CTbuyUZoeH7X0KQEYbvMCisyfGWY7KXdvuUI7bFxX+HeGueSCMcbjx45MpviVfhex4kKwrFHaFQzvdFKCcSYURa8slBBc7fYMfUHCWAmtxfNO66bNXDvOmRtn9ovCKO4XyMo1JaDpe7TDywckwBV7Yih2QmkQLnkwPECRmql3keA3oPtYiPLOUApXlDvxvIuVcTwlmClgddhgkAd93nj0VrvkQ4jzpWAUVGGrXLa77JRxOaqZkJszewpbUoE0KWTGr3LI69Dd4MNzgnNh4s7wkZBRdArmnU6NcL3gxhBuKc6bxuVvtnORYcKxCMPwwl08Epg/e3S0DLnLMgpFoo/4B01BVkhsGzu6nTS+WAeGDEHEJsToNY93MyYmcynQoWlPl4BBkZSAEhYCY6Rc2MOWQsKJEjBsIYZrwsOKHjlEGYbwf0Yv7mSvjQUw3Dglc2XLMBbGoWrLHsbszhCdUOBxCIdEuHVYQXLFi0AtdCrbJ4YnZpw4xVe3gmRPRCKFzndkPdr0pBZs+5nhPUFfqJZSxT4wrD3E8CZEyt5EryeGIJS+7JBhIFqvNjyE/JUhcW5LrdrCszNu3uBjhW8EZW+wiEIIud9f996zg1abGeuA47o6D26RaSBBQ8J3huYKj10etvTI8dkQwwgeOt5E0pXvGGJ99mJsBuGNYa68+wtb7zr8xjC8KqZEuUcdYrChB8MdyKs+8KjF/27mNLgqTZkpYM+J6S0UTGeH94orsdt/Z1hD8WwHVzwz1CgNMTxCfaJ7qI/XO6eGoQnegLW0aePJAp/7sOnO8PqV4VkLfLNRyuB2n2spyLNpT8qaBxU4B06ZD4x0mMOn1Bg3SNhjAjW3Z4YnuGWFD4NROzQulxfBypYhGL8Yz2c+YjS8ZliplmFJdhVLeAQOes1QmhBRptk35k3EJ2K4pboEPWdU3apCfMtMDDFMm2eeIrR9JFpSwjPD2G0TDENBzmhX0ijdEi0yln7avtl9axk6B+0RQyhaF0lVcO/Rfl4zjAWKc+QeyraBP8WxNL2Fz8GEh2Gp1N1iXE1m5RmG0Ig8U++uAm3I8Xs7hOqZ4guIPGNpXFZXeaqpZZuHCnqo4Wkq/vHaa+TGEIP/o6VBpxyBL7t76qrv/eE3hhd4iLJRrX9/DM1Cq5raIfWO+C5ffR/VXJTXZjaNBhqGNoMfyNpQwuHBEM3aERNkmwA6vCqumfFlMgxzBgMQV799TzpMVsz9og/+QSY3klCOip/Mb0SzJ5hDkaX5UNRpfHAMDpViM0+rdq5z8BR0154xVlGDac++BWf8oqiUMq6PB6XavvJwS4AiNx9Ps6cDmCBVQoLAEdNHcoJ8rd7N7Cm/4qXeOxe18Ba9w3kcGM+z7JPSA3Na1EQPMB9ufzCvTwdK4d/AHFa+ByervpTTFkyZHSrw1kR9KoVO3Jrb3Ir36Qo8PWxiVMDnsJXbZMAyq9+fG9Ck3JFMYpec8BfuqZ3lWMtr3b/hDMO63aHoG+fxLwLMJmBOAiP9+N91nIgKGEIVa3YSt7CwsLCwsLCwsLCwsLCwsLCwsLCwsLCwsLCwsLCwWBv+D53G2YT70DOaAAAAAElFTkSuQmCC" width="300" height="300" alt="No Image Available">
-          {{ end }}
-          </a>
-        </div>
-
-        <div class="col-sm-10">
-          <h3>
-            <a href="{{ .Href }}">{{ .Title }}</a>
-            <small>Added: {{ .Datetime }}</small>
-          </h3>
-          <div class="indent">
-          Price: {{ .Price }}<br/>
-          {{ or .NearbyDesc .Neighborhood }}
-          </div>
-        </div>
-      </div>
-      {{ else }}
-      No results
-    {{ end }}
-    </div>
-
-  </body>
-</html>
-`
+	Furniture   = Category("fua")
+
+	Services         = Category("bbb")
+	SkilledTrade     = Category("sks")
+	ComputerServices = Category("cps")
+	Lessons          = Category("lss")
+	Community        = Category("ccc")
+	Activities       = Category("act")
+	Rideshare        = Category("rid")
+	Classes          = Category("cls")
+	Events           = Category("eve")
+	Music            = Category("msa")
+	RVs              = Category("rva")
+	Sporting         = Category("sga")
+	Tools            = Category("tla")
+
+	Housing    = Category("hhh")
+	Apartments = Category("apa")
+	Rooms      = Category("roo")
+	Sublets    = Category("sub")
+	Jobs       = Category("jjj")
+	Gigs       = Category("ggg")
 )
 
-var hashSeed = maphash.MakeSeed()
+var ErrNoMorePages = errors.New("no more pages")
 
+// ResultEntry is one search result.
+// The desc tags document the output schema (see OutputSchema).
 type ResultEntry struct {
-	Title        string
-	Href         string
-	Image        string
-	Datetime     string
-	Neighborhood string
-	NearbyLoc    string
-	NearbyDesc   string
-	Price        string
+	Title        string `desc:"listing title"`
+	Href         string `desc:"listing URL"`
+	Image        string `desc:"thumbnail URL, empty if no images"`
+	Datetime     string `desc:"posting date as shown in the page (yyyy-mm-dd hh:mm)"`
+	Neighborhood string `desc:"neighborhood, as entered by the poster"`
+	NearbyLoc    string `desc:"nearby area name, for results from a nearby region"`
+	NearbyDesc   string `desc:"nearby area short name, for results from a nearby region"`
+	Price        string `desc:"price as shown in the page (i.e. $1,250)"`
+	Region       string `desc:"craigslist region searched"`
+
+	PriceValue    int       `desc:"price in whole currency units, 0 if free or missing"`
+	Currency      string    `desc:"currency of the price (ISO 4217 code), from the price symbol or the region"`
+	Posted        time.Time `desc:"parsed Datetime (local time if the page has no timezone)"`
+	EntryCategory string    `desc:"category code from Href, or unknown"`
+	Meta          string    `desc:"other info in the result meta row (service area, event date)"`
+	Extras        string    `json:",omitempty" desc:"housing details shown next to the price (i.e. 2br 850ft2)"`
+	Distance      float64   `json:",omitempty" desc:"distance in miles from the postal code or location, for searches with a search distance"`
+
+	Archived   bool       `json:",omitempty" desc:"true for entries from an archived page (Wayback Machine)"`
+	ArchivedAt *time.Time `json:",omitempty" desc:"snapshot time of archived entries"`
+
+	Details *Listing `json:",omitempty" desc:"full listing, when details are requested"`
+
+	SellerListingCount int      `json:",omitempty" desc:"number of other listings by the same seller, when seller listings are requested"`
+	SellerListings     []string `json:",omitempty" desc:"titles of the other listings by the same seller"`
+
+	FirstSeenThisRun bool `json:"first_seen_this_run" desc:"true if the entry was not returned by previous runs of the same search (always false with -no-auto-state)"`
+}
+
+// HasPrice returns false for listings that don't have a price (services, community).
+func (entry ResultEntry) HasPrice() bool {
+	return !unpriced(entry.EntryCategory)
 }
 
 func normalize(s string) string {
@@ -150,27 +184,39 @@ func normalize(s string) string {
 	return s
 }
 
+// Hash identifies the listing by title, image, location and PriceValue (so a price change is a new entry).
+// Posted is not included on purpose: a re-post of the same listing has a new date,
+// and should be removed as a duplicate (by craigslist bundleDuplicates, or by DedupStore across runs).
 func (entry ResultEntry) Hash() uint64 {
-	var h maphash.Hash
-
-	h.SetSeed(hashSeed) // we need all hashes for the same string to be the same
-
-	h.WriteString(normalize(entry.Title))
-	h.WriteString(normalize(entry.Image))
-	h.WriteString(normalize(entry.NearbyLoc))
-	h.WriteString(normalize(entry.NearbyDesc))
-	h.WriteString(normalize(entry.Neighborhood))
-	h.WriteString(normalize(entry.Price))
+	// we need all hashes for the same string to be the same, also across runs
+	h := fnv.New64a()
+
+	io.WriteString(h, normalize(entry.Title))
+	io.WriteString(h, normalize(entry.Image))
+	io.WriteString(h, normalize(entry.NearbyLoc))
+	io.WriteString(h, normalize(entry.NearbyDesc))
+	io.WriteString(h, normalize(entry.Neighborhood))
+	io.WriteString(h, strconv.Itoa(entry.PriceValue))
 	return h.Sum64()
 }
 
 type SearchResults struct {
-	Title    string
-	Subtitle string
-	Url      string
-	Entries  []ResultEntry
-	Prev     string
-	Next     string
+	SchemaVersion int `json:"schema_version" desc:"version of this output schema"`
+
+	Title    string        `desc:"search query, or Results"`
+	Subtitle string        `desc:"description of the search options"`
+	Url      string        `desc:"search page URL"`
+	Entries  []ResultEntry `desc:"search results"`
+	Prev     string        `desc:"previous page link"`
+	Next     string        `desc:"next page link"`
+
+	TotalCount int `json:",omitempty" desc:"total number of results, as shown by craigslist (or before Limit and Skip if craigslist doesn't show it)"`
+
+	Breakdown *CategoryBreakdown `json:",omitempty" desc:"count of results per category"`
+	Archive   *ArchiveResults    `json:",omitempty" desc:"statistics from archived results"`
+
+	seen  map[uint64]bool // hashes of entries already returned, when removing duplicates
+	split bool            // the entries are sorted new (FirstSeenThisRun) first, see SeenState.MarkNew
 }
 
 type SearchOption func(params map[string]interface{})
@@ -199,9 +245,99 @@ func WithCategory(c Category) SearchOption {
 	}
 }
 
+// SellerType restricts the search to listings by owners ("owner") or dealers ("dealer").
+// Categories with owner/dealer variants (i.e. cars, cta -> cto/ctd) use the variant,
+// the others use the purveyor parameter. "all" (or "") doesn't restrict the search.
+func SellerType(seller string) SearchOption {
+	return func(params map[string]interface{}) {
+		params["by"] = seller
+	}
+}
+
+// By is the same as SellerType.
 func By(by string) SearchOption {
+	return SellerType(by)
+}
+
+// CryptoAccepted restricts the search to listings accepting cryptocurrency.
+func CryptoAccepted(crypto bool) SearchOption {
+	return func(params map[string]interface{}) {
+		if crypto {
+			params["crypto_currency"] = 1
+		}
+	}
+}
+
+// DeliveryAvailable restricts the search to listings with delivery available.
+func DeliveryAvailable(delivery bool) SearchOption {
+	return func(params map[string]interface{}) {
+		if delivery {
+			params["delivery_available"] = 1
+		}
+	}
+}
+
+// Bedrooms restricts housing searches to listings with min to max bedrooms (0 for no limit).
+func Bedrooms(min, max int) SearchOption {
+	return rangeParams("min_bedrooms", "max_bedrooms", min, max)
+}
+
+// Bathrooms restricts housing searches to listings with min to max bathrooms (0 for no limit).
+func Bathrooms(min, max int) SearchOption {
+	return rangeParams("min_bathrooms", "max_bathrooms", min, max)
+}
+
+// SquareFeet restricts housing searches to listings with min to max square feet (0 for no limit).
+func SquareFeet(min, max int) SearchOption {
+	return rangeParams("minSqft", "maxSqft", min, max)
+}
+
+func rangeParams(minParam, maxParam string, min, max int) SearchOption {
+	return func(params map[string]interface{}) {
+		if min > 0 {
+			params[minParam] = min
+		}
+
+		if max > 0 {
+			params[maxParam] = max
+		}
+	}
+}
+
+// CatsOK restricts housing searches to listings allowing cats.
+func CatsOK(ok bool) SearchOption {
 	return func(params map[string]interface{}) {
-		params["by"] = by
+		if ok {
+			params["pets_cat"] = 1
+		}
+	}
+}
+
+// DogsOK restricts housing searches to listings allowing dogs.
+func DogsOK(ok bool) SearchOption {
+	return func(params map[string]interface{}) {
+		if ok {
+			params["pets_dog"] = 1
+		}
+	}
+}
+
+// AvailableWithin restricts housing searches to listings available within 30 days (1),
+// or after 30 days (2). 0 doesn't restrict the search.
+func AvailableWithin(mode int) SearchOption {
+	return func(params map[string]interface{}) {
+		if mode > 0 {
+			params["availabilityMode"] = mode
+		}
+	}
+}
+
+// PaidOnly restricts gigs searches to paid gigs.
+func PaidOnly(paid bool) SearchOption {
+	return func(params map[string]interface{}) {
+		if paid {
+			params["is_paid"] = "yes"
+		}
 	}
 }
 
@@ -243,6 +379,19 @@ func Nearby(nearby bool) SearchOption {
 	}
 }
 
+// NearbyResults sets whether craigslist can add results from nearby areas (that have
+// NearbyLoc and NearbyDesc set) when a search has few results. Unlike Nearby,
+// NearbyResults(false) asks craigslist not to add them (see also FilterNearby).
+func NearbyResults(include bool) SearchOption {
+	return func(params map[string]interface{}) {
+		if include {
+			params["searchNearby"] = 1
+		} else {
+			params["searchNearby"] = 0
+		}
+	}
+}
+
 func Dedup(dedup bool) SearchOption {
 	return func(params map[string]interface{}) {
 		if dedup {
@@ -259,15 +408,29 @@ func TitleOnly(only bool) SearchOption {
 	}
 }
 
+// SearchDistance restricts the search to listings within d miles (or km, outside the US)
+// of the PostalCode or Location. Search returns ErrNoLocation if neither is set.
 func SearchDistance(d int) SearchOption {
 	return func(params map[string]interface{}) {
-		params["search_distance"] = d
+		if d > 0 {
+			params["search_distance"] = d
+		}
 	}
 }
 
 func PostalCode(p string) SearchOption {
 	return func(params map[string]interface{}) {
-		params["postal_code"] = p
+		if p != "" {
+			params["postal_code"] = p
+		}
+	}
+}
+
+// Location searches around a coordinate (see SearchDistance).
+func Location(lat, lon float64) SearchOption {
+	return func(params map[string]interface{}) {
+		params["lat"] = lat
+		params["lon"] = lon
 	}
 }
 
@@ -287,232 +450,362 @@ func MaxPrice(p int) SearchOption {
 	}
 }
 
+// StopWhen makes SearchAll stop following the next page links when stop returns true for a page.
+func StopWhen(stop func(page *SearchResults) bool) SearchOption {
+	return func(params map[string]interface{}) {
+		params["stopWhen"] = stop
+	}
+}
+
+// MaxEntries makes SearchAll stop following the next page links when it has at least n entries.
+func MaxEntries(n int) SearchOption {
+	return func(params map[string]interface{}) {
+		if n > 0 {
+			params["maxEntries"] = n
+		}
+	}
+}
+
+// OlderThan returns a StopWhen function for searches sorted by date, that stops
+// after the first page where all the entries were posted before watermark.
+// Pages with missing or invalid dates never stop the search.
+func OlderThan(watermark time.Time) func(page *SearchResults) bool {
+	return func(page *SearchResults) bool {
+		if watermark.IsZero() || len(page.Entries) == 0 {
+			return false
+		}
+
+		for _, e := range page.Entries {
+			if e.Posted.IsZero() || !e.Posted.Before(watermark) {
+				return false
+			}
+		}
+
+		return true
+	}
+}
+
+// Search runs the search and returns the first page of results.
+// With WithRegionCheck, it returns ErrUnknownRegion if the region is not a craigslist region.
 func (c *ClClient) Search(options ...SearchOption) (*SearchResults, error) {
+	return c.SearchContext(context.Background(), options...)
+}
+
+// SearchContext is like Search, but the request is cancelled when ctx is done.
+func (c *ClClient) SearchContext(ctx context.Context, options ...SearchOption) (*SearchResults, error) {
 	params := map[string]interface{}{}
 
 	for _, opt := range options {
 		opt(params)
 	}
 
-	reqs := []httpclient.RequestOption{}
+	if err := checkLocation(params); err != nil {
+		return nil, err
+	}
+
+	results := SearchResults{}
+
+	if q, ok := params["query"]; ok {
+		results.Title = q.(string)
+	} else {
+		results.Title = "Results"
+	}
+
+	if params["bundleDuplicates"] != nil {
+		results.seen = map[uint64]bool{}
+	}
+
+	region, uri := c.searchURL(params)
 
-	if r, ok := params["region"]; ok {
-		uri := fmt.Sprintf(searchuri, r.(string))
-		delete(params, "region")
-		reqs = append(reqs, httpclient.URLString(uri))
+	if c.checkRegions {
+		if err := c.CheckRegion(ctx, region); errors.Is(err, ErrUnknownRegion) {
+			return nil, err
+		} else if err != nil {
+			// the region list is not available, let craigslist check the region
+			c.debug("cannot check the region", "region", region, "error", err)
+		}
+	}
+
+	if _, err := c.fetch(ctx, &results, httpclient.URLString(uri), httpclient.Accept("*/*")); err != nil {
+		if results.Url == "" {
+			return nil, err
+		}
+
+		return &results, err
+	}
+
+	return &results, nil
+}
+
+// searchURL returns the region and the URL of the search page for the search parameters.
+// The parameters are encoded with url.Values, so that the query operators (| and ") are escaped.
+func (c *ClClient) searchURL(params map[string]interface{}) (Region, string) {
+	region := c.region
+	if r, ok := params["region"].(string); ok {
+		region = Region(r)
 	}
 
 	path := ""
+	if r, ok := params["subregion"].(string); ok {
+		path = r + "/"
+	}
+
 	cat := string(ForSale)
+	if c, ok := params["category"].(string); ok {
+		cat = c
+	}
 
-	if r, ok := params["subregion"]; ok {
-		path = r.(string) + "/"
-		delete(params, "subregion")
+	values := url.Values{}
+
+	for k, v := range params {
+		switch k {
+		case "region", "subregion", "category", "by", "stopWhen", "maxEntries": // not craigslist parameters
+		default:
+			values.Set(k, fmt.Sprint(v))
+		}
 	}
 
-	if c, ok := params["category"]; ok {
-		cat = c.(string)
-		delete(params, "category")
+	switch by, _ := params["by"].(string); by {
+	case "owner", "dealer":
+		// most for sale categories have owner and dealer variants, i.e. cta, cto, ctd
+		if c, ok := sellerCategory(Category(cat), by); ok {
+			cat = string(c)
+		} else {
+			values.Set("purveyor", by)
+		}
 	}
 
-	if by, ok := params["by"]; ok {
-		delete(params, "by")
+	uri := fmt.Sprintf(searchuri, region) + path + cat
+	if len(values) > 0 {
+		uri += "?" + values.Encode()
+	}
 
-		switch by {
-		case "owner":
-			if strings.HasSuffix(cat, "a") {
-				cat = cat[:len(cat)-1] + "o"
-			}
+	return region, uri
+}
 
-		case "dealer":
-			if strings.HasSuffix(cat, "d") {
-				cat = cat[:len(cat)-1] + "d"
-			}
+// add appends entry to the results, unless it's a duplicate of an entry already returned.
+func (results *SearchResults) add(entry ResultEntry) bool {
+	if results.seen != nil {
+		h := entry.Hash()
+		if results.seen[h] {
+			return false
 		}
+
+		results.seen[h] = true
+	}
+
+	results.Entries = append(results.Entries, entry)
+	return true
+}
+
+// Limit keeps the first n entries (all of them if n is 0).
+// TotalCount is set to the number of entries before Limit, if not known.
+func (results *SearchResults) Limit(n int) {
+	if results.TotalCount == 0 {
+		results.TotalCount = len(results.Entries)
 	}
 
-	path += cat
+	if n > 0 && len(results.Entries) > n {
+		results.Entries = results.Entries[:n]
+	}
+}
+
+// Skip removes the first n entries.
+// TotalCount is set to the number of entries before Skip, if not known.
+func (results *SearchResults) Skip(n int) {
+	if results.TotalCount == 0 {
+		results.TotalCount = len(results.Entries)
+	}
+
+	results.Entries = results.Entries[min(n, len(results.Entries)):]
+}
+
+// SearchNext fetches the page pointed by prev.Next.
+// Duplicates are removed across all pages fetched from the same search.
+func (c *ClClient) SearchNext(prev *SearchResults) (*SearchResults, error) {
+	return c.SearchNextContext(context.Background(), prev)
+}
+
+// SearchNextContext is like SearchNext, but the request is cancelled when ctx is done.
+func (c *ClClient) SearchNextContext(ctx context.Context, prev *SearchResults) (*SearchResults, error) {
+	if prev.Next == "" {
+		return nil, ErrNoMorePages
+	}
 
-	reqs = append(reqs, httpclient.Path(path))
-	reqs = append(reqs, httpclient.Params(params))
-	reqs = append(reqs, httpclient.Accept("*/*"))
-	res, err := httpclient.CheckStatus(c.h.SendRequest(reqs...))
+	uri, err := resolveURL(prev.Url, prev.Next)
 	if err != nil {
 		return nil, err
 	}
 
-	results := SearchResults{Url: res.Response.Request.URL.String()}
-
-	doc, err := goquery.NewDocumentFromReader(res.Body)
-	res.Body.Close()
+	results := SearchResults{
+		Title:    prev.Title,
+		Subtitle: prev.Subtitle,
+		seen:     prev.seen,
+	}
 
+	rows, err := c.fetch(ctx, &results, httpclient.URLString(uri), httpclient.Accept("*/*"))
 	if err != nil {
+		if results.Url == "" {
+			return nil, err
+		}
+
 		return &results, err
 	}
 
-	if q, ok := params["query"]; ok {
-		results.Title = q.(string)
-	} else {
-		results.Title = "Results"
+	if rows == 0 {
+		results.Next = ""
 	}
 
-	dedup := params["bundleDuplicates"] != nil
-	duplicates := map[uint64]bool{}
-
-	doc.Find(".rows li.result-row").Each(func(i int, s *goquery.Selection) {
-		title := s.Find(".result-heading a").First().Text()
-		href, _ := s.Find(".result-heading a").First().Attr("href")
-		iids, _ := s.Find("a.result-image").Attr("data-ids")
-		datetime, _ := s.Find(".result-info .result-date").First().Attr("datetime")
-		hood := s.Find(".result-meta .result-hood").First().Text()
-		nearby := s.Find(".result-meta .nearby").First()
-		loc, _ := nearby.Attr("title")
-		ldesc := nearby.Text()
-		price := s.Find(".result-meta .result-price").First().Text()
+	return &results, nil
+}
 
-		image := ""
-		ids := strings.Split(iids, ",")
-		if len(iids) > 0 && len(ids) > 0 {
-			parts := strings.Split(ids[0], ":")
-			image = fmt.Sprintf("https://images.craigslist.org/%v_300x300.jpg", parts[1])
-		}
+// SearchAll runs the search and follows the Next links for up to maxPages pages,
+// returning all the entries in one SearchResults.
+func (c *ClClient) SearchAll(maxPages int, options ...SearchOption) (*SearchResults, error) {
+	return c.SearchAllContext(context.Background(), maxPages, options...)
+}
 
-		entry := ResultEntry{
-			Title:        title,
-			Href:         href,
-			Image:        image,
-			Datetime:     datetime,
-			NearbyLoc:    loc,
-			NearbyDesc:   strings.TrimSpace(ldesc),
-			Neighborhood: strings.TrimSpace(hood),
-			Price:        price,
-		}
+// SearchAllContext is like SearchAll, but stops (returning the pages already fetched and ctx.Err())
+// when ctx is done.
+func (c *ClClient) SearchAllContext(ctx context.Context, maxPages int, options ...SearchOption) (*SearchResults, error) {
+	params := map[string]interface{}{}
+	for _, opt := range options {
+		opt(params)
+	}
 
-		if dedup {
-			h := entry.Hash()
-			if duplicates[h] == true {
-				//log.Println("hash", h, "duplicate", entry)
-				return
-			}
+	stop, _ := params["stopWhen"].(func(*SearchResults) bool)
+	if stop == nil {
+		stop = func(*SearchResults) bool { return false }
+	}
 
-			duplicates[h] = true
-			//log.Println("duplicates", duplicates)
-		}
+	maxEntries, _ := params["maxEntries"].(int)
 
-		results.Entries = append(results.Entries, entry)
+	results, err := c.SearchContext(ctx, options...)
+	if err != nil {
+		return results, err
+	}
 
-		//fmt.Println("<!-------------------------------------------------------------------------------->")
-		//fmt.Println(goquery.OuterHtml(s))
-		//fmt.Println("<!-------------------------------------------------------------------------------->")
-	})
+	page := results
 
-	results.Prev, _ = doc.Find(".buttons .prev").Attr("href")
-	results.Next, _ = doc.Find(".buttons .next").Attr("href")
+	for i := 1; i < maxPages && page.Next != "" && !stop(page); i++ {
+		if maxEntries > 0 && len(results.Entries) >= maxEntries {
+			break
+		}
 
-	return &results, nil
-}
+		page, err = c.SearchNextContext(ctx, page)
+		if err != nil {
+			return results, err
+		}
 
-func mapCategory(name string) Category {
-	var categories = map[string]Category{
-		"all":         ForSale,
-		"bikes":       Bikes,
-		"boats":       Boats,
-		"cars":        Cars,
-		"phones":      Cellphones,
-		"computers":   Computers,
-		"electronics": Electronics,
-		"free":        Free,
-		"music":       Music,
-		"rvs":         RVs,
-		"sports":      Sporting,
-		"tools":       Tools,
-	}
-
-	if c, ok := categories[name]; ok {
-		return c
+		results.Entries = append(results.Entries, page.Entries...)
+		results.Next = page.Next
 	}
 
-	return Category(name)
+	return results, nil
 }
 
-func applyFilter(f string, in []ResultEntry) (out []ResultEntry) {
-	if f == "" {
-		return in
+// resolveURL returns href as an absolute URL, using base for relative links.
+func resolveURL(base, href string) (string, error) {
+	u, err := url.Parse(href)
+	if err != nil {
+		return "", err
+	}
+
+	if u.IsAbs() {
+		return u.String(), nil
 	}
 
-	f = strings.ToLower(f)
+	b, err := url.Parse(base)
+	if err != nil {
+		return "", err
+	}
 
-	var any bool
-	var fpat []string
+	return b.ResolveReference(u).String(), nil
+}
 
-	if strings.Contains(f, "|") { // any
-		any = true
-		fpat = strings.Split(f, "|")
-	} else if strings.ContainsAny(f, "&, ") { // all
-		fpat = regexp.MustCompile("[& ,]").Split(f, -1)
-	} else { // one element
-		any = true
-		fpat = []string{f}
+// fetch sends the request and parses the returned page into results,
+// returning the number of result rows in the page (including duplicates).
+// It returns ErrBlocked if craigslist refuses the request or returns a block or captcha page.
+func (c *ClClient) fetch(ctx context.Context, results *SearchResults, reqs ...httpclient.RequestOption) (int, error) {
+	res, err := c.send(ctx, reqs...)
+	if err == nil && (res.StatusCode == http.StatusForbidden || res.StatusCode == http.StatusTooManyRequests) {
+		res.Body.Close()
+		return 0, ErrBlocked
 	}
 
-	neg := map[string]bool{}
-	mcount := 0
-	ncount := 0
+	res, err = httpclient.CheckStatus(res, err)
+	if err != nil {
+		return 0, err
+	}
 
-	for i, f := range fpat {
-		if len(f) == 0 { // shouldn't happen but...
-			log.Fatalf("empty filter in %q", fpat)
-		}
+	results.Url = res.Response.Request.URL.String()
+	region, _, _ := strings.Cut(res.Response.Request.URL.Hostname(), ".")
 
-		if strings.ContainsAny(f[:1], "-!^") {
-			fv := f[1:]
-			fpat[i] = fv
-			neg[fv] = true
-			ncount++
-		} else {
-			mcount++
-		}
+	doc, err := c.readDocument(res)
+	if err != nil {
+		return 0, err
 	}
 
-	out = make([]ResultEntry, 0, len(in))
-	re := regexp.MustCompile("(" + strings.Join(fpat, "|") + ")")
+	entries := len(results.Entries)
 
-	for _, r := range in {
-		res := re.FindAllString(strings.ToLower(r.Title), -1)
-		if len(res) == 0 {
-			if ncount == len(fpat) { // all negative
-				out = append(out, r)
-			}
+	rows := parseResults(doc, region, c.regionCountry(Region(region)), results)
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
 
-			continue
-		}
+	c.debug("parsed results", "url", results.Url, "rows", rows, "added", len(results.Entries)-entries,
+		"duplicates", rows-(len(results.Entries)-entries))
 
-		// check that all positive pattern matched
-		matches := 0
-		nmatches := 0
+	if rows == 0 && isBlocked(doc) {
+		return 0, ErrBlocked
+	}
 
-		for _, v := range res {
-			if neg[v] {
-				nmatches++
-			} else {
-				matches++
-			}
-		}
+	return rows, nil
+}
 
-		if nmatches > 0 {
-			continue
-		}
+// categoryNames maps the category names accepted by -cat to the craigslist codes
+var categoryNames = map[string]Category{
+	"all":         ForSale,
+	"bikes":       Bikes,
+	"boats":       Boats,
+	"cars":        Cars,
+	"phones":      Cellphones,
+	"computers":   Computers,
+	"electronics": Electronics,
+	"free":        Free,
+	"furniture":   Furniture,
+
+	"services":          Services,
+	"skilled-trade":     SkilledTrade,
+	"computer-services": ComputerServices,
+	"lessons":           Lessons,
+	"community":         Community,
+	"activities":        Activities,
+	"rideshare":         Rideshare,
+	"classes":           Classes,
+	"events":            Events,
+	"music":             Music,
+	"rvs":               RVs,
+	"sports":            Sporting,
+	"tools":             Tools,
+
+	"housing":    Housing,
+	"apartments": Apartments,
+	"rooms":      Rooms,
+	"sublets":    Sublets,
+	"jobs":       Jobs,
+	"gigs":       Gigs,
+}
 
-		if matches == mcount || // all positive matches
-			(any && matches > 0) { // any positive match
-			out = append(out, r)
-		}
+func mapCategory(name string) Category {
+	if c, ok := categoryNames[name]; ok {
+		return c
 	}
 
-	return
+	return Category(name)
 }
 
-func openbrowser(url string) {
+func openbrowser(url string) error {
 	var err error
 
 	switch runtime.GOOS {
@@ -525,54 +818,792 @@ func openbrowser(url string) {
 	default:
 		err = fmt.Errorf("unsupported platform")
 	}
-	if err != nil {
-		log.Fatal(err)
+
+	return err
+}
+
+// stringList is a flag that can be repeated
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ", ")
+}
+
+func (l *stringList) Set(s string) error {
+	*l = append(*l, s)
+	return nil
+}
+
+// intRange is a flag with a minimum, or a minimum and maximum: "2" or "2-3" (0 for no limit, i.e. "0-3")
+type intRange struct {
+	Min, Max int
+}
+
+func (r *intRange) String() string {
+	switch {
+	case r.Min == 0 && r.Max == 0:
+		return ""
+	case r.Max == 0:
+		return strconv.Itoa(r.Min)
+	default:
+		return fmt.Sprintf("%v-%v", r.Min, r.Max)
 	}
+}
+
+func (r *intRange) Set(s string) error {
+	min, max, isRange := strings.Cut(s, "-")
+
+	var err error
 
+	if r.Min, err = strconv.Atoi(strings.TrimSpace(min)); err != nil {
+		return fmt.Errorf("invalid range %q (should be min or min-max)", s)
+	}
+
+	r.Max = 0
+
+	if isRange {
+		if r.Max, err = strconv.Atoi(strings.TrimSpace(max)); err != nil || (r.Max > 0 && r.Max < r.Min) {
+			return fmt.Errorf("invalid range %q (should be min or min-max)", s)
+		}
+	}
+
+	return nil
 }
 
-func main() {
-	region := flag.String("region", "sfbay", "Region")
+// Main runs the searchcraigs command (see cmd/searchcraigs), with the options in os.Args.
+func Main() {
+	if len(os.Args) > 1 && os.Args[1] == "schema" {
+		fmt.Println(simplejson.MustDumpString(OutputSchema(), simplejson.Indent(" ")))
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "export-site" {
+		if err := exportSiteCommand(os.Args[2:]); err != nil {
+			log.Fatalf("ERROR: %v", err)
+		}
+		return
+	}
+
+	region := flag.String("region", "sfbay", "Region (or comma separated list of regions)")
 	subregion := flag.String("subregion", "", "Subregion")
 	cat := flag.String("cat", "sss", "Category")
 	by := flag.String("by", "all", "all, owner, dealer")
+	seller := flag.String("seller", "", "Only listings by owner or dealer (all, owner, dealer), same as -by")
+	crypto := flag.Bool("crypto", false, "Only listings accepting cryptocurrency")
+	delivery := flag.Bool("delivery", false, "Only listings with delivery available")
+	var bedrooms, sqft intRange
+	flag.Var(&bedrooms, "bedrooms", "Number of bedrooms, min or min-max (housing categories)")
+	flag.Var(&sqft, "sqft", "Square feet, min or min-max (housing categories)")
+	near := flag.String("near", "", "Search around this location (lat,lon, i.e. 37.77,-122.42), see -distance")
+	distance := flag.Int("distance", 0, "Search within this distance (miles, or km outside the US) from -near")
+	dedupFile := flag.String("dedupfile", "", "File storing the entries already returned, to remove them from the following runs")
+	dedupTTL := flag.Duration("dedupfile-ttl", 7*24*time.Hour, "Forget the entries in -dedupfile after this time")
+	dedupHref := flag.Bool("dedupfile-href", false, "With -dedupfile, only remove entries with the same link (not re-posts of the same listing)")
 	dedup := flag.Bool("dedup", true, "Bundle duplicates")
 	pictures := flag.Bool("pictures", true, "Has pictures")
 	sort := flag.String("sort", "", "Sort type (priceasc,pricedsc,date,rel)")
 	titleOnly := flag.Bool("titles", false, "Search in title only")
 	filter := flag.String("filter", "", "Title filter")
+	exclude := flag.String("exclude", "", "Comma separated list of words or phrases to exclude from the search")
 	today := flag.Bool("today", false, "Added today")
 	min := flag.Int("min", 0, "Min price")
 	max := flag.Int("max", 0, "Max price")
+	minLocal := flag.Int("min-local", 0, "Min price, applied to the returned results")
+	maxLocal := flag.Int("max-local", 0, "Max price, applied to the returned results")
+	localSort := flag.String("localsort", "", "Sort the returned results (price,priceasc,pricedsc,date)")
 	html := flag.Bool("html", true, "Return an HTML page")
+	format := flag.String("format", "", "Output format (html,json,rss,atom,csv,tsv). Overrides -html and -browse")
+	fieldList := flag.String("fields", "", "Columns for csv/tsv output (default title,price,datetime,neighborhood,nearby,href,image)")
 	browse := flag.Bool("browse", true, "Create HTML page and open browser")
+	serveMode := flag.Bool("serve", false, "Serve the HTML page from a local web server and open the browser")
+	serveIdle := flag.Duration("serve-idle", 5*time.Minute, "Stop the -serve web server after this idle time")
+	titleTemplate := flag.String("title", "", "Template for the results title, i.e. '{{ .Label }}: {{ .NewCount }} new, cheapest {{ .MinPrice }}'")
+	templatePath := flag.String("template", "", "Use this html/template file for the HTML page")
+	layout := flag.String("layout", "list", "Layout of the HTML page (list,grid)")
+	printFriendly := flag.Bool("print-friendly", false, "Create a compact HTML page, without images, for printing")
+	embedImages := flag.Bool("embed-images", false, "Embed the thumbnails in the HTML page, so that it still works when the image links expire")
+	maxEmbedSize := flag.Int("max-embed-size", defaultMaxEmbedSize, "Don't embed images larger than this (bytes), link them")
 	nearby := flag.Bool("nearby", false, "Search nearby")
+	noNearby := flag.Bool("no-nearby", false, "Remove the results from nearby areas that craigslist adds to searches with few results")
+	pages := flag.Int("pages", 1, "Number of result pages to fetch")
+	limit := flag.Int("limit", 0, "Return only the first N results (after the local filters and sort)")
+	skip := flag.Int("skip", 0, "Skip the first N results (after the local filters and sort)")
+	synonyms := flag.Bool("synonyms", false, "Expand query and filter terms with the built-in multilingual synonyms")
+	synonymsFile := flag.String("synonyms-file", "", "JSON file mapping terms to lists of synonyms (implies -synonyms)")
+	simulate := flag.Int("simulate", 0, "Use N simulated entries instead of searching craigslist")
+	seed := flag.Int64("seed", 1, "Random seed for -simulate")
+	breakdown := flag.Bool("category-breakdown", false, "Show how many results are in each category")
+	watchMode := flag.Bool("watch", false, "Repeat the search every -interval, reporting only new entries")
+	interval := flag.Duration("interval", 15*time.Minute, "Interval between searches in -watch mode")
+	notifyTarget := flag.String("notify", "", "Send the new entries to this webhook URL, or show desktop notifications (desktop)")
+	notifyTemplate := flag.String("notify-template", "", "Template for the -notify webhook payload (the default is a JSON object with title, count, entries and more)")
+	statePath := flag.String("state", ".searchcraigs-seen.json", "File storing the entries already seen in -watch mode")
+	noAutoState := flag.Bool("no-auto-state", false, "Don't split the results in new and seen in previous runs of the same search")
+	stateExpire := flag.Duration("state-expire", 30*24*time.Hour, "Forget seen entries after this time")
+	wayback := flag.Bool("wayback", false, "Add price statistics for archived results from the Wayback Machine")
+	waybackFrom := flag.String("wayback-from", "", "Start date for -wayback (yyyy-mm-dd, default one year ago)")
+	waybackTo := flag.String("wayback-to", "", "End date for -wayback (yyyy-mm-dd, default today)")
+	assumeCurrency := flag.String("assume-currency", "", "Compute the -wayback price stats as if all prices were in this currency (i.e. USD), even if they are not")
+	waybackMax := flag.Int("wayback-max", 10, "Max number of archived pages to fetch for each region")
+	details := flag.Int("details", 0, "Fetch the listing details for the first N results")
+	sellerListings := flag.Int("seller-listings", 0, "Fetch the other listings of up to N sellers (max 5) of the -details results")
+	maxPerSeller := flag.Int("max-per-seller", 0, "Return at most N of the -details results from the same seller")
+	detailsCachePath := flag.String("details-cache", "", "File storing the listing details and the -embed-images images already fetched, so that they are not fetched again")
+	dbPath := flag.String("db", "", "SQLite database storing the results and their price changes")
+	dbHistory := flag.String("db-history", "", "Print the price history of the listings in -db with a link containing this, and exit")
+	detailsCacheTTL := flag.Duration("details-cache-ttl", 24*time.Hour, "Fetch again the listing details and images older than this")
+	cpuProfile := flag.String("profile", "", "Write a CPU profile to this file")
+	memProfile := flag.String("profile-mem", "", "Write a memory profile to this file")
+	showStats := flag.Bool("stats", false, "Show how long each stage takes")
+	rateLimit := flag.Int("rate", 30, "Max requests per minute (0 for no limit)")
+	retries := flag.Int("retries", 3, "Retry requests failing with 429 (Too Many Requests) or 5xx up to this many times")
+	proxy := flag.String("proxy", "", "Send the requests through this proxy (http://host:port or socks5://host:port)")
+	userAgent := flag.String("useragent", "", "User-Agent for the requests (default a desktop browser)")
+	var headers stringList
+	flag.Var(&headers, "header", "Extra request header (name: value), can be repeated")
+	timeout := flag.Duration("timeout", 30*time.Second, "Timeout for each request")
+	backoff := flag.Duration("backoff", defaultBackoff, "Delay before the first retry (doubled at each retry)")
+	maxRequests := flag.Int("max-requests", defaultMaxRequests, "Refuse to run if the estimated number of requests is above this (see -yes)")
+	yes := flag.Bool("yes", false, "Run even if the estimated number of requests is above -max-requests")
+	configPath := flag.String("config", DefaultConfig(), "Config file with the saved searches")
+	savedName := flag.String("saved", "", "Run the saved search with this name (other options override the saved ones)")
+	listSaved := flag.Bool("list-saved", false, "List the saved searches")
+	listRegions := flag.Bool("list-regions", false, "List the craigslist regions (codes and names)")
+	listSubregions := flag.Bool("list-subregions", false, "List the subregions of -region")
 	//url := flag.Bool("url", false, "Display Craigslist URL")
 
 	debug := flag.Bool("debug", false, "Log HTTP requests")
+	verbose := flag.Bool("v", false, "Log the requests, the number of results in each stage and the timings to stderr")
+	dumpHTML := flag.String("dump-html", "", "Write the pages fetched to this file before parsing them (the following pages to file-2, file-3...)")
+
+	if len(os.Args) > 2 && os.Args[1] == "help" && os.Args[2] == "options" {
+		printOptions(os.Stdout, flag.CommandLine)
+		return
+	}
+
 	flag.Parse()
 
+	query := strings.Join(flag.Args(), " ")
+
+	if *listSaved || *savedName != "" {
+		searches, warnings, err := LoadSavedSearches(*configPath)
+		if err != nil {
+			log.Fatalf("ERROR: %v", err)
+		}
+
+		for _, w := range warnings {
+			log.Printf("WARNING: %v", w)
+		}
+
+		if *listSaved {
+			for _, name := range searches.Names() {
+				fmt.Println(name)
+			}
+			return
+		}
+
+		saved, err := searches.Get(*savedName)
+		if err != nil {
+			log.Fatalf("ERROR: %v", err)
+		}
+
+		if err := saved.Apply(flag.CommandLine); err != nil {
+			log.Fatalf("ERROR: %v", err)
+		}
+
+		if query == "" {
+			query = saved.Query
+		}
+	}
+
+	if err := validateOptions(flag.CommandLine); err != nil {
+		log.Fatal(err)
+	}
+
+	if *dbHistory != "" {
+		if err := printPriceHistory(*dbPath, *dbHistory); err != nil {
+			log.Fatalf("ERROR: %v", err)
+		}
+		return
+	}
+
 	if *debug {
 		httpclient.StartLogging(false, false, true)
 	}
 
-	query := strings.Join(flag.Args(), " ")
+	// debug output and warnings go to stderr, so that they don't mix with the results
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn}))
+	if *verbose || *debug {
+		logger = slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	}
+
+	stopProfiling := startProfiling(*cpuProfile, *memProfile)
+	defer stopProfiling()
+
+	var stats *Stats
+	if *showStats {
+		stats = NewStats()
+		defer stats.Print(os.Stderr)
+	}
+
+	var syn Synonyms
+
+	if *synonymsFile != "" {
+		var err error
+
+		if syn, err = LoadSynonyms(*synonymsFile, true); err != nil {
+			log.Fatalf("ERROR: %v", err)
+		}
+	} else if *synonyms {
+		syn = NewSynonyms(true, nil)
+	}
+
+	if _, err := parseFilter(*filter, syn); err != nil {
+		log.Fatalf("ERROR: -filter: %v", err)
+	}
+
+	fields, err := ParseFields(*fieldList)
+	if err != nil {
+		log.Fatalf("invalid -fields: %v", err)
+	}
+
+	if *format != "" {
+		*html = *format == "html"
+	}
+
+	tmpl, err := loadTemplate(*templatePath, *layout)
+	if err != nil {
+		log.Fatalf("ERROR: %v", err)
+	}
+
+	var titleTmpl *texttemplate.Template
+	if *titleTemplate != "" {
+		if titleTmpl, err = ParseTitleTemplate(*titleTemplate); err != nil {
+			log.Fatalf("invalid -title: %v", err)
+		}
+	}
+
+	var notifier Notifier
+	if *notifyTarget != "" {
+		if notifier, err = NewNotifier(*notifyTarget, *notifyTemplate); err != nil {
+			log.Fatalf("invalid -notify: %v", err)
+		}
+
+		if *simulate > 0 {
+			notifier = DryRun(notifier)
+		}
+	}
+
+	localSortBy := SortType(*localSort)
+	if localSortBy == "price" {
+		localSortBy = PriceAsc
+	}
 
-	cl := New(Region(*region))
-	res, err := cl.Search(
-		WithSubregion(SubRegion(*subregion)),
-		WithCategory(mapCategory(*cat)),
-		By(*by),
-		Dedup(*dedup),
-		Pictures(*pictures),
-		Sort(SortType(*sort)),
-		TitleOnly(*titleOnly || *filter != ""),
-		Today(*today),
-		Nearby(*nearby),
-		MinPrice(*min),
-		MaxPrice(*max),
-		Query(query))
+	var regions []Region
+	for _, r := range strings.Split(*region, ",") {
+		regions = append(regions, Region(strings.TrimSpace(r)))
+	}
+
+	// -embed-images only applies to the HTML page (-format overrides -html)
+	embed := *embedImages && !*printFriendly && (*serveMode || *format == "html" || (*format == "" && *html))
+
+	// the region list used to check the regions, cached in the data directory
+	regionsPath := ""
+	if dir, err := dataDir(); err == nil && os.MkdirAll(dir, 0o755) == nil {
+		regionsPath = filepath.Join(dir, "regions.json")
+	}
+
+	if *simulate == 0 {
+		est := RequestEstimate{
+			Regions:    len(regions),
+			Categories: 1,
+			Pages:      *pages,
+			Details:    *details,
+			Sellers:    *sellerListings,
+		}
+
+		if _, err := readRegionCache(regionsPath, 0); err != nil {
+			est.RegionList = true // not cached, or no data directory
+		}
+
+		if est.Sellers > maxSellers {
+			est.Sellers = maxSellers
+		}
+
+		if *wayback {
+			est.Wayback = *waybackMax
+		}
+
+		if embed {
+			// one thumbnail per result, at most
+			est.Images = est.Searches() * resultsPerPage
+			if *limit > 0 && *limit < est.Images {
+				est.Images = *limit
+			}
+		}
+
+		if est.Total() > *maxRequests && !*yes {
+			est.Print(os.Stderr)
+			log.Fatalf("this search could send up to %v requests (more than -max-requests %v), use -yes to run it anyway", est.Total(), *maxRequests)
+		}
+	}
+
+	clientOptions := []ClientOption{
+		WithRetries(*retries),
+		WithBackoff(*backoff),
+		WithTimeout(*timeout),
+		WithProxy(*proxy),
+		WithUserAgent(*userAgent),
+		WithRateLimit(*rateLimit),
+		WithLogger(logger),
+		WithDumpHTML(*dumpHTML),
+	}
+
+	if regionsPath != "" {
+		clientOptions = append(clientOptions, WithRegionCache(regionsPath, 0))
+	}
+
+	var detailsCache *DetailsCache
+	if *detailsCachePath != "" {
+		var err error
+		if detailsCache, err = LoadDetailsCache(*detailsCachePath, *detailsCacheTTL); err != nil {
+			log.Fatalf("ERROR: %v", err)
+		}
 
+		clientOptions = append(clientOptions, WithDetailsCache(detailsCache))
+	}
+
+	for _, h := range headers {
+		name, value, ok := strings.Cut(h, ":")
+		if !ok {
+			log.Fatalf("invalid -header %q (should be name: value)", h)
+		}
+
+		clientOptions = append(clientOptions, WithHeader(strings.TrimSpace(name), strings.TrimSpace(value)))
+	}
+
+	cl, err := New(regions[0], clientOptions...)
+	if err != nil {
+		log.Fatalf("ERROR: %v", err)
+	}
+
+	// ctrl-C stops all the requests in flight
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if *listRegions {
+		list, err := cl.ListRegionsContext(ctx)
+		if err != nil {
+			log.Fatalf("ERROR: %v", err)
+		}
+
+		for _, r := range list {
+			fmt.Printf("%v\t%v\n", r.Code, r.Name)
+		}
+		return
+	}
+
+	if *listSubregions {
+		for _, r := range regions {
+			list, err := cl.ListSubRegionsContext(ctx, r)
+			if err != nil {
+				log.Fatalf("ERROR: %v: %v", r, err)
+			}
+
+			for _, s := range list {
+				fmt.Printf("%v\t%v\t%v\n", r, s.Code, s.Name)
+			}
+		}
+		return
+	}
+
+	if *simulate == 0 {
+		for _, r := range regions {
+			if err := cl.CheckRegion(ctx, r); errors.Is(err, ErrUnknownRegion) {
+				log.Fatalf("ERROR: %v", err)
+			} else if err != nil {
+				// don't stop the search if the region list is not available
+				log.Printf("WARNING: cannot check the regions: %v", err)
+				break
+			}
+		}
+	}
+
+	if *seller != "" {
+		*by = *seller
+	}
+
+	// the search parameters, to identify the search in the watch state
+	searchKey := strings.Join([]string{*region, *subregion, *cat, *by, query,
+		strconv.Itoa(*min), strconv.Itoa(*max), strconv.FormatBool(*pictures),
+		strconv.FormatBool(*titleOnly || *filter != ""), strconv.FormatBool(*today), strconv.FormatBool(*nearby)}, "|")
+
+	if *exclude != "" { // only when set, so that the existing keys don't change
+		searchKey += "|exclude=" + *exclude
+	}
+
+	if *crypto || *delivery { // only when set, so that the existing keys don't change
+		searchKey += fmt.Sprintf("|crypto=%v|delivery=%v", *crypto, *delivery)
+	}
+
+	var nearOptions []SearchOption
+	if *near != "" {
+		lat, lon, err := ParseLatLon(*near)
+		if err != nil {
+			log.Fatalf("ERROR: %v", err)
+		}
+
+		nearOptions = append(nearOptions, Location(lat, lon), SearchDistance(*distance))
+		searchKey += fmt.Sprintf("|near=%v,%v|distance=%v", lat, lon, *distance)
+	}
+
+	if bedrooms.String() != "" || sqft.String() != "" {
+		searchKey += fmt.Sprintf("|bedrooms=%v|sqft=%v", bedrooms.String(), sqft.String())
+	}
+
+	var state *SeenState
+
+	var dedupStore *DedupStore
+	if *dedupFile != "" {
+		if dedupStore, err = LoadDedupStore(*dedupFile, *dedupTTL); err != nil {
+			log.Fatalf("ERROR: %v", err)
+		}
+
+		dedupStore.ByHref = *dedupHref
+	}
+
+	var priceDB *PriceDB
+	if *dbPath != "" {
+		if priceDB, err = OpenPriceDB(*dbPath); err != nil {
+			log.Fatalf("ERROR: %v", err)
+		}
+
+		defer priceDB.Close()
+	}
+
+	search := func(ctx context.Context) (*SearchResults, error) {
+		var res *SearchResults
+
+		start := time.Now()
+
+		if *simulate > 0 {
+			res = Simulate(*simulate, *seed, query, *dedup)
+			res.Subtitle = "Simulated"
+		} else {
+			var err error
+
+			options := []SearchOption{
+				WithSubregion(SubRegion(*subregion)),
+				WithCategory(mapCategory(*cat)),
+				SellerType(*by),
+				CryptoAccepted(*crypto),
+				DeliveryAvailable(*delivery),
+				Bedrooms(bedrooms.Min, bedrooms.Max),
+				SquareFeet(sqft.Min, sqft.Max),
+				Dedup(*dedup),
+				Pictures(*pictures),
+				Sort(SortType(*sort)),
+				TitleOnly(*titleOnly || *filter != ""),
+				Today(*today),
+				Nearby(*nearby),
+				MinPrice(*min),
+				MaxPrice(*max),
+			}
+
+			options = append(options, nearOptions...)
+
+			if *limit > 0 && *filter == "" && *minLocal == 0 && *maxLocal == 0 && !*noNearby && dedupStore == nil && localSortBy == "" {
+				// without local filters or sorting, the pages after the first skip+limit entries are not needed
+				options = append(options, MaxEntries(*skip+*limit))
+			}
+
+			if state != nil && SortType(*sort) == Date {
+				// no need to fetch pages older than the last search
+				options = append(options, StopWhen(OlderThan(state.Watermark(searchKey))))
+			}
+
+			query := query
+			if *exclude != "" {
+				query = NewQuery(query).Exclude(strings.Split(*exclude, ",")...).String()
+			}
+
+			if syn != nil {
+				q, err := ExpandQuery(query, syn)
+				if err != nil {
+					return nil, err
+				}
+
+				options = append(options, Query(q))
+			} else {
+				options = append(options, Query(query))
+			}
+
+			if len(regions) > 1 {
+				res, err = cl.MultiSearchAllContext(ctx, *pages, regions, options...)
+			} else {
+				res, err = cl.SearchAllContext(ctx, *pages, options...)
+			}
+
+			if err != nil {
+				if res == nil || len(res.Entries) == 0 {
+					return res, err
+				}
+
+				log.Printf("WARNING %v: %v (showing partial results)", res.Url, err)
+			}
+
+			if state != nil {
+				state.UpdateWatermark(searchKey, res.Entries)
+			}
+		}
+
+		stats.Since("search", start)
+		logger.Debug("search", "entries", len(res.Entries), "time", time.Since(start).Round(time.Millisecond))
+
+		if (syn != nil || *exclude != "") && query != "" {
+			res.Title = query // not the expanded query
+		}
+
+		if *sort != "" {
+			res.Subtitle = strings.TrimPrefix(fmt.Sprintf("%v, Sort: %v", res.Subtitle, *sort), ", ")
+		}
+
+		start = time.Now()
+		searched := len(res.Entries)
+
+		if *filter != "" {
+			res.Subtitle = strings.TrimPrefix(fmt.Sprintf("%v, Filter Title: %v", res.Subtitle, *filter), ", ")
+			filtered, err := applyFilter(*filter, syn, res.Entries)
+			if err != nil {
+				return nil, err
+			}
+
+			res.Entries = filtered
+		}
+
+		if *minLocal > 0 || *maxLocal > 0 {
+			res.Subtitle = strings.TrimPrefix(fmt.Sprintf("%v, Price: %v-%v", res.Subtitle, *minLocal, *maxLocal), ", ")
+			res.Entries = FilterPrice(res.Entries, *minLocal, *maxLocal)
+		}
+
+		if *noNearby {
+			total := len(res.Entries)
+			res.Entries = FilterNearby(res.Entries)
+			res.Subtitle = strings.TrimPrefix(fmt.Sprintf("%v, Nearby Removed: %v", res.Subtitle, total-len(res.Entries)), ", ")
+		}
+
+		if dedupStore != nil {
+			total := len(res.Entries)
+			res.Entries = dedupStore.Unseen(res.Entries)
+			res.Subtitle = strings.TrimPrefix(fmt.Sprintf("%v, Already Seen: %v", res.Subtitle, total-len(res.Entries)), ", ")
+		}
+
+		stats.Since("filter", start)
+		logger.Debug("filter", "before", searched, "after", len(res.Entries), "time", time.Since(start).Round(time.Millisecond))
+
+		if localSortBy != "" {
+			start = time.Now()
+
+			res.Subtitle = strings.TrimPrefix(fmt.Sprintf("%v, Local Sort: %v", res.Subtitle, localSortBy), ", ")
+			SortEntries(res.Entries, localSortBy)
+
+			stats.Since("sort", start)
+		}
+
+		if *skip > 0 {
+			res.Skip(*skip)
+		}
+
+		if *limit > 0 {
+			res.Limit(*limit)
+		}
+
+		if *details > 0 && *simulate == 0 {
+			start = time.Now()
+
+			if err := cl.GetDetailsContext(ctx, res.Entries, *details); err != nil {
+				log.Printf("WARNING: %v", err)
+			}
+
+			if *sellerListings > 0 {
+				if err := cl.GetSellerListings(ctx, res.Entries, *sellerListings); err != nil {
+					log.Printf("WARNING: %v", err)
+				}
+			}
+
+			if detailsCache != nil {
+				if err := detailsCache.Save(); err != nil {
+					log.Printf("WARNING: %v", err)
+				}
+			}
+
+			stats.Since("details", start)
+			logger.Debug("details", "time", time.Since(start).Round(time.Millisecond))
+
+			if *maxPerSeller > 0 {
+				total := len(res.Entries)
+				res.Entries = LimitPerSeller(res.Entries, *maxPerSeller)
+				res.Subtitle = strings.TrimPrefix(fmt.Sprintf("%v, Max Per Seller: %v (%v removed)", res.Subtitle, *maxPerSeller, total-len(res.Entries)), ", ")
+			}
+		}
+
+		if dedupStore != nil {
+			// only the entries returned, not the ones removed by -skip, -limit or -max-per-seller
+			dedupStore.Mark(res.Entries)
+
+			if err := dedupStore.Save(); err != nil {
+				log.Printf("WARNING: %v", err)
+			}
+		}
+
+		if priceDB != nil {
+			if _, _, err := priceDB.Record(res.Entries, time.Now()); err != nil {
+				log.Printf("WARNING: %v", err)
+			}
+		}
+
+		return res, nil
+	}
+
+	// the notification title, with -title or DefaultTitleTemplate
+	notifyTitle := func(res *SearchResults) string {
+		title, err := RenderTitle(titleTmpl, NewTitleData(*savedName, query, res), NotificationTitleLength)
+		if err != nil {
+			log.Printf("WARNING: -title: %v", err)
+			return res.Title
+		}
+
+		return title
+	}
+
+	output := func(res *SearchResults) {
+		defer stats.Since("output", time.Now())
+
+		res.SchemaVersion = SchemaVersion
+
+		if titleTmpl != nil {
+			title, err := RenderTitle(titleTmpl, NewTitleData(*savedName, query, res), PageTitleLength)
+			if err != nil {
+				log.Printf("WARNING: -title: %v", err)
+			} else {
+				res.Title = title
+			}
+		}
+
+		if embed {
+			if err := cl.EmbedImagesContext(ctx, res.Entries, *maxEmbedSize); err != nil {
+				log.Printf("WARNING: %v (linking them instead)", err)
+			}
+
+			if detailsCache != nil {
+				if err := detailsCache.Save(); err != nil {
+					log.Printf("WARNING: %v", err)
+				}
+			}
+		}
+
+		if *serveMode {
+			if err := serve(res, tmpl, *printFriendly, *serveIdle, searchKey, *stateExpire); err != nil {
+				log.Printf("ERROR: %v", err)
+			}
+			return
+		}
+
+		if *breakdown {
+			res.Breakdown = BreakdownByCategory(res.Entries)
+
+			if *html {
+				res.Breakdown.Print(os.Stderr)
+			}
+		}
+
+		if res.Archive != nil && *html {
+			res.Archive.Print(os.Stderr)
+		}
+
+		switch *format {
+		case "html":
+			if err := writeHTML(os.Stdout, tmpl, res, *printFriendly); err != nil {
+				log.Printf("ERROR: %v", err)
+			}
+			return
+
+		case "json":
+			fmt.Println(simplejson.MustDumpString(res, simplejson.Indent(" ")))
+			return
+
+		case "rss":
+			if err := WriteRSS(os.Stdout, res); err != nil {
+				log.Printf("ERROR: %v", err)
+			}
+			return
+
+		case "atom":
+			if err := WriteAtom(os.Stdout, res); err != nil {
+				log.Printf("ERROR: %v", err)
+			}
+			return
+
+		case "csv":
+			if err := WriteCSV(os.Stdout, res.Entries, fields); err != nil {
+				log.Printf("ERROR: %v", err)
+			}
+			return
+
+		case "tsv":
+			if err := WriteTSV(os.Stdout, res.Entries, fields); err != nil {
+				log.Printf("ERROR: %v", err)
+			}
+			return
+		}
+
+		if *html && *browse {
+			var b bytes.Buffer
+			if err := writeHTML(&b, tmpl, res, *printFriendly); err != nil {
+				log.Fatalf("ERROR: %v", err)
+			}
+
+			// note that by default data: URLs don't "open" in MacOS
+			// and you need to add a mapping scheme -> app
+			// (see for example SwiftDefaultApps)
+			durl := fmt.Sprintf("data:text/html;base64,%v", base64.StdEncoding.EncodeToString(b.Bytes()))
+			if err := openbrowser(durl); err != nil {
+				log.Fatal(err)
+			}
+		} else if *html {
+			if err := writeHTML(os.Stdout, tmpl, res, *printFriendly); err != nil {
+				log.Printf("ERROR: %v", err)
+			}
+		} else {
+			fmt.Println(simplejson.MustDumpString(res, simplejson.Indent(" ")))
+		}
+	}
+
+	if *watchMode {
+		state, err = LoadSeenState(*statePath, *stateExpire)
+		if err != nil {
+			log.Fatalf("ERROR: %v", err)
+		}
+
+		watchOutput := output
+		if notifier != nil {
+			watchOutput = func(res *SearchResults) {
+				entries := append([]ResultEntry(nil), res.Entries...) // before -embed-images changes the images
+				title := notifyTitle(res)
+
+				output(res)
+				notifyNew(ctx, notifier, title, entries)
+			}
+		}
+
+		watch(ctx, *interval, state, search, watchOutput)
+		return
+	}
+
+	res, err := search(ctx)
+	if errors.Is(err, ErrBlocked) {
+		log.Fatalf("ERROR: craigslist is blocking the requests from this IP (try again later, or with fewer -pages): %v", err)
+	}
 	if err != nil {
 		if res != nil {
 			log.Fatalf("ERROR %v: %v", res.Url, err)
@@ -581,29 +1612,76 @@ func main() {
 		log.Fatalf("ERROR: %v", err)
 	}
 
-	if *sort != "" {
-		res.Subtitle = fmt.Sprintf("Sort: %v", *sort)
+	// without the automatic state all the entries are new
+	newEntries := res.Entries
+
+	if !*noAutoState && *simulate == 0 {
+		if err := splitNew(searchKey, res, *stateExpire); err != nil {
+			log.Printf("WARNING: %v", err)
+		} else {
+			newEntries = nil
+			for _, e := range res.Entries {
+				if e.FirstSeenThisRun {
+					newEntries = append(newEntries, e)
+				}
+			}
+		}
 	}
 
-	if *filter != "" {
-		res.Subtitle = strings.TrimPrefix(fmt.Sprintf("%v, Filter Title: %v", res.Subtitle, *filter), ", ")
-		res.Entries = applyFilter(*filter, res.Entries)
+	if notifier != nil && len(newEntries) > 0 {
+		notifyNew(ctx, notifier, notifyTitle(res), append([]ResultEntry(nil), newEntries...)) // before -embed-images changes the images
 	}
 
-	if *html && *browse {
-		var b bytes.Buffer
-		t := template.Must(template.New("webpage").Parse(pageTemplate))
-		t.Execute(&b, res)
+	if *wayback {
+		start := time.Now()
 
-		// note that by default data: URLs don't "open" in MacOS
-		// and you need to add a mapping scheme -> app
-		// (see for example SwiftDefaultApps)
-		durl := fmt.Sprintf("data:text/html;base64,%v", base64.StdEncoding.EncodeToString(b.Bytes()))
-		openbrowser(durl)
-	} else if *html {
-		t := template.Must(template.New("webpage").Parse(pageTemplate))
-		t.Execute(os.Stdout, res)
-	} else {
-		fmt.Println(simplejson.MustDumpString(res, simplejson.Indent(" ")))
+		to := time.Now()
+		from := to.AddDate(-1, 0, 0)
+
+		if *waybackFrom != "" {
+			if from, err = time.Parse("2006-01-02", *waybackFrom); err != nil {
+				log.Fatalf("invalid -wayback-from: %v", err)
+			}
+		}
+
+		if *waybackTo != "" {
+			if to, err = time.Parse("2006-01-02", *waybackTo); err != nil {
+				log.Fatalf("invalid -wayback-to: %v", err)
+			}
+		}
+
+		wb := cl.Wayback(3 * time.Second) // be nice to archive.org
+
+		for _, r := range regions {
+			ar, err := wb.SearchContext(ctx, r, mapCategory(*cat), query, from, to, *waybackMax)
+			if ctx.Err() != nil {
+				break
+			}
+
+			if err != nil {
+				log.Printf("WARNING wayback %v: %v", r, err)
+				continue
+			}
+
+			if res.Archive == nil {
+				res.Archive = ar
+			} else {
+				res.Archive.Snapshots += ar.Snapshots
+				res.Archive.Skipped += ar.Skipped
+				res.Archive.Entries = append(res.Archive.Entries, ar.Entries...)
+			}
+		}
+
+		if res.Archive != nil {
+			res.Archive.Stats = ComputePriceStats(res.Archive.Entries, strings.ToUpper(*assumeCurrency))
+		}
+
+		stats.Since("wayback", start)
 	}
+
+	if len(res.Entries) == 0 {
+		log.Println("no results")
+	}
+
+	output(res)
 }
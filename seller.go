@@ -0,0 +1,90 @@
+package searchcraigs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/gobs/httpclient"
+)
+
+// hard limit on the number of sellers whose listings are fetched in one run, to stay polite
+const maxSellers = 5
+
+// GetSellerListings fetches the "more ads by this user" page for up to sellers sellers
+// (capped at maxSellers) of the entries with Details, one at a time, and sets SellerListingCount and SellerListings.
+// Entries without a seller link (most by-owner postings) are left alone and cost no requests.
+// The seller pages that can't be fetched are skipped, and reported in the (joined) error.
+func (c *ClClient) GetSellerListings(ctx context.Context, entries []ResultEntry, sellers int) error {
+	sellers = min(sellers, maxSellers)
+
+	found := map[string][]ResultEntry{} // seller page -> listings
+	var errs []error
+
+	for i, e := range entries {
+		if e.Details == nil || e.Details.SellerHref == "" {
+			continue
+		}
+
+		seller := e.Details.SellerHref
+
+		listings, ok := found[seller]
+		if !ok {
+			if len(found) >= sellers {
+				continue
+			}
+
+			var results SearchResults
+			if _, err := c.fetch(ctx, &results, httpclient.URLString(seller), httpclient.Accept("*/*")); err != nil {
+				if ctx.Err() != nil {
+					return err
+				}
+
+				errs = append(errs, fmt.Errorf("seller listings %v: %w", seller, err))
+			}
+
+			listings = results.Entries
+			found[seller] = listings
+		}
+
+		for _, l := range listings {
+			if l.Href != e.Href {
+				entries[i].SellerListings = append(entries[i].SellerListings, l.Title)
+			}
+		}
+
+		entries[i].SellerListingCount = len(entries[i].SellerListings)
+	}
+
+	if len(found) > 0 {
+		c.debug("seller listings", "sellers", len(found), "failed", len(errs))
+	}
+
+	return errors.Join(errs...)
+}
+
+// LimitPerSeller returns the entries, keeping only the first n (if n > 0) of each seller,
+// identified by the "more ads by this user" link of the Details.
+// The entries without Details or without a seller link are all kept.
+func LimitPerSeller(entries []ResultEntry, n int) []ResultEntry {
+	if n <= 0 {
+		return entries
+	}
+
+	count := map[string]int{}
+	out := make([]ResultEntry, 0, len(entries))
+
+	for _, e := range entries {
+		if e.Details != nil && e.Details.SellerHref != "" {
+			if count[e.Details.SellerHref] >= n {
+				continue
+			}
+
+			count[e.Details.SellerHref]++
+		}
+
+		out = append(out, e)
+	}
+
+	return out
+}
@@ -0,0 +1,113 @@
+package searchcraigs
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestGetSellerListings(t *testing.T) {
+	s := newTestServer(t, responses(searchPage("", "bike 1", "trailer", "helmet")))
+
+	dealer := "https://sfbay.craigslist.org/search/sss?purveyor=dealer&seller=42"
+	entries := []ResultEntry{
+		{Title: "bike 1", Href: "https://sfbay.craigslist.org/sfc/bik/d/bike-1.html", Details: &Listing{SellerHref: dealer}},
+		{Title: "by owner", Href: "https://sfbay.craigslist.org/sfc/bik/d/by-owner.html", Details: &Listing{}},
+		{Title: "no details", Href: "https://sfbay.craigslist.org/sfc/bik/d/no-details.html"},
+		{Title: "bike 2", Href: "https://sfbay.craigslist.org/sfc/bik/d/bike-2.html", Details: &Listing{SellerHref: dealer}},
+	}
+
+	if err := s.client(t).GetSellerListings(context.Background(), entries, 5); err != nil {
+		t.Fatal(err)
+	}
+
+	// one request for the dealer, none for the others
+	if n := len(s.received()); n != 1 {
+		t.Errorf("%v requests, want 1", n)
+	}
+
+	want := []struct {
+		count    int
+		listings []string
+	}{
+		{2, []string{"trailer", "helmet"}}, // not the entry itself
+		{0, nil},
+		{0, nil},
+		{3, []string{"bike 1", "trailer", "helmet"}},
+	}
+
+	for i, e := range entries {
+		if e.SellerListingCount != want[i].count || !slices.Equal(e.SellerListings, want[i].listings) {
+			t.Errorf("%v: %v %q, want %v %q", e.Title, e.SellerListingCount, e.SellerListings, want[i].count, want[i].listings)
+		}
+	}
+}
+
+// no seller links, no requests
+func TestGetSellerListingsNoLinks(t *testing.T) {
+	s := newTestServer(t, responses(searchPage("", "bike")))
+
+	entries := []ResultEntry{{Title: "by owner", Details: &Listing{}}, {Title: "no details"}}
+
+	if err := s.client(t).GetSellerListings(context.Background(), entries, 5); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := len(s.received()); n != 0 {
+		t.Errorf("%v requests, want none", n)
+	}
+}
+
+func TestLimitPerSeller(t *testing.T) {
+	seller := func(title, href string) ResultEntry {
+		return ResultEntry{Title: title, Details: &Listing{SellerHref: href}}
+	}
+
+	entries := []ResultEntry{
+		seller("a1", "a"), seller("b1", "b"), seller("a2", "a"), {Title: "owner1", Details: &Listing{}},
+		seller("a3", "a"), {Title: "no details"}, seller("b2", "b"), {Title: "owner2", Details: &Listing{}},
+	}
+
+	tests := []struct {
+		n    int
+		want []string
+	}{
+		{0, []string{"a1", "b1", "a2", "owner1", "a3", "no details", "b2", "owner2"}},
+		{1, []string{"a1", "b1", "owner1", "no details", "owner2"}},
+		{2, []string{"a1", "b1", "a2", "owner1", "no details", "b2", "owner2"}},
+	}
+
+	for _, tt := range tests {
+		if got := entryTitles(LimitPerSeller(entries, tt.n)); !slices.Equal(got, tt.want) {
+			t.Errorf("%v: got %q, want %q", tt.n, got, tt.want)
+		}
+	}
+}
+
+// a seller page that can't be fetched is skipped and reported, the other sellers are still fetched
+func TestGetSellerListingsError(t *testing.T) {
+	s := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.URL.RawQuery, "seller=1") {
+			http.Error(w, "error", http.StatusInternalServerError)
+			return
+		}
+
+		responses(searchPage("", "trailer"))(w, r)
+	}))
+
+	entries := []ResultEntry{
+		{Title: "bike 1", Details: &Listing{SellerHref: "https://sfbay.craigslist.org/search/sss?seller=1"}},
+		{Title: "bike 2", Details: &Listing{SellerHref: "https://sfbay.craigslist.org/search/sss?seller=2"}},
+	}
+
+	err := s.client(t).GetSellerListings(context.Background(), entries, 5)
+	if err == nil || !strings.Contains(err.Error(), "seller=1") {
+		t.Errorf("got %v, want an error for seller 1", err)
+	}
+
+	if entries[0].SellerListingCount != 0 || entries[1].SellerListingCount != 1 {
+		t.Errorf("seller listings %v and %v, want 0 and 1", entries[0].SellerListingCount, entries[1].SellerListingCount)
+	}
+}
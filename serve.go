@@ -0,0 +1,290 @@
+package searchcraigs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html/template"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/gobs/simplejson"
+)
+
+// serve starts an HTTP server on a random local port, serving the results page at /,
+// the raw results at /json and the output schema at /api/schema, and opens the browser.
+// The server runs until it receives no requests for idle time, or it's interrupted.
+//
+// The page can be shared by multiple people: each user (selected with ?user=name, then remembered in a cookie)
+// has their own seen entries and favorites, stored in the data directory for the search identified by searchKey.
+func serve(res *SearchResults, t *template.Template, printFriendly bool, idle time.Duration, searchKey string, maxAge time.Duration) error {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return err
+	}
+
+	var last atomic.Int64 // time of the last request
+	last.Store(time.Now().UnixNano())
+
+	dir, err := userStateDir(searchKey)
+	if err != nil {
+		return err
+	}
+
+	mux := serveMux(res, t, printFriendly, &userStates{dir: dir, maxAge: maxAge})
+
+	server := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			last.Store(time.Now().UnixNano())
+			mux.ServeHTTP(w, r)
+		}),
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	go func() {
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+			case <-ticker.C:
+				if time.Since(time.Unix(0, last.Load())) < idle {
+					continue
+				}
+
+				log.Println("idle, shutting down")
+			}
+
+			sctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			server.Shutdown(sctx)
+			cancel()
+			return
+		}
+	}()
+
+	url := fmt.Sprintf("http://%v/", l.Addr())
+	log.Println("serving results at", url)
+	if err := openbrowser(url); err != nil {
+		log.Printf("cannot open browser: %v", err)
+	}
+
+	if err := server.Serve(l); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	return nil
+}
+
+// name of the cookie storing the -serve user
+const userCookie = "searchcraigs-user"
+
+// valid user names, that are also used as file names
+var validUser = regexp.MustCompile(`^[a-z0-9_-]{1,32}$`)
+
+// userStates are the seen states of the -serve users, loaded on first use.
+type userStates struct {
+	dir    string // a file per user
+	maxAge time.Duration
+
+	mu     sync.Mutex
+	states map[string]*SeenState
+}
+
+// with calls fn with the state of user, holding the lock (the handlers run concurrently).
+// If fn returns true the state is saved.
+func (u *userStates) with(user string, fn func(state *SeenState) bool) error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	state, ok := u.states[user]
+	if !ok {
+		var err error
+		if state, err = LoadSeenState(filepath.Join(u.dir, user+".json"), u.maxAge); err != nil {
+			return err
+		}
+
+		if u.states == nil {
+			u.states = map[string]*SeenState{}
+		}
+
+		u.states[user] = state
+	}
+
+	if !fn(state) {
+		return nil
+	}
+
+	return state.Save()
+}
+
+// users returns the known users (the ones with a state file), sorted.
+func (u *userStates) users() []string {
+	names := map[string]bool{}
+
+	u.mu.Lock()
+	for name := range u.states {
+		names[name] = true
+	}
+	u.mu.Unlock()
+
+	files, _ := filepath.Glob(filepath.Join(u.dir, "*.json"))
+	for _, f := range files {
+		if name := strings.TrimSuffix(filepath.Base(f), ".json"); validUser.MatchString(name) {
+			names[name] = true
+		}
+	}
+
+	return sortedKeys(names)
+}
+
+// requestUser returns the user selected with ?user=name (and remembers it in a cookie),
+// or in the cookie. An empty ?user= goes back to the shared page, without a user.
+func requestUser(w http.ResponseWriter, r *http.Request) (string, error) {
+	if r.URL.Query().Has("user") {
+		user := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("user")))
+		if user != "" && !validUser.MatchString(user) {
+			return "", fmt.Errorf("invalid user %q (use up to 32 letters, digits, - and _)", user)
+		}
+
+		cookie := &http.Cookie{Name: userCookie, Value: user, Path: "/", MaxAge: 365 * 24 * 60 * 60, SameSite: http.SameSiteLaxMode}
+		if user == "" {
+			cookie.MaxAge = -1
+		}
+
+		http.SetCookie(w, cookie)
+		return user, nil
+	}
+
+	if c, err := r.Cookie(userCookie); err == nil && validUser.MatchString(c.Value) {
+		return c.Value, nil
+	}
+
+	return "", nil
+}
+
+// serveMux returns the handlers of serve. The page of a user shows the entries not seen by the user first,
+// and can hide the ones seen before (?hide-seen=1). POST /seen marks all entries as seen by the user,
+// POST /favorite (with an href) adds or removes a favorite.
+func serveMux(res *SearchResults, t *template.Template, printFriendly bool, users *userStates) *http.ServeMux {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+
+		user, err := requestUser(w, r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		page := pageData{SearchResults: res, PrintFriendly: printFriendly, Serve: true, User: user, Users: users.users()}
+
+		if user != "" {
+			view := *res
+			view.Entries = slices.Clone(res.Entries)
+			page.SearchResults = &view
+			page.HideSeen = r.URL.Query().Get("hide-seen") == "1"
+			page.Favorites = map[string]bool{}
+
+			err := users.with(user, func(state *SeenState) bool {
+				n := state.SplitSeen(&view)
+				if page.HideSeen {
+					view.Entries = view.Entries[:n]
+					view.split = false
+				}
+
+				for href := range state.Favorites {
+					page.Favorites[href] = true
+				}
+
+				return false
+			})
+			if err != nil {
+				log.Printf("ERROR: %v", err)
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if err := writePage(w, t, page); err != nil {
+			log.Printf("ERROR: %v", err)
+		}
+	})
+
+	// userAction handles the POST requests that change the state of the user, and goes back to the page
+	userAction := func(action func(r *http.Request, state *SeenState)) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodPost {
+				http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+
+			user, err := requestUser(w, r)
+			if err == nil && user == "" {
+				err = errors.New("no user selected")
+			}
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			err = users.with(user, func(state *SeenState) bool {
+				action(r, state)
+				return true
+			})
+			if err != nil {
+				log.Printf("ERROR: %v", err)
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+
+			back := url.Values{"user": {user}}
+			if r.FormValue("hide-seen") == "1" {
+				back.Set("hide-seen", "1")
+			}
+
+			http.Redirect(w, r, "/?"+back.Encode(), http.StatusSeeOther)
+		}
+	}
+
+	mux.HandleFunc("/seen", userAction(func(r *http.Request, state *SeenState) {
+		state.Mark(res.Entries)
+	}))
+
+	mux.HandleFunc("/favorite", userAction(func(r *http.Request, state *SeenState) {
+		if href := r.FormValue("href"); href != "" {
+			state.ToggleFavorite(href)
+		}
+	}))
+
+	mux.HandleFunc("/json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintln(w, simplejson.MustDumpString(res, simplejson.Indent(" ")))
+	})
+
+	mux.HandleFunc("/api/schema", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintln(w, simplejson.MustDumpString(OutputSchema(), simplejson.Indent(" ")))
+	})
+
+	return mux
+}
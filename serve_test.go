@@ -0,0 +1,222 @@
+package searchcraigs
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type testServe struct {
+	*testServer
+
+	t   *testing.T
+	res *SearchResults
+	dir string
+
+	mu  sync.Mutex
+	mux *http.ServeMux
+}
+
+func newTestServe(t *testing.T) *testServe {
+	s := &testServe{t: t, res: goldenResults(), dir: t.TempDir()}
+	s.restart()
+
+	s.testServer = newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		mux := s.mux
+		s.mu.Unlock()
+
+		mux.ServeHTTP(w, r)
+	}))
+
+	return s
+}
+
+// restart creates new handlers on the same state directory, as when serve runs again.
+func (s *testServe) restart() {
+	tmpl, err := loadTemplate("", "list")
+	if err != nil {
+		s.t.Fatal(err)
+	}
+
+	s.mu.Lock()
+	s.mux = serveMux(s.res, tmpl, false, &userStates{dir: s.dir})
+	s.mu.Unlock()
+}
+
+// do sends the request, without following the redirects.
+func (s *testServe) do(method, path string, body io.Reader, cookies ...*http.Cookie) *http.Response {
+	s.t.Helper()
+
+	r, err := http.NewRequest(method, s.URL+path, body)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+
+	if body != nil {
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+
+	for _, c := range cookies {
+		r.AddCookie(c)
+	}
+
+	resp, err := http.DefaultTransport.RoundTrip(r)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+
+	s.t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func (s *testServe) page(path string) string {
+	s.t.Helper()
+
+	resp := s.do("GET", path, nil)
+	if resp.StatusCode != http.StatusOK {
+		s.t.Fatalf("GET %v: status %v", path, resp.Status)
+	}
+
+	return readBody(s.t, resp)
+}
+
+func (s *testServe) post(path string, form url.Values) {
+	s.t.Helper()
+
+	resp := s.do("POST", path, strings.NewReader(form.Encode()))
+	if resp.StatusCode != http.StatusSeeOther {
+		s.t.Fatalf("POST %v: status %v, want 303", path, resp.Status)
+	}
+}
+
+func readBody(t *testing.T, resp *http.Response) string {
+	t.Helper()
+
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(b)
+}
+
+func TestServeUsers(t *testing.T) {
+	s := newTestServe(t)
+	all := len(s.res.Entries)
+	first := s.res.Entries[0].Href
+
+	for _, user := range []string{"anna", "ben"} {
+		if page := s.page("/?user=" + user); !strings.Contains(page, fmt.Sprintf("%v new", all)) {
+			t.Errorf("%v: want all %v entries new", user, all)
+		}
+	}
+
+	s.post("/seen?user=anna", nil)
+	s.post("/favorite?user=ben", url.Values{"href": {first}})
+
+	check := func(when string) {
+		anna := s.page("/?user=anna")
+		ben := s.page("/?user=ben")
+
+		if !strings.Contains(anna, "0 new") {
+			t.Errorf("%v: anna has entries still new after marking them seen", when)
+		}
+		if !strings.Contains(ben, fmt.Sprintf("%v new", all)) {
+			t.Errorf("%v: ben has entries marked seen by anna", when)
+		}
+
+		if strings.Contains(anna, "Remove from favorites") {
+			t.Errorf("%v: anna has ben's favorite", when)
+		}
+		if n := strings.Count(ben, "Remove from favorites"); n != 1 {
+			t.Errorf("%v: ben has %v favorites, want 1", when, n)
+		}
+
+		if n := strings.Count(s.page("/?user=anna&hide-seen=1"), "<article"); n != 0 {
+			t.Errorf("%v: anna hiding the seen entries has %v entries, want 0", when, n)
+		}
+		if n := strings.Count(s.page("/?user=ben&hide-seen=1"), "<article"); n != all {
+			t.Errorf("%v: ben hiding the seen entries has %v entries, want %v", when, n, all)
+		}
+	}
+
+	check("running")
+
+	for _, user := range []string{"anna", "ben"} {
+		if _, err := os.Stat(filepath.Join(s.dir, user+".json")); err != nil {
+			t.Errorf("state of %v not saved: %v", user, err)
+		}
+	}
+
+	s.restart()
+	check("restarted")
+
+	// removing the favorite
+	s.post("/favorite?user=ben", url.Values{"href": {first}})
+	if strings.Contains(s.page("/?user=ben"), "Remove from favorites") {
+		t.Error("ben's favorite not removed")
+	}
+
+	// carl is only listed after marking or adding something
+	if page := s.page("/?user=carl"); !strings.Contains(page, `<datalist id="users"><option value="anna"><option value="ben"></datalist>`) {
+		t.Error("user picker without the known users")
+	}
+}
+
+func TestServeUserSelection(t *testing.T) {
+	s := newTestServe(t)
+	s.post("/seen?user=anna", nil)
+
+	// ?user= sets the cookie
+	resp := s.do("GET", "/?user=Anna", nil)
+	cookies := resp.Cookies()
+	if len(cookies) != 1 || cookies[0].Name != userCookie || cookies[0].Value != "anna" {
+		t.Fatalf("cookies %v, want %v=anna", cookies, userCookie)
+	}
+
+	// the cookie selects the user
+	if page := readBody(t, s.do("GET", "/", nil, cookies[0])); !strings.Contains(page, "0 new") || !strings.Contains(page, `value="anna"`) {
+		t.Error("cookie doesn't select anna")
+	}
+
+	// ?user overrides the cookie
+	if page := readBody(t, s.do("GET", "/?user=ben", nil, cookies[0])); !strings.Contains(page, fmt.Sprintf("%v new", len(s.res.Entries))) {
+		t.Error("?user=ben doesn't override the cookie")
+	}
+
+	// an empty ?user= removes the cookie and goes back to the shared page
+	resp = s.do("GET", "/?user=", nil, cookies[0])
+	if cookies := resp.Cookies(); len(cookies) != 1 || cookies[0].MaxAge >= 0 {
+		t.Errorf("cookies %v, want %v removed", cookies, userCookie)
+	}
+	if page := readBody(t, resp); strings.Contains(page, "favorites") || strings.Contains(page, `class="new"`) {
+		t.Error("shared page with the state of a user")
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		status int
+	}{
+		{"invalid user", "GET", "/?user=../anna", http.StatusBadRequest},
+		{"long user", "GET", "/?user=" + strings.Repeat("a", 33), http.StatusBadRequest},
+		{"no user", "POST", "/seen", http.StatusBadRequest},
+		{"get seen", "GET", "/seen?user=anna", http.StatusMethodNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if resp := s.do(tt.method, tt.path, nil); resp.StatusCode != tt.status {
+				t.Errorf("%v %v: status %v, want %v", tt.method, tt.path, resp.StatusCode, tt.status)
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package searchcraigs
+
+import (
+	"fmt"
+	"math/rand"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Simulated results, to exercise the post-fetch pipeline (filters, dedup, rendering)
+// without sending requests to craigslist.
+
+var (
+	simAdjectives = []string{"vintage", "new", "used", "like new", "antique", "small", "large", "electric", "wooden", "folding", "rare", "mint"}
+	simNouns      = []string{"road bike", "mountain bike", "record player", "guitar", "amplifier", "desk", "sofa", "kayak", "laptop", "iphone", "table saw", "drill", "camera", "tent", "dresser", "monitor"}
+	simSuffixes   = []string{"", "", "", " - must go", " w/ extras", " (obo)", " !!!", " great condition", " needs work"}
+	simHoods      = []string{"mission district", "oakland", "berkeley", "san jose", "palo alto", "sunnyvale", "fremont", "santa cruz", "san rafael", "daly city", "hayward", "mountain view"}
+	simNearby     = [][2]string{{"sacramento", "sac"}, {"monterey", "mnt"}, {"stockton", "stk"}, {"modesto", "mod"}}
+	simCategories = []string{"bik", "ele", "fuo", "msg", "sgd", "tls", "sys", "mob"}
+	simSubregions = []string{"sfc", "eby", "sby", "pen", "nby", "scz"}
+)
+
+// SimulateEntries returns n plausible, randomly generated entries.
+// The same seed always generates the same entries.
+func SimulateEntries(n int, seed int64) []ResultEntry {
+	rnd := rand.New(rand.NewSource(seed))
+	now := time.Date(2021, 8, 20, 18, 0, 0, 0, time.UTC)
+
+	entries := make([]ResultEntry, 0, n)
+
+	for i := 0; i < n; i++ {
+		if i > 0 && rnd.Intn(20) == 0 { // about 5% are reposts of a previous listing
+			entry := entries[rnd.Intn(len(entries))]
+			entry.Href = simHref(rnd, entry.Title)
+			entries = append(entries, entry)
+			continue
+		}
+
+		title := simAdjectives[rnd.Intn(len(simAdjectives))] + " " +
+			simNouns[rnd.Intn(len(simNouns))] +
+			simSuffixes[rnd.Intn(len(simSuffixes))]
+
+		if rnd.Intn(4) == 0 {
+			title = strings.ToUpper(title[:1]) + title[1:]
+		}
+
+		entry := ResultEntry{
+			Title:    title,
+			Href:     simHref(rnd, title),
+			Datetime: now.Add(-time.Duration(rnd.Intn(7*24*60)) * time.Minute).Format("2006-01-02 15:04"),
+			Price:    simPrice(rnd),
+			Region:   string(SFBay),
+		}
+
+		entry.Currency = priceCurrency(entry.Price, "US")
+		entry.PriceValue = parsePrice(entry.Price, entry.Currency)
+		entry.Posted = parseDatetime(entry.Datetime, "US")
+		entry.EntryCategory = categoryFor(entry.Href)
+
+		if rnd.Intn(5) > 0 {
+			entry.Image = fmt.Sprintf("https://images.craigslist.org/%05d_%x_300x300.jpg", rnd.Intn(100000), rnd.Int63())
+		}
+
+		if rnd.Intn(8) == 0 {
+			nearby := simNearby[rnd.Intn(len(simNearby))]
+			entry.NearbyLoc = nearby[0]
+			entry.NearbyDesc = nearby[1]
+		} else {
+			entry.Neighborhood = "(" + simHoods[rnd.Intn(len(simHoods))] + ")"
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries
+}
+
+// Simulate returns SearchResults for n simulated entries,
+// optionally removing duplicates as Search would do.
+func Simulate(n int, seed int64, query string, dedup bool) *SearchResults {
+	results := SearchResults{
+		Title: query,
+		Url:   fmt.Sprintf(searchuri, SFBay) + string(ForSale) + "?" + url.Values{"query": {query}}.Encode(),
+	}
+
+	if query == "" {
+		results.Title = "Results"
+	}
+
+	if dedup {
+		results.seen = map[uint64]bool{}
+	}
+
+	for _, entry := range SimulateEntries(n, seed) {
+		results.add(entry)
+	}
+
+	return &results
+}
+
+func simHref(rnd *rand.Rand, title string) string {
+	slug := strings.Trim(strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' {
+			return r
+		}
+
+		return '-'
+	}, strings.ToLower(title)), "-")
+
+	return fmt.Sprintf("https://sfbay.craigslist.org/%v/%v/d/%v/%v.html",
+		simSubregions[rnd.Intn(len(simSubregions))],
+		simCategories[rnd.Intn(len(simCategories))],
+		slug,
+		7300000000+rnd.Int63n(100000000))
+}
+
+func simPrice(rnd *rand.Rand) string {
+	switch rnd.Intn(10) {
+	case 0:
+		return ""
+	case 1:
+		return "$0"
+	}
+
+	p := rnd.Intn(200) * 5
+	if rnd.Intn(4) == 0 {
+		p *= 10
+	}
+
+	if p < 1000 {
+		return fmt.Sprintf("$%v", p)
+	}
+
+	return fmt.Sprintf("$%v,%03d", p/1000, p%1000)
+}
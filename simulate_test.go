@@ -0,0 +1,149 @@
+package searchcraigs
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/gobs/simplejson"
+)
+
+func TestSimulateEntries(t *testing.T) {
+	a, b := SimulateEntries(500, 42), SimulateEntries(500, 42)
+
+	if !reflect.DeepEqual(a, b) {
+		t.Error("the same seed generated different entries")
+	}
+
+	if reflect.DeepEqual(a, SimulateEntries(500, 43)) {
+		t.Error("different seeds generated the same entries")
+	}
+
+	res := Simulate(500, 42, "bike", true)
+	if n := len(res.Entries); n >= 500 || n < 400 {
+		t.Errorf("%v entries after removing the duplicates, want about 95%% of 500", n)
+	}
+
+	if res.Title != "bike" || res.Url != "https://sfbay.craigslist.org/search/sss?query=bike" {
+		t.Errorf("title %q, url %q", res.Title, res.Url)
+	}
+}
+
+// benchmarkSizes are the number of simulated entries for the pipeline benchmarks
+var benchmarkSizes = []int{1000, 10000, 50000}
+
+// benchmark runs fn for each of benchmarkSizes, with fresh simulated results (not timed) for each run,
+// reporting the entries processed per second.
+func benchmark(b *testing.B, fn func(b *testing.B, res *SearchResults)) {
+	for _, n := range benchmarkSizes {
+		b.Run(fmt.Sprintf("%dk", n/1000), func(b *testing.B) {
+			entries := SimulateEntries(n, 1)
+
+			b.ReportAllocs()
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				res := &SearchResults{Title: "bike", SchemaVersion: SchemaVersion, Entries: append([]ResultEntry(nil), entries...)}
+				b.StartTimer()
+
+				fn(b, res)
+			}
+
+			b.ReportMetric(float64(n)*float64(b.N)/b.Elapsed().Seconds(), "entries/s")
+		})
+	}
+}
+
+// The whole post-fetch pipeline, as run by the command with
+// -simulate N -filter "bike -kids" -min-local 50 -max-local 500 -no-nearby -localsort price -limit 100.
+// The baseline numbers are printed by go test -bench Pipeline -run XXX.
+func BenchmarkPipeline(b *testing.B) {
+	syn := NewSynonyms(true, nil)
+
+	tmpl, err := loadTemplate("", "list")
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	benchmark(b, func(b *testing.B, res *SearchResults) {
+		var err error
+
+		res.seen = map[uint64]bool{}
+		entries := res.Entries
+		res.Entries = nil
+		for _, e := range entries {
+			res.add(e)
+		}
+
+		if res.Entries, err = applyFilter("bike -kids", syn, res.Entries); err != nil {
+			b.Fatal(err)
+		}
+
+		res.Entries = FilterPrice(res.Entries, 50, 500)
+		res.Entries = FilterNearby(res.Entries)
+		SortEntries(res.Entries, PriceAsc)
+		res.Limit(100)
+
+		if err := writeHTML(io.Discard, tmpl, res, false); err != nil {
+			b.Fatal(err)
+		}
+	})
+}
+
+func BenchmarkDedup(b *testing.B) {
+	benchmark(b, func(b *testing.B, res *SearchResults) {
+		deduped := SearchResults{seen: map[uint64]bool{}}
+		for _, e := range res.Entries {
+			deduped.add(e)
+		}
+	})
+}
+
+func BenchmarkFilter(b *testing.B) {
+	syn := NewSynonyms(true, nil)
+
+	benchmark(b, func(b *testing.B, res *SearchResults) {
+		if _, err := applyFilter("(bike|guitar) -kids -electric", syn, res.Entries); err != nil {
+			b.Fatal(err)
+		}
+	})
+}
+
+func BenchmarkSort(b *testing.B) {
+	benchmark(b, func(b *testing.B, res *SearchResults) {
+		SortEntries(res.Entries, PriceAsc)
+	})
+}
+
+// the output formats, for all the entries
+func BenchmarkOutput(b *testing.B) {
+	tmpl, err := loadTemplate("", "list")
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	outputs := []struct {
+		name  string
+		write func(w io.Writer, res *SearchResults) error
+	}{
+		{"html", func(w io.Writer, res *SearchResults) error { return writeHTML(w, tmpl, res, false) }},
+		{"json", func(w io.Writer, res *SearchResults) error {
+			_, err := io.WriteString(w, simplejson.MustDumpString(res, simplejson.Indent(" ")))
+			return err
+		}},
+		{"csv", func(w io.Writer, res *SearchResults) error { return WriteCSV(w, res.Entries, nil) }},
+		{"rss", WriteRSS},
+	}
+
+	for _, o := range outputs {
+		b.Run(o.name, func(b *testing.B) {
+			benchmark(b, func(b *testing.B, res *SearchResults) {
+				if err := o.write(io.Discard, res); err != nil {
+					b.Fatal(err)
+				}
+			})
+		})
+	}
+}
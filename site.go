@@ -0,0 +1,540 @@
+package searchcraigs
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"flag"
+	"fmt"
+	"html/template"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The export-site pages. Links are relative so that the site works from file://,
+// and there are no timestamps other than the listing ones, so that exports can be diffed.
+
+const siteIndexTemplate = `<!DOCTYPE html>
+<html lang="en">
+  <head>
+    <title>searchcraigs export</title>
+    <meta charset="UTF-8">
+    <style>
+      body { font-family: sans-serif; margin: 2em; }
+      table { border-collapse: collapse; width: 100%; }
+      th, td { text-align: left; padding: 4px 8px; border-bottom: 1px solid #ddd; }
+      .chart { margin-bottom: 2em; max-width: 50em; }
+      .bar { display: flex; align-items: center; margin: 2px 0; }
+      .bar .label { width: 12em; }
+      .bar .fill { background: #6a8caf; height: 1em; margin-right: 4px; }
+    </style>
+  </head>
+  <body>
+    <h1>{{ .Stats.Count }} listings</h1>
+    <p>{{ .StatsLine }}</p>
+
+    {{ define "chart" }}
+    <div class="chart">
+      {{ range . }}
+      <div class="bar"><span class="label">{{ .Label }}</span><span class="fill" style="width: {{ .Width }}%"></span>{{ .Count }}</div>
+      {{ end }}
+    </div>
+    {{ end }}
+
+    {{ if .PriceChart }}
+    <h2>Prices</h2>
+    {{ template "chart" .PriceChart }}
+    {{ end }}
+
+    {{ if .MonthChart }}
+    <h2>Listings by month</h2>
+    {{ template "chart" .MonthChart }}
+    {{ end }}
+
+    <input id="search" type="search" placeholder="Filter" aria-label="Filter listings" size="40">
+
+    <table id="listings">
+      <thead><tr><th>Title</th><th>Price</th><th>Posted</th><th>Seen</th><th>Link</th></tr></thead>
+      <tbody>
+      {{ range .Listings }}
+        <tr>
+          <td>{{ if .Page }}<a href="{{ .Page }}">{{ .Title }}</a> &#9733;{{ else }}{{ .Title }}{{ end }}</td>
+          <td>{{ range $i, $p := .Prices }}{{ if $i }} &rarr; {{ end }}{{ $p }}{{ end }}</td>
+          <td>{{ if not .Posted.IsZero }}{{ .Posted.Format "2006-01-02" }}{{ end }}</td>
+          <td>{{ if not .FirstSeen.IsZero }}{{ .FirstSeen.Format "2006-01-02" }} - {{ .LastSeen.Format "2006-01-02" }}{{ end }}</td>
+          <td><a href="{{ .Href }}">craigslist</a></td>
+        </tr>
+      {{ end }}
+      </tbody>
+    </table>
+
+    <script>
+      document.getElementById("search").addEventListener("input", function (e) {
+        var q = e.target.value.toLowerCase();
+        document.querySelectorAll("#listings tbody tr").forEach(function (tr) {
+          tr.style.display = tr.textContent.toLowerCase().indexOf(q) >= 0 ? "" : "none";
+        });
+      });
+    </script>
+  </body>
+</html>
+`
+
+const siteListingTemplate = `<!DOCTYPE html>
+<html lang="en">
+  <head>
+    <title>{{ .Title }}</title>
+    <meta charset="UTF-8">
+    <style>
+      body { font-family: sans-serif; margin: 2em; max-width: 60em; }
+      img { max-width: 300px; margin: 4px; }
+    </style>
+  </head>
+  <body>
+    <p><a href="../index.html">All listings</a></p>
+    <h1>{{ .Title }}{{ if .Price }} - {{ .Price }}{{ end }}</h1>
+    <p>
+      <a href="{{ .Href }}">{{ .Href }}</a><br/>
+      {{ if not .Posted.IsZero }}Posted: {{ .Posted.Format "2006-01-02 15:04" }}<br/>{{ end }}
+      {{ if not .Updated.IsZero }}Updated: {{ .Updated.Format "2006-01-02 15:04" }}<br/>{{ end }}
+    </p>
+    {{ if .Attributes }}
+    <ul>
+      {{ range .Attributes }}
+      <li>{{ if .Name }}{{ .Name }}: {{ end }}<b>{{ .Value }}</b></li>
+      {{ end }}
+    </ul>
+    {{ end }}
+    <p style="white-space: pre-line">{{ .Description }}</p>
+    {{ if .NotCached }}<p>The details of this listing are not in the cache.</p>{{ end }}
+    {{ range .Images }}<img src="{{ . }}" alt="" loading="lazy">{{ end }}
+    {{ if .MissingImages }}<p>{{ .MissingImages }} images not in the cache</p>{{ end }}
+  </body>
+</html>
+`
+
+// SiteStore is the local data exported by ExportSite.
+type SiteStore struct {
+	Cache   *DetailsCache  // the listing details and images (-details-cache)
+	States  []*SeenState   // the entries seen by the searches, and the favorites
+	History []PriceHistory // the listings recorded in the price database (-db), optional
+}
+
+// siteListing is a row of the index table
+type siteListing struct {
+	Href      string
+	Title     string
+	Prices    []string // the prices recorded in the database (oldest first), or the cached price
+	Posted    time.Time
+	FirstSeen time.Time // when the searches found the listing (from the database)
+	LastSeen  time.Time
+	Page      string // the relative link to the listing page, for the favorites
+
+	entry ResultEntry // the last price, for the stats
+}
+
+// date returns the time the listing was posted or, if not known, first seen
+func (l siteListing) date() time.Time {
+	if !l.Posted.IsZero() {
+		return l.Posted
+	}
+
+	return l.FirstSeen
+}
+
+// siteListingPage is a Listing with the images copied from the cache
+type siteListingPage struct {
+	*Listing
+
+	Images        []string // relative to the page
+	MissingImages int      // images not in the cache
+	NotCached     bool     // the listing details are not in the cache
+}
+
+// siteBar is a bar of the index charts
+type siteBar struct {
+	Label string
+	Count int
+	Width int // percent of the largest bar
+}
+
+// priceBuckets is the number of bars of the price chart
+const priceBuckets = 5
+
+// ExportSite writes a static site with the listings in store to dir:
+// index.html, with the price stats, the price and month charts and a table of all the listings
+// seen by the searches (with the price changes in the database), a page for each favorite
+// in listings/ and the favorite images in the cache in images/.
+// listings/ and images/ are removed first, so that they only have the current favorites.
+// It doesn't use the network: the images that are not in the cache are left out.
+func ExportSite(dir string, store SiteStore) error {
+	index := template.Must(template.New("index").Parse(siteIndexTemplate))
+	page := template.Must(template.New("listing").Parse(siteListingTemplate))
+
+	for _, sub := range []string{"listings", "images"} {
+		if err := os.RemoveAll(filepath.Join(dir, sub)); err != nil {
+			return err
+		}
+
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0o755); err != nil {
+			return err
+		}
+	}
+
+	hrefs := map[string]bool{}
+	favorites := map[string]bool{}
+
+	for _, state := range store.States {
+		for k := range state.Seen {
+			if href, ok := strings.CutPrefix(k, "href:"); ok {
+				hrefs[href] = true
+			}
+		}
+
+		for href := range state.Favorites {
+			hrefs[href] = true
+			favorites[href] = true
+		}
+	}
+
+	history := map[string]PriceHistory{}
+	for _, h := range store.History {
+		history[h.Href] = h
+	}
+
+	images := siteImages(store.Cache)
+
+	var listings []siteListing
+	var entries []ResultEntry
+
+	for _, href := range sortedKeys(hrefs) {
+		l := siteHistoryListing(href, history[href], store.Cache.Listings[href].Listing)
+		entries = append(entries, l.entry)
+
+		if favorites[href] {
+			name := strings.TrimSuffix(path.Base(href), ".html") + ".html"
+			l.Page = "listings/" + name
+
+			if err := writeSiteListing(dir, name, page, l, store.Cache.Listings[href].Listing, images); err != nil {
+				return err
+			}
+		}
+
+		listings = append(listings, l)
+	}
+
+	// most recent first, then by link, so that the order is always the same
+	sort.SliceStable(listings, func(i, j int) bool {
+		return listings[i].date().After(listings[j].date())
+	})
+
+	stats := ComputePriceStats(entries, "")
+
+	var line strings.Builder
+	stats.Print(&line)
+
+	return writeSitePage(filepath.Join(dir, "index.html"), index, map[string]any{
+		"Stats":      stats,
+		"StatsLine":  strings.TrimSpace(line.String()),
+		"PriceChart": priceChart(entries, stats),
+		"MonthChart": monthChart(listings),
+		"Listings":   listings,
+	})
+}
+
+// siteHistoryListing returns the index row for href, from the database history and the cached details (if any).
+func siteHistoryListing(href string, h PriceHistory, cached *Listing) siteListing {
+	l := siteListing{Href: href, Title: h.Title, FirstSeen: h.FirstSeen, LastSeen: h.LastSeen}
+
+	for _, s := range h.Sightings {
+		if s.Price > 0 {
+			l.Prices = append(l.Prices, currencySymbol(h.Currency)+strconv.Itoa(s.Price))
+		}
+	}
+
+	if n := len(h.Sightings); n > 0 {
+		l.entry = ResultEntry{PriceValue: h.Sightings[n-1].Price, Currency: h.Currency}
+	}
+
+	if cached != nil {
+		l.Posted = cached.Posted
+
+		if l.Title == "" {
+			l.Title = cached.Title
+		}
+
+		if len(h.Sightings) == 0 {
+			currency := priceCurrency(cached.Price, countryOf(regionOf(href)))
+			l.entry = ResultEntry{PriceValue: parsePrice(cached.Price, currency), Currency: currency}
+
+			if cached.Price != "" {
+				l.Prices = []string{cached.Price}
+			}
+		}
+	}
+
+	if l.Title == "" {
+		l.Title = href
+	}
+
+	return l
+}
+
+// writeSiteListing writes the page of the favorite l to listings/name in dir, copying the cached images to images/.
+// A favorite that is not in the cache gets a page with the title and price in the index.
+func writeSiteListing(dir, name string, t *template.Template, l siteListing, cached *Listing, images map[string]string) error {
+	p := siteListingPage{Listing: cached}
+
+	if cached == nil {
+		p.Listing = &Listing{Href: l.Href, Title: l.Title}
+		p.NotCached = true
+
+		if n := len(l.Prices); n > 0 {
+			p.Price = l.Prices[n-1]
+		}
+	}
+
+	for _, u := range p.Listing.Images {
+		data, ok := images[u]
+		if !ok {
+			data, ok = images[imageKey(u)]
+		}
+
+		if !ok {
+			p.MissingImages++
+			continue
+		}
+
+		file, err := writeSiteImage(filepath.Join(dir, "images"), u, data)
+		if err != nil {
+			return err
+		}
+
+		p.Images = append(p.Images, "../images/"+file)
+	}
+
+	return writeSitePage(filepath.Join(dir, "listings", name), t, p)
+}
+
+// thumbnailSize matches the size suffix of the craigslist image URLs (i.e. _300x300.jpg, _600x450.jpg)
+var thumbnailSize = regexp.MustCompile(`_\d+x\d+\.\w+$`)
+
+// imageKey returns the craigslist image URL without the size, so that a listing image
+// can be matched to the (smaller) thumbnail cached by EmbedImages.
+func imageKey(u string) string {
+	return thumbnailSize.ReplaceAllString(u, "")
+}
+
+// siteImages returns the data: URIs of the cached images, by URL and by imageKey.
+// When more images have the same key the first URL (in sorted order) is used.
+func siteImages(cache *DetailsCache) map[string]string {
+	images := map[string]string{}
+
+	for _, u := range sortedKeys(cache.Images) {
+		data := cache.Images[u].Data
+		images[u] = data
+
+		if k := imageKey(u); k != u {
+			if _, ok := images[k]; !ok {
+				images[k] = data
+			}
+		}
+	}
+
+	return images
+}
+
+// writeSiteImage writes the image in the data: URI to dir, with a name computed from its URL.
+// It returns the name of the file.
+func writeSiteImage(dir, u, data string) (string, error) {
+	mimeType, encoded, ok := strings.Cut(strings.TrimPrefix(data, "data:"), ";base64,")
+	if !ok || !strings.HasPrefix(data, "data:") {
+		return "", fmt.Errorf("%v: invalid cached image", u)
+	}
+
+	b, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", fmt.Errorf("%v: %w", u, err)
+	}
+
+	ext := ".img"
+	switch mimeType {
+	case "image/jpeg":
+		ext = ".jpg"
+	case "image/png":
+		ext = ".png"
+	case "image/gif":
+		ext = ".gif"
+	case "image/webp":
+		ext = ".webp"
+	}
+
+	sum := sha256.Sum256([]byte(u))
+	name := fmt.Sprintf("%x%v", sum[:8], ext)
+	return name, os.WriteFile(filepath.Join(dir, name), b, 0o644)
+}
+
+// priceChart returns the number of entries in priceBuckets price ranges between the min and max price,
+// or nil if the stats could not be computed (no prices, or multiple currencies).
+func priceChart(entries []ResultEntry, stats PriceStats) []siteBar {
+	if stats.Priced == 0 {
+		return nil
+	}
+
+	step := (stats.Max-stats.Min)/priceBuckets + 1
+	counts := make([]int, priceBuckets)
+
+	for _, e := range entries {
+		if e.PriceValue > 0 {
+			counts[min((e.PriceValue-stats.Min)/step, priceBuckets-1)]++
+		}
+	}
+
+	c := currencySymbol(stats.Currency)
+	bars := make([]siteBar, priceBuckets)
+
+	for i, n := range counts {
+		from := stats.Min + i*step
+		bars[i] = siteBar{Label: fmt.Sprintf("%v%v - %v%v", c, from, c, from+step-1), Count: n}
+	}
+
+	return scaleBars(bars)
+}
+
+// monthChart returns the number of listings posted (or first seen, if the posting time is not known)
+// in each month, oldest first.
+func monthChart(listings []siteListing) []siteBar {
+	counts := map[string]int{}
+
+	for _, l := range listings {
+		if d := l.date(); !d.IsZero() {
+			counts[d.Format("2006-01")]++
+		}
+	}
+
+	var bars []siteBar
+	for _, month := range sortedKeys(counts) {
+		bars = append(bars, siteBar{Label: month, Count: counts[month]})
+	}
+
+	return scaleBars(bars)
+}
+
+// scaleBars sets the width of the bars, relative to the largest one.
+func scaleBars(bars []siteBar) []siteBar {
+	largest := 0
+	for _, b := range bars {
+		largest = max(largest, b.Count)
+	}
+
+	if largest > 0 {
+		for i := range bars {
+			bars[i].Width = bars[i].Count * 100 / largest
+		}
+	}
+
+	return bars
+}
+
+func writeSitePage(path string, t *template.Template, data any) error {
+	var b strings.Builder
+
+	if err := t.Execute(&b, data); err != nil {
+		return fmt.Errorf("%v: %w", path, err)
+	}
+
+	return os.WriteFile(path, []byte(b.String()), 0o644)
+}
+
+// exportSiteCommand runs "searchcraigs export-site -details-cache FILE [-state FILE]... [-db FILE] DIR".
+func exportSiteCommand(args []string) error {
+	fs := flag.NewFlagSet("export-site", flag.ContinueOnError)
+	cachePath := fs.String("details-cache", "", "The -details-cache file with the listing details and images")
+	dbPath := fs.String("db", "", "The -db price database with the price changes of the listings")
+	var statePaths stringList
+	fs.Var(&statePaths, "state", "File with the entries seen by a search and the favorites, can be repeated\n"+
+		"(the default is all the searches and -serve users in the data directory)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: searchcraigs export-site -details-cache FILE [-state FILE]... [-db FILE] DIR")
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+
+		return err
+	}
+
+	if *cachePath == "" || fs.NArg() != 1 {
+		fs.Usage()
+		return errors.New("export-site needs -details-cache and the output directory")
+	}
+
+	cache, err := LoadDetailsCache(*cachePath, 0)
+	if err != nil {
+		return err
+	}
+
+	store := SiteStore{Cache: cache}
+
+	if len(statePaths) == 0 {
+		if statePaths, err = siteStatePaths(); err != nil {
+			return err
+		}
+	}
+
+	for _, path := range statePaths {
+		state, err := LoadSeenState(path, 0)
+		if err != nil {
+			return err
+		}
+
+		store.States = append(store.States, state)
+	}
+
+	if *dbPath != "" {
+		db, err := OpenPriceDB(*dbPath)
+		if err != nil {
+			return err
+		}
+
+		defer db.Close()
+
+		if store.History, err = db.History(""); err != nil {
+			return err
+		}
+	}
+
+	return ExportSite(fs.Arg(0), store)
+}
+
+// siteStatePaths returns the state files of the searches (see autoStatePath) and of the -serve users
+// (see userStateDir) in the data directory, sorted.
+func siteStatePaths() ([]string, error) {
+	dir, err := dataDir()
+	if err != nil {
+		return nil, err
+	}
+
+	var paths []string
+
+	for _, pattern := range []string{filepath.Join(dir, "seen", "*.json"), filepath.Join(dir, "users", "*", "*.json")} {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, err
+		}
+
+		paths = append(paths, matches...)
+	}
+
+	sort.Strings(paths)
+	return paths, nil
+}
@@ -0,0 +1,228 @@
+package searchcraigs
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testSiteCache(t *testing.T) *DetailsCache {
+	t.Helper()
+
+	cache, err := LoadDetailsCache(filepath.Join(t.TempDir(), "details.json"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	posted := time.Date(2026, 3, 14, 10, 0, 0, 0, time.UTC)
+
+	for i, price := range []string{"$100", "$250", "$900", ""} {
+		href := fmt.Sprintf("https://sfbay.craigslist.org/sfc/bik/d/bike/74000000%v.html", i)
+		cache.Listings[href] = CachedListing{Fetched: time.Now(), Listing: &Listing{
+			Href:   href,
+			Title:  "bike " + price,
+			Price:  price,
+			Posted: posted.AddDate(0, i, 0),
+			Images: []string{
+				fmt.Sprintf("https://images.craigslist.org/00a0a_bike%v_600x450.jpg", i),
+				"https://images.craigslist.org/00a0a_other_600x450.jpg",
+			},
+		}}
+
+		// the thumbnail of the first image, cached by EmbedImages
+		cache.Images[fmt.Sprintf("https://images.craigslist.org/00a0a_bike%v_300x300.jpg", i)] = CachedImage{
+			Fetched: time.Now(),
+			Data:    "data:image/jpeg;base64," + base64.StdEncoding.EncodeToString([]byte("jpeg "+price)),
+		}
+	}
+
+	return cache
+}
+
+// testSiteStore returns the testSiteCache listings, with 1 and 3 as favorites, 0 and 2 seen
+// with a listing not in the cache, a favorite not in the cache and the price history of 0.
+func testSiteStore(t *testing.T) SiteStore {
+	t.Helper()
+
+	state, err := LoadSeenState(filepath.Join(t.TempDir(), "seen.json"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	href := func(i int) string {
+		return fmt.Sprintf("https://sfbay.craigslist.org/sfc/bik/d/bike/74000000%v.html", i)
+	}
+
+	seen := time.Date(2026, 8, 1, 10, 0, 0, 0, time.UTC)
+
+	for _, i := range []int{0, 2, 9} {
+		state.Seen["href:"+href(i)] = seen
+	}
+
+	state.Seen["hash:0123456789abcdef"] = seen
+
+	for _, i := range []int{1, 3, 8} {
+		state.Favorites[href(i)] = seen
+	}
+
+	return SiteStore{
+		Cache:  testSiteCache(t),
+		States: []*SeenState{state},
+		History: []PriceHistory{
+			{Href: href(0), Title: "bike in the db", Currency: "USD", FirstSeen: seen.AddDate(0, -1, 0), LastSeen: seen,
+				Sightings: []Sighting{{Seen: seen.AddDate(0, -1, 0), Price: 120}, {Seen: seen, Price: 100}}},
+			{Href: href(9), Title: "old bike", Currency: "USD", FirstSeen: seen, LastSeen: seen,
+				Sightings: []Sighting{{Seen: seen, Price: 300}}},
+		},
+	}
+}
+
+// readTree returns the content of the files in dir, by relative path
+func readTree(t *testing.T, dir string) map[string]string {
+	t.Helper()
+
+	files := map[string]string{}
+
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		rel, _ := filepath.Rel(dir, path)
+		files[rel] = string(data)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return files
+}
+
+func TestExportSite(t *testing.T) {
+	store := testSiteStore(t)
+
+	dir1, dir2 := t.TempDir(), t.TempDir()
+	if err := ExportSite(dir1, store); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ExportSite(dir2, store); err != nil {
+		t.Fatal(err)
+	}
+
+	files1, files2 := readTree(t, dir1), readTree(t, dir2)
+
+	if len(files1) != len(files2) {
+		t.Fatalf("%v files, then %v", len(files1), len(files2))
+	}
+
+	for name, data := range files1 {
+		if files2[name] != data {
+			t.Errorf("%v changed between exports", name)
+		}
+	}
+
+	// index, the 3 favorites and the cached images of the 2 cached ones
+	if len(files1) != 6 {
+		t.Errorf("%v files, want 6", len(files1))
+	}
+
+	for _, name := range []string{"740000001.html", "740000003.html", "740000008.html"} {
+		if _, ok := files1[filepath.Join("listings", name)]; !ok {
+			t.Errorf("no page for the favorite %v", name)
+		}
+	}
+
+	page := files1[filepath.Join("listings", "740000001.html")]
+
+	if strings.Contains(page, "craigslist.org/00a0a") {
+		t.Error("the listing page links the remote images")
+	}
+
+	if !strings.Contains(page, `src="../images/`) || !strings.Contains(page, "1 images not in the cache") {
+		t.Errorf("listing page images:\n%v", page)
+	}
+
+	if page := files1[filepath.Join("listings", "740000008.html")]; !strings.Contains(page, "not in the cache") {
+		t.Errorf("page of a favorite not in the cache:\n%v", page)
+	}
+
+	index := files1["index.html"]
+	for _, want := range []string{"Prices", "$100 - $260", "Listings by month", "2026-06", "2026-08",
+		"bike in the db", "$120 &rarr; $100", "old bike", "bike $900", `href="listings/740000003.html"`} {
+		if !strings.Contains(index, want) {
+			t.Errorf("index without %q", want)
+		}
+	}
+
+	if strings.Contains(index, `href="listings/740000000.html"`) {
+		t.Error("the index links a page for a listing that is not a favorite")
+	}
+}
+
+func TestExportSiteRemoved(t *testing.T) {
+	store := testSiteStore(t)
+
+	dir := t.TempDir()
+	if err := ExportSite(dir, store); err != nil {
+		t.Fatal(err)
+	}
+
+	store.States[0].ToggleFavorite("https://sfbay.craigslist.org/sfc/bik/d/bike/740000001.html")
+
+	if err := ExportSite(dir, store); err != nil {
+		t.Fatal(err)
+	}
+
+	files := readTree(t, dir)
+
+	if _, ok := files[filepath.Join("listings", "740000001.html")]; ok {
+		t.Error("the page of a removed favorite is still there")
+	}
+
+	// index, the 2 favorites and the image of 3
+	if len(files) != 4 {
+		t.Errorf("%v files, want 4: %v", len(files), sortedKeys(files))
+	}
+}
+
+func TestPriceChart(t *testing.T) {
+	entries := []ResultEntry{{PriceValue: 10}, {PriceValue: 10}, {PriceValue: 50}, {PriceValue: 0}, {PriceValue: 110}}
+
+	bars := priceChart(entries, ComputePriceStats(entries, ""))
+	if len(bars) != priceBuckets {
+		t.Fatalf("%v bars, want %v", len(bars), priceBuckets)
+	}
+
+	counts := []int{2, 1, 0, 0, 1}
+	widths := []int{100, 50, 0, 0, 50}
+
+	for i, b := range bars {
+		if b.Count != counts[i] || b.Width != widths[i] {
+			t.Errorf("bar %v: %+v, want count %v width %v", i, b, counts[i], widths[i])
+		}
+	}
+
+	if bars := priceChart(nil, PriceStats{}); bars != nil {
+		t.Errorf("no prices: %+v", bars)
+	}
+}
+
+func TestExportSiteCommandUsage(t *testing.T) {
+	for _, args := range [][]string{nil, {"dir"}, {"-details-cache", "details.json"}, {"-unknown"}} {
+		if err := exportSiteCommand(args); err == nil {
+			t.Errorf("export-site %v should fail", args)
+		}
+	}
+}
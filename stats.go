@@ -0,0 +1,93 @@
+package searchcraigs
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// PriceStats are simple statistics on the prices of a list of entries.
+// Entries without a price (free or missing) are counted but not included in the price stats.
+//
+// Prices in different currencies are not mixed: if there is more than one currency
+// only Count and Currencies are set, unless a currency is assumed for all prices.
+type PriceStats struct {
+	Count  int
+	Priced int
+
+	Currency   string
+	Currencies []string `json:",omitempty"` // set if the prices are in multiple currencies
+
+	Min    int
+	Max    int
+	Median int
+	Mean   float64
+}
+
+// ComputePriceStats returns the price statistics for the entries. If assume is not empty
+// all prices are considered to be in that currency.
+func ComputePriceStats(entries []ResultEntry, assume string) PriceStats {
+	stats := PriceStats{Count: len(entries), Currency: assume}
+
+	var prices []int
+	var currencies []string
+
+	for _, e := range entries {
+		if e.PriceValue > 0 {
+			prices = append(prices, e.PriceValue)
+
+			if e.Currency != "" {
+				currencies = appendUnique(currencies, e.Currency)
+			}
+		}
+	}
+
+	if len(prices) == 0 {
+		return stats
+	}
+
+	if assume == "" {
+		if len(currencies) > 1 {
+			sort.Strings(currencies)
+			stats.Currencies = currencies
+			return stats
+		}
+
+		if len(currencies) == 1 {
+			stats.Currency = currencies[0]
+		}
+	}
+
+	sort.Ints(prices)
+
+	total := 0
+	for _, p := range prices {
+		total += p
+	}
+
+	stats.Priced = len(prices)
+	stats.Min = prices[0]
+	stats.Max = prices[len(prices)-1]
+	stats.Median = prices[len(prices)/2]
+	stats.Mean = float64(total) / float64(len(prices))
+	return stats
+}
+
+func (s PriceStats) Print(w io.Writer) {
+	if len(s.Currencies) > 1 {
+		fmt.Fprintf(w, "%v entries, prices in multiple currencies (%v): use -assume-currency to compute the stats\n",
+			s.Count, strings.Join(s.Currencies, ", "))
+		return
+	}
+
+	if s.Priced == 0 {
+		fmt.Fprintf(w, "%v entries, no prices\n", s.Count)
+		return
+	}
+
+	c := currencySymbol(s.Currency)
+
+	fmt.Fprintf(w, "%v entries, %v with price: min %v%v, median %v%v, mean %v%.0f, max %v%v\n",
+		s.Count, s.Priced, c, s.Min, c, s.Median, c, s.Mean, c, s.Max)
+}
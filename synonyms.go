@@ -0,0 +1,197 @@
+package searchcraigs
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// craigslist silently truncates longer queries
+const maxQueryLength = 255
+
+// Synonyms maps a (lowercase) term to the list of equivalent terms, including the term itself.
+type Synonyms map[string][]string
+
+// builtinSynonyms is a starter mapping for common marketplace nouns (english, spanish, french)
+var builtinSynonyms = map[string][]string{
+	"bicycle":      {"bike", "bicicleta", "vélo", "velo"},
+	"car":          {"auto", "coche", "carro", "voiture"},
+	"sofa":         {"couch", "sofá", "canapé", "canape"},
+	"chair":        {"silla", "chaise"},
+	"desk":         {"escritorio", "bureau"},
+	"dresser":      {"cómoda", "comoda", "commode"},
+	"bed":          {"cama"},
+	"mattress":     {"colchón", "colchon", "matelas"},
+	"refrigerator": {"fridge", "nevera", "refrigerador", "réfrigérateur", "frigo"},
+	"washer":       {"lavadora", "laveuse"},
+	"dryer":        {"secadora", "sécheuse", "secheuse"},
+	"phone":        {"teléfono", "telefono", "téléphone", "telephone", "celular"},
+	"computer":     {"computadora", "ordenador", "ordinateur"},
+	"laptop":       {"portátil", "portatil", "ordinateur portable"},
+	"television":   {"tv", "televisión", "télévision", "tele"},
+	"guitar":       {"guitarra", "guitare"},
+	"stroller":     {"carriola", "cochecito", "poussette"},
+	"tools":        {"herramientas", "outils"},
+	"free":         {"gratis", "gratuit"},
+}
+
+// NewSynonyms returns the built-in synonyms (if builtin is true) merged with the ones in groups.
+func NewSynonyms(builtin bool, groups map[string][]string) Synonyms {
+	s := Synonyms{}
+
+	if builtin {
+		s.Add(builtinSynonyms)
+	}
+
+	s.Add(groups)
+	return s
+}
+
+// LoadSynonyms reads a JSON file mapping terms to a list of synonyms, i.e.
+//
+//	{ "bicycle": ["bike", "bicicleta", "vélo"] }
+func LoadSynonyms(path string, builtin bool) (Synonyms, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var groups map[string][]string
+	if err := json.Unmarshal(data, &groups); err != nil {
+		return nil, fmt.Errorf("%v: %w", path, err)
+	}
+
+	return NewSynonyms(builtin, groups), nil
+}
+
+// Add adds the terms in groups. All terms in a group (the key and its list) become equivalent.
+func (s Synonyms) Add(groups map[string][]string) {
+	keys := make([]string, 0, len(groups))
+	for k := range groups {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys) // so that the order of the expanded terms is always the same
+
+	for _, k := range keys {
+		group := []string{strings.ToLower(k)}
+		for _, t := range groups[k] {
+			group = appendUnique(group, strings.ToLower(t))
+		}
+
+		// merge with existing groups for any of the terms
+		for i := 0; i < len(group); i++ {
+			for _, e := range s[group[i]] {
+				group = appendUnique(group, e)
+			}
+		}
+
+		for _, t := range group {
+			s[t] = group
+		}
+	}
+}
+
+// Lookup returns all the terms equivalent to term (including term).
+func (s Synonyms) Lookup(term string) []string {
+	term = strings.ToLower(term)
+
+	if group, ok := s[term]; ok {
+		return group
+	}
+
+	return []string{term}
+}
+
+// ExpandQuery replaces each word in the query that has synonyms with an OR group, (a|b|c),
+// and each negated word with the list of negated synonyms (-a -b -c).
+// Quoted phrases and existing groups are left untouched.
+func ExpandQuery(query string, s Synonyms) (string, error) {
+	var out []string
+
+	for _, tok := range splitQuery(query) {
+		neg := strings.HasPrefix(tok, "-")
+		word := strings.TrimPrefix(tok, "-")
+
+		if word == "" || strings.ContainsAny(word[:1], `"(`) || len(s.Lookup(word)) == 1 {
+			out = append(out, tok)
+			continue
+		}
+
+		var terms []string
+		for _, t := range s.Lookup(word) {
+			if strings.Contains(t, " ") {
+				t = `"` + t + `"`
+			}
+
+			if neg {
+				t = "-" + t
+			}
+
+			terms = append(terms, t)
+		}
+
+		if neg {
+			out = append(out, strings.Join(terms, " "))
+		} else {
+			out = append(out, "("+strings.Join(terms, "|")+")")
+		}
+	}
+
+	expanded := strings.Join(out, " ")
+	if n := utf8.RuneCountInString(expanded); n > maxQueryLength {
+		return "", fmt.Errorf("expanded query is too long (%v characters, max %v): %v", n, maxQueryLength, expanded)
+	}
+
+	return expanded, nil
+}
+
+// splitQuery splits a query in words, keeping "quoted phrases" and (grouped | terms) together.
+func splitQuery(query string) (tokens []string) {
+	var cur strings.Builder
+	var closing rune
+
+	for _, r := range query {
+		switch {
+		case closing != 0:
+			if r == closing {
+				closing = 0
+			}
+
+		case r == '"':
+			closing = '"'
+
+		case r == '(':
+			closing = ')'
+
+		case r == ' ' || r == '\t':
+			if cur.Len() > 0 {
+				tokens = append(tokens, cur.String())
+				cur.Reset()
+			}
+
+			continue
+		}
+
+		cur.WriteRune(r)
+	}
+
+	if cur.Len() > 0 {
+		tokens = append(tokens, cur.String())
+	}
+
+	return
+}
+
+func appendUnique(list []string, s string) []string {
+	for _, v := range list {
+		if v == s {
+			return list
+		}
+	}
+
+	return append(list, s)
+}
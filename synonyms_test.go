@@ -0,0 +1,81 @@
+package searchcraigs
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestSplitQuery(t *testing.T) {
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"", nil},
+		{"road bike", []string{"road", "bike"}},
+		{"  road \t bike  ", []string{"road", "bike"}},
+		{`"road bike" -carbon`, []string{`"road bike"`, "-carbon"}},
+		{`(road | gravel) bike`, []string{"(road | gravel)", "bike"}},
+		{`-"kids bike" (a "b c")`, []string{`-"kids bike"`, `(a "b c")`}},
+		{`"unterminated phrase`, []string{`"unterminated phrase`}},
+	}
+
+	for _, tt := range tests {
+		if got := splitQuery(tt.query); !slices.Equal(got, tt.want) {
+			t.Errorf("splitQuery(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestExpandQuery(t *testing.T) {
+	syn := NewSynonyms(false, map[string][]string{
+		"bicycle": {"bike", "vélo"},
+		"laptop":  {"notebook", "ordinateur portable"},
+	})
+
+	tests := []struct {
+		name, query, want string
+	}{
+		{"no synonyms", "road frame", "road frame"},
+		{"or group", "bicycle", "(bicycle|bike|vélo)"},
+		{"any term of the group", "Bike road", "(bicycle|bike|vélo) road"},
+		{"negated", "road -bicycle", "road -bicycle -bike -vélo"},
+		{"phrase synonym", "laptop", `(laptop|notebook|"ordinateur portable")`},
+		{"negated phrase synonym", "-laptop", `-laptop -notebook -"ordinateur portable"`},
+		{"quoted phrase untouched", `"bicycle rack"`, `"bicycle rack"`},
+		{"negated quoted phrase untouched", `-"bicycle rack"`, `-"bicycle rack"`},
+		{"group untouched", "(bicycle|trike)", "(bicycle|trike)"},
+		{"lone dash", "road - bike", "road - (bicycle|bike|vélo)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ExpandQuery(tt.query, syn)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got != tt.want {
+				t.Errorf("ExpandQuery(%q) = %q, want %q", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExpandQueryLength(t *testing.T) {
+	syn := NewSynonyms(false, map[string][]string{"vélo": {"bicyclette"}})
+
+	// "(vélo|bicyclette)" is 17 characters but 18 bytes
+	query := strings.TrimSpace(strings.Repeat("(vélo|bicyclette) ", 14)) + " " + strings.Repeat("x", maxQueryLength-14*18)
+	if _, err := ExpandQuery(query, syn); err != nil {
+		t.Errorf("ExpandQuery of %v characters: %v", len([]rune(query)), err)
+	}
+
+	if _, err := ExpandQuery(query+"x", syn); err == nil || !strings.Contains(err.Error(), "256 characters, max 255") {
+		t.Errorf("ExpandQuery of %v characters: got error %v", len([]rune(query))+1, err)
+	}
+
+	if _, err := ExpandQuery(strings.Repeat("vélo ", 15), syn); err == nil {
+		t.Error("ExpandQuery should fail when the expansion is too long")
+	}
+}
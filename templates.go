@@ -0,0 +1,359 @@
+package searchcraigs
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// placeholder for entries without an image
+const noImage = "data:image/png;base64,iVBORw0KGgoAAAANSUhEUgAAAOEAAADhCAMAAAAJbSJIAAAAaVBMVEX///9mZmZfX19nZ2dcXFxiYmKysrL29vbAwMD7+/tzc3NZWVmTk5NdXV34+PjOzs58fHzg4OCioqKGhobY2Nju7u6cnJypqamUlJTm5ubHx8eMjIxtbW2xsbGlpaW8vLyDg4NPT09JSUm0hXY6AAAOgklEQVR4nO1dCZurLA9VhFqhuFWtVrvM9/9/5JcEu8zMbcdt1HtfzvPM0oKYYyBsITqOhYWFhYWFhYWFhYWFhYWFxRvkx3O564ryfMyXFrgnkj3njImuYIzzfba00D0QlEq4AK8rMLNQZbC04F3hS+aSzN1B+Zn0lxa9GwKJAjMl687tsJYKH4qQf4cWSxSWF1Wvi6pCuZ7Lyl+SaVJkCjUY9b4u0nCd+hvMTSw8l/cnCBS564l4cnkmR648VxSDLi2E66n194sXaIV8mJg5h+p9mVie6QGKEHLgtWCEB6p/TtQg5VCLWMK1+0ml+Q3shcvSgdem7F9nWPzzDNPla2meRD8gi91RDN04++kWyW91KPm2Flzxn4DDyzEM3R/vwLmot9OzTGqu8f400fkBgxkWokPpKIHgvE4m5ZfX7YSvgwQwuBzaa29Up/Lb6WQ9oR63WtAslSnF2U9VSPPd0DlQUP/cChhXNNOCMazeTkWwUOahyUNU+cGPGHOrn0sP/Co6SFOl1EQDoJqshyqnrfjjkJh1El5PUdgO520s7jeh/X1UMdZVvRtf0gE06Knz+IImxxnmaS4/jC2GZuyDjePv4kKyjV0VaFCDm0kEmh4b1GIzsgzteqx21rIElh+30WPFMXBq5rl61PMPyCivZRXTr7E7VkX4+Ia6sTHP/whmhk/Wr45E7ppxlZYPilvueqMEjLHE8bJNg1jQMjp0XU9dBNgJd8T6nK+gFa7FzERgOdn1hAvq6tE5b6AlquHNKMJKupauvhAew1EaTrEfT71CEYeszhoc2GhjPB2uwuU4cIz0pwU5qKZseK+P62aTjPymAAijUVkb5rLzp69HyDhmRWJynLBCZX4E7ZAfH1+nbIypkcDwNIFwkyDHMRoX+Fs8ugsiPnQF2jBciyl9WgFQx+dv/yGGzoHmhIxfnkeRa2MY+rkfDh5lJUXjyvTz8sx6GFbHcy1dXOURrqzPx4Hd7Lensw6GQVQ0SrPHWqFgWjVFNMWUZQ0Mq1SY1bEvYEqk4wdMyzNMaqLneTBqZtqsNzIYP7fLk6MXdpdmWJmlZA8aoJblZhtlWRZtN6XUnJnVa1WP0+PCDE9cmKVWkUafJwB+BHXXJPJRg4pFGVaSm/ZW/3m1KDMV2OVyhBqXZBiR8RS8fL2/kJekZDHA/eaGBRmaQRa/vtdPdeXYHocv5y3HMFUdN09w2wcoDp3DLMaw4J7r6bjLBlgea8jLB+6yLMWQ9gJ4V6+Tkg9fn1+IoVlv777Zcab8g1YFl2GImx2e6qOTA67PD9qCWIRh6OG6bb92VWi8KPw541cswBB3E+C6vqtD7UW9pxtL6BCXbvurgxSv+vf8S+iwwVXp/lOGBB6MaP4GHeIt9RDLf9BDbzczQxxt91eFg8qH8Rvve+X8DPGOzyu2PYB7eYPuNy9D3O4a7CPs9t8nmZ0hbVcNU2G7IdvTnM7OcIfzvcFeXzhX7OkhMzfDQMGUafhmzhkmUqrf85mbIVXS4ctnSf9qOjdD3OtyB9/Pcdze+3lzM4zdcScm0Ie2327gzAxDHM+Mcf7Y4rim15B2ZoaVGjQkfQAHp6rX2uLMDNHQsDEuVD7ra2pmZngZ7bzR9D3cNTPD0yi3AUTc13VgZobjDy7tRc/DDDMzLEY74NR9D+hZhu+xTC3tN6iZmeEBGI60NKKnm5rtLd6jf4+vJ+jx9Zp7/P6Dri/oP+ybmSGqgA1dw0AcWd9KMPfsSU4we+on7xIz4DGu797qZ8AR97wR0ydox97KVzFCPsrtGh3Leb89ndlXE2vh9VrTD8MnZ0xc1+876ltmRbhzPQvoSOrddva72GB2hrSo23ng5ju57+fBrV7GA5aT59+3wElw141OUF8A1dRvGdKpmL5ObvMz9HGjs+MtgyAPfT/I22pKB376jvnmYxiAqPQP2kPdURPADy5rdXjSQ+zwfAyhshmGIbmz/TQ4NebFd/z8dmHFv5yk6IYFGDpH1SHyGuiOroKG2OaUGCWq/5h2JoZgLHwyGH4Iv66ubDR6fAV+W3UhDZ7AzaJg24MPPmYOMAOYG6cUsqH1gUe+TpiHIRAJc9ShT6rJoeOO+Yl4E8UwNHxCEp0eRkhN0Ec+RPDQSLdpciyrH8VZGBpxscKhdI4fRI3UsdoQc8MC+Rn6DikzpBTMTD/OxRVNLI4BldWL4jwMUaKQGILGwDQGGyUb1904YWBsJf6Evun5jF7xV2D+wiO5KOlKtQnwaYS5f2/Sa2FIukGRUWEkY5CivwL67pFqQ/9WNcObjaGH0uowLDwX6miBygR+/vpqqVFKeGeIn2uNQl/xu+8Mg2eGQbhDDbLSMTUaOKIGu5KctR2SiMaS+EHJgaJsjo4hbviF99ZoDAp8H0YC8rleHRiGptHCeDXvVlNns6W3xuXcLWeqpBQNK3LnwdBkRPUElCdw8l2DT0Kltwfk3xl20+Jc/WFIHWE7/mrVcFEecJTuwQ+op8QnENwbGY3y/AOTnutJdXHaWmuuDmGs09HaLHqiJGnowAhj6Z/XNZKUmQzNH9K7WtNlTwUFqTKHnpQ8fSWRnExkXVcMPohAWPpkVxJzcTuKdz1vs6SqqiTbHq4ub6Mp83jc6bWlGcK0to1aRdWVKwRnt+OIQsnh54EMlmfoONmO6T+FmhOa7caHDV4DQwxIuAfFiSeaAtS5nyQ04DoYAoJss4s9jdWUay8uN9lEcYtWw9DAz8HS5JPGLFoZw1+AZfgeq4r88QInNjz2NK1BizVG23vGWYxxjlhVBJ4XGCfjqqIovcC4KEp0OmDdge5zPeL0gwnrs/JA9+jBM+ZtA3Fvx4G5Ifts5v0BW959q2wR4IbcqKiC6Jm+aluDp6z6eb5/Be14rfeFDEWPnbwXwE1rb9gx8hmwxRPgg09ZtaBDvQM2vebAkWQbbSZSeoXPGsffFFpETxAT8Eqvb9qvrePP97hnzK5TlEWBs4U6r4ljfjbR6aZ5eRLuslCsu/0lWUNIYT+57M1apK6nCuF8aiM6MaW6vJ/hdyGUYm30qQnnrlW8AmqfIdTEgfCj+B5VrvNbDX8DrQxMxdMPJqtTzBRbHlorFp9+KbpxmETH7dI4RsmogaiFhYWFhYWFhYWFhYWFxX8AjxdQvnkV5ci3VPZCENLN/nDLT990F2mjeLvPsVcfL5wisw917SXlGGwUBiNOPtTXbdpCPb+xEKTtuC61YV77oqFavDqvnKlpVtK7CcTRCyFR38L6pOx5970WXU+eon+RYfaGoYzn20/cxvEGGX7byB7D0DyulwznfxNiMLUOzbb4neGxlrJ+3j6s0vMGf6eRc4plWTn+IZaFWb7MiljGuzZ3VMvrxbmkKe11bPcy/vxGkkfmQ2pS8jQ9B21CTbuyUZoeH7X0KQEYbvMCisyfGWY7KXdvuUI7bFxX+HeGueSCMcbjx45MpviVfhex4kKwrFHaFQzvdFKCcSYURa8slBBc7fYMfUHCWAmtxfNO66bNXDvOmRtn9ovCKO4XyMo1JaDpe7TDywckwBV7Yih2QmkQLnkwPECRmql3keA3oPtYiPLOUApXlDvxvIuVcTwlmClgddhgkAd93nj0VrvkQ4jzpWAUVGGrXLa77JRxOaqZkJszewpbUoE0KWTGr3LI69Dd4MNzgnNh4s7wkZBRdArmnU6NcL3gxhBuKc6bxuVvtnORYcKxCMPwwl08Epg/e3S0DLnLMgpFoo/4B01BVkhsGzu6nTS+WAeGDEHEJsToNY93MyYmcynQoWlPl4BBkZSAEhYCY6Rc2MOWQsKJEjBsIYZrwsOKHjlEGYbwf0Yv7mSvjQUw3Dglc2XLMBbGoWrLHsbszhCdUOBxCIdEuHVYQXLFi0AtdCrbJ4YnZpw4xVe3gmRPRCKFzndkPdr0pBZs+5nhPUFfqJZSxT4wrD3E8CZEyt5EryeGIJS+7JBhIFqvNjyE/JUhcW5LrdrCszNu3uBjhW8EZW+wiEIIud9f996zg1abGeuA47o6D26RaSBBQ8J3huYKj10etvTI8dkQwwgeOt5E0pXvGGJ99mJsBuGNYa68+wtb7zr8xjC8KqZEuUcdYrChB8MdyKs+8KjF/27mNLgqTZkpYM+J6S0UTGeH94orsdt/Z1hD8WwHVzwz1CgNMTxCfaJ7qI/XO6eGoQnegLW0aePJAp/7sOnO8PqV4VkLfLNRyuB2n2spyLNpT8qaBxU4B06ZD4x0mMOn1Bg3SNhjAjW3Z4YnuGWFD4NROzQulxfBypYhGL8Yz2c+YjS8ZliplmFJdhVLeAQOes1QmhBRptk35k3EJ2K4pboEPWdU3apCfMtMDDFMm2eeIrR9JFpSwjPD2G0TDENBzmhX0ijdEi0yln7avtl9axk6B+0RQyhaF0lVcO/Rfl4zjAWKc+QeyraBP8WxNL2Fz8GEh2Gp1N1iXE1m5RmG0Ig8U++uAm3I8Xs7hOqZ4guIPGNpXFZXeaqpZZuHCnqo4Wkq/vHaa+TGEIP/o6VBpxyBL7t76qrv/eE3hhd4iLJRrX9/DM1Cq5raIfWO+C5ffR/VXJTXZjaNBhqGNoMfyNpQwuHBEM3aERNkmwA6vCqumfFlMgxzBgMQV799TzpMVsz9og/+QSY3klCOip/Mb0SzJ5hDkaX5UNRpfHAMDpViM0+rdq5z8BR0154xVlGDac++BWf8oqiUMq6PB6XavvJwS4AiNx9Ps6cDmCBVQoLAEdNHcoJ8rd7N7Cm/4qXeOxe18Ba9w3kcGM+z7JPSA3Na1EQPMB9ufzCvTwdK4d/AHFa+ByervpTTFkyZHSrw1kR9KoVO3Jrb3Ir36Qo8PWxiVMDnsJXbZMAyq9+fG9Ck3JFMYpec8BfuqZ3lWMtr3b/hDMO63aHoG+fxLwLMJmBOAiP9+N91nIgKGEIVa3YSt7CwsLCwsLCwsLCwsLCwsLCwsLCwsLCwsLCwsLCwWBv+D53G2YT70DOaAAAAAElFTkSuQmCC"
+
+// built-in page layouts, selected with -layout
+var layouts = map[string]string{
+	"list": listTemplate,
+	"grid": gridTemplate,
+}
+
+// pageBlocks are the templates shared by the layouts (and available to the -template files):
+// the common CSS, the navigation, the -serve forms and the parts of the entries.
+const pageBlocks = `
+{{ define "style" }}
+      a:focus-visible {
+        outline: 3px solid #0366d6;
+        outline-offset: 2px;
+      }
+      mark.nearby {
+        font-size: 0.75em;
+        background: #ddd;
+        color: #333;
+      }
+      form.user, form.favorite {
+        display: inline;
+      }
+      @media print {
+        form {
+          display: none;
+        }
+        article {
+          break-inside: avoid;
+          page-break-inside: avoid;
+        }
+        a {
+          color: black;
+          text-decoration: none;
+        }
+        article h3 a::after {
+          content: " (" attr(href) ")";
+          font-size: 0.6em;
+          font-weight: normal;
+          word-break: break-all;
+        }
+      }
+{{ end }}
+
+{{ define "nav" }}
+      {{ if .Split }}
+      <nav>
+        <a href="#new">New ({{ .New }})</a>
+        <a href="#seen">Seen before ({{ .Seen }})</a>
+      </nav>
+      {{ end }}
+      {{ if .Serve }}
+      <nav>
+        <form class="user" method="get" action="/">
+          <input name="user" list="users" value="{{ .User }}" placeholder="user" aria-label="User">
+          <datalist id="users">{{ range .Users }}<option value="{{ . }}">{{ end }}</datalist>
+          <button type="submit">Switch user</button>
+        </form>
+        {{ if .User }}
+        <mark class="new">{{ .New }} new</mark>
+        <a href="/?user={{ .User }}{{ if not .HideSeen }}&hide-seen=1{{ end }}">{{ if .HideSeen }}Show seen{{ else }}Hide seen{{ end }}</a>
+        <form class="user" method="post" action="/seen?user={{ .User }}">
+          {{ if .HideSeen }}<input type="hidden" name="hide-seen" value="1">{{ end }}
+          <button type="submit">Mark all as seen</button>
+        </form>
+        {{ end }}
+      </nav>
+      {{ end }}
+{{ end }}
+
+{{ define "favorite" }}
+          {{- if .Page.User }}<form class="favorite" method="post" action="/favorite?user={{ .Page.User }}">
+          {{- "" }}<input type="hidden" name="href" value="{{ .Href }}">{{ if .Page.HideSeen }}<input type="hidden" name="hide-seen" value="1">{{ end }}
+          {{- if index .Page.Favorites .Href }}<button type="submit" title="Remove from favorites">★</button>{{ else }}<button type="submit" title="Add to favorites">☆</button>{{ end }}
+          {{- "" }}</form>{{ end -}}
+{{ end }}
+
+{{ define "location" }}
+          {{ or .NearbyDesc .Neighborhood }}
+          {{ if .NearbyDesc }}<mark class="nearby" title="Result from a nearby area: {{ .NearbyLoc }}">nearby</mark>{{ end }}
+          {{ if .Region }}<br/>Region: {{ .Region }}{{ end }}
+          {{ if .Distance }}<br/>Distance: {{ printf "%.1f" .Distance }}mi{{ end }}
+          {{ if .SellerListingCount }}<br/><span title="{{ range .SellerListings }}{{ . }}
+{{ end }}">Seller has {{ .SellerListingCount }} other listings</span>{{ end }}
+{{ end }}
+
+{{ define "details" }}
+            <summary>Details{{ if .Images }} ({{ len .Images }} images){{ end }}</summary>
+            {{ if .Attributes }}
+            <ul>
+              {{ range .Attributes }}
+              <li>{{ if .Name }}{{ .Name }}: {{ end }}<b>{{ .Value }}</b></li>
+              {{ end }}
+            </ul>
+            {{ end }}
+            <p style="white-space: pre-line">{{ .Description }}</p>
+{{ end }}
+`
+
+const (
+	// one entry per row, image on the left
+	listTemplate = `<!DOCTYPE html>
+<html lang="en">
+  <head>
+    <title>{{ .Title }}</title>
+    <meta charset="UTF-8">
+    <link rel="stylesheet" href="https://cdnjs.cloudflare.com/ajax/libs/mini.css/3.0.1/mini-default.min.css">
+    <style>
+      .indent {
+        padding-left: 12px;
+      }
+      {{ template "style" }}
+    </style>
+  </head>
+  <body>
+    <header>
+      <h2>
+        <a href="{{ .Url }}">{{ .Title }}</a>
+        {{ if .Subtitle }}
+          <small>({{ .Subtitle }})</small>
+        {{ end }}
+        {{ if gt .TotalCount .Count }}
+          <small>showing {{ .Count }} of {{ .TotalCount }}</small>
+        {{ end }}
+      </h2>
+      {{ template "nav" . }}
+    </header>
+
+    <main class="container">
+    {{ range $i, $e := .Entries }}
+      {{ if and $.Split (eq $i 0) }}<h3 id="new" class="divider">New ({{ $.New }})</h3>{{ end }}
+      {{ if and $.Split (eq $i $.New) }}<hr><h3 id="seen" class="divider">Seen before ({{ $.Seen }})</h3>{{ end }}
+      <article class="row">
+        {{ if not $.PrintFriendly }}
+        <div class="col-sm-2">
+          <a href="{{ .Href }}" tabindex="-1">
+          {{ if .Image }}
+            <img src="{{ image .Image }}" alt="{{ .Title }}{{ if .Price }}, {{ .Price }}{{ end }}">
+          {{ else }}
+          <img src="` + noImage + `" width="300" height="300" alt="No Image Available">
+          {{ end }}
+          </a>
+        </div>
+        {{ end }}
+
+        <div class="{{ if $.PrintFriendly }}col-sm-12{{ else }}col-sm-10{{ end }}">
+          <h3>
+            <a href="{{ .Href }}">{{ .Title }}</a>
+            <small>Added: <time datetime="{{ .Datetime }}">{{ .Datetime }}</time></small>
+          </h3>
+          {{ template "favorite" ($.Entry .) }}
+          <div class="indent">
+          {{ if .HasPrice }}Price: {{ .Price }}{{ if .Extras }} - {{ .Extras }}{{ end }}<br/>{{ end }}
+          {{ if .Meta }}{{ .Meta }}<br/>{{ end }}
+          {{ template "location" . }}
+          </div>
+          {{ with .Details }}
+          <details class="indent">
+            {{ template "details" . }}
+          </details>
+          {{ end }}
+        </div>
+      </article>
+      {{ else }}
+      <p>No results</p>
+    {{ end }}
+    </main>
+
+  </body>
+</html>`
+
+	// responsive grid of cards, image on top
+	gridTemplate = `<!DOCTYPE html>
+<html lang="en">
+  <head>
+    <title>{{ .Title }}</title>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1">
+    <link rel="stylesheet" href="https://cdnjs.cloudflare.com/ajax/libs/mini.css/3.0.1/mini-default.min.css">
+    <style>
+      .grid {
+        display: grid;
+        grid-template-columns: repeat(auto-fill, minmax(220px, 1fr));
+        gap: 12px;
+        padding: 12px;
+      }
+      .grid article {
+        border: 1px solid #ddd;
+        border-radius: 4px;
+        overflow: hidden;
+      }
+      .grid img {
+        width: 100%;
+        aspect-ratio: 1;
+        object-fit: cover;
+        display: block;
+      }
+      .grid .body {
+        padding: 8px;
+      }
+      .grid h3 {
+        margin: 0 0 4px 0;
+        font-size: 1em;
+      }
+      .grid .divider {
+        grid-column: 1 / -1;
+        font-size: 1.2em;
+      }
+      {{ template "style" }}
+    </style>
+  </head>
+  <body>
+    <header>
+      <h2>
+        <a href="{{ .Url }}">{{ .Title }}</a>
+        <small>({{ if gt .TotalCount .Count }}showing {{ .Count }} of {{ .TotalCount }}{{ else }}{{ .Count }} results{{ end }}{{ if .Subtitle }}, {{ .Subtitle }}{{ end }})</small>
+      </h2>
+      {{ template "nav" . }}
+    </header>
+
+    <main class="grid">
+    {{ range $i, $e := .Entries }}
+      {{ if and $.Split (eq $i 0) }}<h3 id="new" class="divider">New ({{ $.New }})</h3>{{ end }}
+      {{ if and $.Split (eq $i $.New) }}<h3 id="seen" class="divider">Seen before ({{ $.Seen }})</h3>{{ end }}
+      <article>
+        {{ if not $.PrintFriendly }}
+        <a href="{{ .Href }}" tabindex="-1">
+        {{ if .Image }}
+          <img src="{{ image .Image }}" alt="{{ .Title }}{{ if .Price }}, {{ .Price }}{{ end }}" loading="lazy">
+        {{ else }}
+          <img src="` + noImage + `" alt="No Image Available">
+        {{ end }}
+        </a>
+        {{ end }}
+        <div class="body">
+          <h3><a href="{{ .Href }}">{{ .Title }}</a></h3>
+          {{ template "favorite" ($.Entry .) }}
+          {{ if .HasPrice }}<b>{{ .Price }}</b>{{ if .Extras }} {{ .Extras }}{{ end }}<br/>{{ end }}
+          <small>
+            <time datetime="{{ .Datetime }}">{{ .Datetime }}</time><br/>
+            {{ if .Meta }}{{ .Meta }}<br/>{{ end }}
+            {{ template "location" . }}
+          </small>
+          {{ with .Details }}
+          <details>
+            {{ template "details" . }}
+          </details>
+          {{ end }}
+        </div>
+      </article>
+      {{ else }}
+      <p>No results</p>
+    {{ end }}
+    </main>
+
+  </body>
+</html>`
+)
+
+// pageData is what's passed to the HTML template
+type pageData struct {
+	*SearchResults
+
+	PrintFriendly bool
+	Count         int
+
+	Split bool // show the new entries and the ones seen before in two sections
+	New   int
+	Seen  int
+
+	// with -serve, see serveMux
+	Serve     bool
+	User      string          // current user, empty for the shared page
+	Users     []string        // known users, for the user picker
+	HideSeen  bool            // only the entries not seen by the user are shown
+	Favorites map[string]bool // hrefs of the favorites of the user
+}
+
+// pageEntry is an entry with the page data, for the "favorite" block.
+type pageEntry struct {
+	ResultEntry
+	Page pageData
+}
+
+// Entry returns the entry e with the page data (i.e. {{ template "favorite" ($.Entry .) }}).
+func (p pageData) Entry(e ResultEntry) pageEntry {
+	return pageEntry{ResultEntry: e, Page: p}
+}
+
+// loadTemplate parses the user template in path or, if path is empty, the built-in layout.
+// The templates can use the functions in templateFuncs (truncate, money, json, image).
+// The template is executed with the SearchResults, plus PrintFriendly, Count (the number of entries)
+// and, if the entries are split in new and seen before (see SeenState.MarkNew), Split, New and Seen.
+// With -serve it also gets Serve, User, Users, HideSeen and Favorites.
+// The templates can use the blocks in pageBlocks, or redefine them.
+func loadTemplate(path, layout string) (*template.Template, error) {
+	if path != "" {
+		t, err := template.New(filepath.Base(path)).Funcs(templateFuncs).Parse(pageBlocks)
+		if err != nil {
+			return nil, err
+		}
+
+		// errors from ParseFiles include the file name and line
+		return t.ParseFiles(path)
+	}
+
+	text, ok := layouts[layout]
+	if !ok {
+		return nil, fmt.Errorf("unknown layout %q (list, grid)", layout)
+	}
+
+	t, err := template.New(layout).Funcs(templateFuncs).Parse(pageBlocks)
+	if err != nil {
+		return nil, err
+	}
+
+	return t.Parse(text)
+}
+
+// imageSrc returns the image URL for an img src. The data: URIs set by EmbedImages are marked as safe,
+// html/template would replace them with #ZgotmplZ. The other URLs are still checked by html/template.
+func imageSrc(image string) any {
+	if strings.HasPrefix(image, "data:image/") && !strings.HasPrefix(image, "data:image/svg") {
+		return template.URL(image)
+	}
+
+	return image
+}
+
+func writeHTML(w io.Writer, t *template.Template, res *SearchResults, printFriendly bool) error {
+	return writePage(w, t, pageData{SearchResults: res, PrintFriendly: printFriendly})
+}
+
+// writePage executes the template with data, setting the counts from its SearchResults.
+func writePage(w io.Writer, t *template.Template, data pageData) error {
+	data.Count = len(data.Entries)
+	data.Split = data.split
+
+	for _, e := range data.Entries {
+		if e.FirstSeenThisRun {
+			data.New++
+		}
+	}
+
+	data.Seen = data.Count - data.New
+	return t.Execute(w, data)
+}
@@ -0,0 +1,193 @@
+package searchcraigs
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var updateGolden = flag.Bool("update", false, "update the golden files in testdata/golden")
+
+const testDataURI = "data:image/jpeg;base64,/9j/4AAQSkZJRgABAQ=="
+
+// the data: URIs set by -embed-images must not be replaced by html/template
+func TestTemplateEmbeddedImage(t *testing.T) {
+	res := &SearchResults{
+		Title: "bike",
+		Entries: []ResultEntry{
+			{Title: "road bike", Href: "https://sfbay.craigslist.org/1.html", Image: testDataURI, Price: "$100"},
+			{Title: "bad link", Href: "https://sfbay.craigslist.org/2.html", Image: "javascript:alert(1)"},
+		},
+	}
+
+	for _, layout := range sortedKeys(layouts) {
+		t.Run(layout, func(t *testing.T) {
+			tmpl, err := loadTemplate("", layout)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var b strings.Builder
+			if err := writeHTML(&b, tmpl, res, false); err != nil {
+				t.Fatal(err)
+			}
+
+			page := b.String()
+
+			if !strings.Contains(page, `src="`+testDataURI+`"`) {
+				t.Error("the data: image was not rendered")
+			}
+
+			if strings.Contains(page, "javascript:") {
+				t.Error("the javascript: image was not sanitized")
+			}
+		})
+	}
+}
+
+// goldenResults are the results rendered by TestTemplateGolden: a new and a seen entry,
+// with and without images, prices, details and the optional fields.
+func goldenResults() *SearchResults {
+	return &SearchResults{
+		Title:      "desk",
+		Subtitle:   "Region: sfbay, Category: sss",
+		Url:        "https://sfbay.craigslist.org/search/sss?query=desk",
+		TotalCount: 25,
+		split:      true,
+		Entries: []ResultEntry{
+			{
+				Title:            "Standing desk, electric",
+				Href:             "https://sfbay.craigslist.org/eby/fuo/d/oakland-standing-desk-electric/7780000001.html",
+				Image:            "https://images.craigslist.org/00K0K_1aBcDeFgHiJ_300x300.jpg",
+				Datetime:         "2024-09-16 09:30",
+				Neighborhood:     "oakland",
+				Price:            "$1,250",
+				Region:           "sfbay",
+				PriceValue:       1250,
+				EntryCategory:    "fuo",
+				Distance:         2.34,
+				FirstSeenThisRun: true,
+				Details: &Listing{
+					Description: "Electric standing desk.\nPick up only.",
+					Images:      []string{"https://images.craigslist.org/00K0K_1aBcDeFgHiJ_600x450.jpg"},
+					Attributes:  []ListingAttribute{{Value: "uplift v2"}, {Name: "condition", Value: "like new"}},
+				},
+			},
+			{
+				Title:              "Sunny 2br near the lake",
+				Href:               "https://sfbay.craigslist.org/eby/apa/d/oakland-sunny-2br-near-the-lake/7780000002.html",
+				Datetime:           "2024-09-15 18:05",
+				NearbyLoc:          "sacramento",
+				NearbyDesc:         "davis",
+				Price:              "$2,800",
+				Region:             "sacramento",
+				PriceValue:         2800,
+				EntryCategory:      "apa",
+				Extras:             "2br 850ft2",
+				SellerListingCount: 2,
+				SellerListings:     []string{"Studio downtown", "1br with parking"},
+			},
+			{
+				Title:         "Desk assembly, same day",
+				Href:          "https://sfbay.craigslist.org/eby/lbs/d/richmond-desk-assembly-same-day/7780000004.html",
+				Datetime:      "2024-09-13 07:45",
+				Neighborhood:  "richmond",
+				EntryCategory: "lbs",
+				Meta:          "serving the east bay",
+			},
+		},
+	}
+}
+
+// the built-in layouts, normal and print friendly, compared with the pages in testdata/golden.
+// Run go test -run TestTemplateGolden -update to update them after changing the templates.
+func TestTemplateGolden(t *testing.T) {
+	for _, layout := range sortedKeys(layouts) {
+		for _, printFriendly := range []bool{false, true} {
+			name := layout
+			if printFriendly {
+				name += "-print"
+			}
+
+			t.Run(name, func(t *testing.T) {
+				tmpl, err := loadTemplate("", layout)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				var b strings.Builder
+				if err := writeHTML(&b, tmpl, goldenResults(), printFriendly); err != nil {
+					t.Fatal(err)
+				}
+
+				golden := filepath.Join("testdata", "golden", name+".html")
+
+				if *updateGolden {
+					if err := os.MkdirAll(filepath.Dir(golden), 0o755); err != nil {
+						t.Fatal(err)
+					}
+
+					if err := os.WriteFile(golden, []byte(b.String()), 0o644); err != nil {
+						t.Fatal(err)
+					}
+				}
+
+				want, err := os.ReadFile(golden)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if got := b.String(); got != string(want) {
+					t.Errorf("%v is different from %v (run with -update to update it)", name, golden)
+				}
+
+				if printFriendly == strings.Contains(b.String(), "<img") {
+					t.Errorf("print friendly %v, images %v", printFriendly, !printFriendly)
+				}
+
+				if !strings.Contains(b.String(), `article h3 a::after`) {
+					t.Error("no print rule for the link URLs")
+				}
+
+				for _, s := range []string{"Region: sacramento", "Distance: 2.3mi", "serving the east bay", "<summary>Details (1 images)</summary>", "Seller has 2 other listings"} {
+					if !strings.Contains(b.String(), s) {
+						t.Errorf("%q not found", s)
+					}
+				}
+			})
+		}
+	}
+}
+
+// the -template files can use the blocks of the layouts, and redefine them
+func TestTemplateBlocks(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "page.html")
+	text := `{{ define "details" }}[{{ .Description }}]{{ end }}` +
+		`<style>{{ template "style" }}</style>{{ range .Entries }}{{ template "location" . }}{{ with .Details }}{{ template "details" . }}{{ end }}{{ end }}`
+
+	if err := os.WriteFile(path, []byte(text), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tmpl, err := loadTemplate(path, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var b strings.Builder
+	if err := writeHTML(&b, tmpl, goldenResults(), false); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, s := range []string{"@media print", "Region: sacramento", "Seller has 2 other listings", "[Electric standing desk."} {
+		if !strings.Contains(b.String(), s) {
+			t.Errorf("%q not found", s)
+		}
+	}
+
+	if strings.Contains(b.String(), "<summary>") {
+		t.Error("the details block was not redefined")
+	}
+}
@@ -0,0 +1,118 @@
+package searchcraigs
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/template"
+	"unicode/utf8"
+)
+
+// Max lengths (in characters) of the titles rendered for each output, see RenderTitle.
+const (
+	PageTitleLength         = 120 // the HTML page, the feeds and the JSON output
+	NotificationTitleLength = 64  // about what the desktop notifications show
+)
+
+// DefaultTitleTemplate is the title template of the notifications when there is no -title.
+const DefaultTitleTemplate = `{{ .Label }}: {{ .NewCount }} new{{ if .MinPrice }}, cheapest {{ .MinPrice }}{{ end }}`
+
+var defaultTitleTemplate = template.Must(ParseTitleTemplate(DefaultTitleTemplate))
+
+// templateFuncs are the functions available in the page and title templates
+var templateFuncs = map[string]any{
+	// truncate shortens s to n characters, adding … if truncated
+	"truncate": truncate,
+
+	// money formats an amount in the currency (i.e. money 25 "CAD" is CA$25)
+	"money": func(amount int, currency string) string {
+		return currencySymbol(currency) + fmt.Sprint(amount)
+	},
+
+	// json encodes a value as JSON (i.e. a quoted string, for the -notify-template payloads)
+	"json": func(v any) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+
+	// image is the src of an entry image (i.e. <img src="{{ image .Image }}">), see imageSrc
+	"image": imageSrc,
+}
+
+// TitleData is what's passed to the title templates.
+type TitleData struct {
+	Label    string // saved search name, or the query
+	Query    string
+	Count    int    // number of entries
+	NewCount int    // entries not seen before (all entries if unknown)
+	MinPrice string // cheapest entry, i.e. $25 (empty if no prices, or prices in different currencies)
+}
+
+// ParseTitleTemplate parses a title template, i.e. `{{ .Label }}: {{ .NewCount }} new, cheapest {{ .MinPrice }}`.
+func ParseTitleTemplate(text string) (*template.Template, error) {
+	return template.New("title").Funcs(templateFuncs).Option("missingkey=error").Parse(text)
+}
+
+// NewTitleData returns the title data for the search results.
+func NewTitleData(label, query string, res *SearchResults) TitleData {
+	data := TitleData{Label: label, Query: query, Count: len(res.Entries), NewCount: len(res.Entries)}
+
+	if data.Label == "" {
+		data.Label = query
+	}
+
+	if data.Label == "" {
+		data.Label = "Results"
+	}
+
+	if res.split {
+		data.NewCount = 0
+
+		for _, e := range res.Entries {
+			if e.FirstSeenThisRun {
+				data.NewCount++
+			}
+		}
+	}
+
+	// no cheapest entry if the prices are in different currencies, as in ComputePriceStats
+	if stats := ComputePriceStats(res.Entries, ""); stats.Priced > 0 {
+		data.MinPrice = currencySymbol(stats.Currency) + fmt.Sprint(stats.Min)
+	}
+
+	return data
+}
+
+// RenderTitle executes the title template (DefaultTitleTemplate if t is nil), truncating the result
+// to max characters (i.e. NotificationTitleLength) if max > 0.
+func RenderTitle(t *template.Template, data TitleData, max int) (string, error) {
+	if t == nil {
+		t = defaultTitleTemplate
+	}
+
+	var b strings.Builder
+
+	if err := t.Execute(&b, data); err != nil {
+		return "", err
+	}
+
+	title := strings.Join(strings.Fields(b.String()), " ")
+	if max > 0 {
+		title = truncate(max, title)
+	}
+
+	return title, nil
+}
+
+func truncate(n int, s string) string {
+	if n <= 0 {
+		return ""
+	}
+
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+
+	r := []rune(s)
+	return string(r[:n-1]) + "…"
+}
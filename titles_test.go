@@ -0,0 +1,177 @@
+package searchcraigs
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"text/template"
+)
+
+func titleResults(split bool, entries ...ResultEntry) *SearchResults {
+	return &SearchResults{Entries: entries, split: split}
+}
+
+func TestNewTitleData(t *testing.T) {
+	entries := []ResultEntry{
+		{Title: "bike 1", PriceValue: 250, Currency: "USD", FirstSeenThisRun: true},
+		{Title: "bike 2", PriceValue: 0, Currency: "USD"},
+		{Title: "bike 3", PriceValue: 120, Currency: "USD"},
+	}
+
+	tests := []struct {
+		name  string
+		label string
+		query string
+		res   *SearchResults
+		want  TitleData
+	}{
+		{"label", "roadbike", "road bike", titleResults(false, entries...),
+			TitleData{Label: "roadbike", Query: "road bike", Count: 3, NewCount: 3, MinPrice: "$120"}},
+		{"query as label", "", "road bike", titleResults(false, entries...),
+			TitleData{Label: "road bike", Query: "road bike", Count: 3, NewCount: 3, MinPrice: "$120"}},
+		{"default label", "", "", titleResults(false),
+			TitleData{Label: "Results"}},
+		{"split", "roadbike", "", titleResults(true, entries...),
+			TitleData{Label: "roadbike", Count: 3, NewCount: 1, MinPrice: "$120"}},
+		{"no prices", "free", "", titleResults(false, ResultEntry{Title: "couch"}),
+			TitleData{Label: "free", Count: 1, NewCount: 1}},
+		{"CAD", "", "canoe", titleResults(false, ResultEntry{PriceValue: 900, Currency: "CAD"}, ResultEntry{PriceValue: 400, Currency: "CAD"}),
+			TitleData{Label: "canoe", Query: "canoe", Count: 2, NewCount: 2, MinPrice: "CA$400"}},
+		{"GBP", "", "sofa", titleResults(false, ResultEntry{PriceValue: 75, Currency: "GBP"}),
+			TitleData{Label: "sofa", Query: "sofa", Count: 1, NewCount: 1, MinPrice: "£75"}},
+		{"mixed currencies", "", "canoe", titleResults(false, ResultEntry{PriceValue: 900, Currency: "CAD"}, ResultEntry{PriceValue: 400, Currency: "USD"}),
+			TitleData{Label: "canoe", Query: "canoe", Count: 2, NewCount: 2}},
+		{"EUR", "", "velo", titleResults(false, ResultEntry{PriceValue: 1200, Currency: "EUR"}),
+			TitleData{Label: "velo", Query: "velo", Count: 1, NewCount: 1, MinPrice: "€1200"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NewTitleData(tt.label, tt.query, tt.res); got != tt.want {
+				t.Errorf("NewTitleData %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderTitle(t *testing.T) {
+	data := TitleData{Label: "roadbike", Query: "road bike", Count: 12, NewCount: 3, MinPrice: "$120"}
+
+	tests := []struct {
+		name     string
+		template string
+		data     TitleData
+		max      int
+		want     string
+	}{
+		{"fields", `{{ .Label }}: {{ .NewCount }} new, cheapest {{ .MinPrice }}`, data, 0, "roadbike: 3 new, cheapest $120"},
+		{"conditional", `{{ .Label }}{{ if .MinPrice }} from {{ .MinPrice }}{{ end }}`, TitleData{Label: "free"}, 0, "free"},
+		{"whitespace", "  {{ .Label }}\n\t{{ .Count }}   results  ", data, 0, "roadbike 12 results"},
+		{"money", `{{ .Query }} under {{ money 500 "CAD" }}`, data, 0, "road bike under CA$500"},
+		{"truncate", `{{ truncate 5 .Query }}`, data, 0, "road…"},
+		{"default", "", data, 0, "roadbike: 3 new, cheapest $120"},
+		{"default no prices", "", TitleData{Label: "free", NewCount: 2}, 0, "free: 2 new"},
+		{"no max", `{{ .Label }}`, TitleData{Label: strings.Repeat("x", 200)}, 0, strings.Repeat("x", 200)},
+		{"page", `{{ .Label }}`, TitleData{Label: strings.Repeat("x", 200)}, PageTitleLength, strings.Repeat("x", PageTitleLength-1) + "…"},
+		{"notification", `{{ .Label }}`, TitleData{Label: strings.Repeat("x", 200)}, NotificationTitleLength, strings.Repeat("x", NotificationTitleLength-1) + "…"},
+		{"max length runes", `{{ .Label }}`, TitleData{Label: strings.Repeat("é", 200)}, NotificationTitleLength, strings.Repeat("é", NotificationTitleLength-1) + "…"},
+		{"exact length", `{{ .Label }}`, TitleData{Label: strings.Repeat("x", PageTitleLength)}, PageTitleLength, strings.Repeat("x", PageTitleLength)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var tmpl *template.Template
+			if tt.template != "" {
+				var err error
+				if tmpl, err = ParseTitleTemplate(tt.template); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			got, err := RenderTitle(tmpl, tt.data, tt.max)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got != tt.want {
+				t.Errorf("RenderTitle %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTitleTemplateErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+	}{
+		{"syntax", `{{ .Label `},
+		{"unknown function", `{{ upper .Label }}`},
+		{"unknown field", `{{ .Price }}`},
+		{"bad arguments", `{{ money .Label "USD" }}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl, err := ParseTitleTemplate(tt.template)
+			if err != nil {
+				return
+			}
+
+			if got, err := RenderTitle(tmpl, TitleData{Label: "bikes"}, PageTitleLength); err == nil {
+				t.Errorf("RenderTitle %q, want error", got)
+			}
+		})
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		n    int
+		s    string
+		want string
+	}{
+		{10, "road bike", "road bike"},
+		{9, "road bike", "road bike"},
+		{8, "road bike", "road bi…"},
+		{1, "road bike", "…"},
+		{0, "road bike", ""},
+		{-1, "road bike", ""},
+		{5, "", ""},
+		{3, "vélo", "vé…"},
+		{4, "vélo", "vélo"},
+	}
+
+	for _, tt := range tests {
+		if got := truncate(tt.n, tt.s); got != tt.want {
+			t.Errorf("truncate(%v, %q) = %q, want %q", tt.n, tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestSavedSearchTitle(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string
+		wantErr bool
+	}{
+		{"valid", `{"roadbike": {"query": "road bike", "title": "{{ .Label }}: {{ .NewCount }} new"}}`, false},
+		{"syntax", `{"roadbike": {"query": "road bike", "title": "{{ .Label "}}`, true},
+		{"unknown function", `{"roadbike": {"query": "road bike", "title": "{{ upper .Label }}"}}`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.json")
+			if err := os.WriteFile(path, []byte(tt.config), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			_, _, err := LoadSavedSearches(path)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("LoadSavedSearches error %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
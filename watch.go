@@ -0,0 +1,247 @@
+package searchcraigs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// SeenState records the entries already reported, so that they are not reported again.
+// An entry is considered seen if either its hash or its href was seen before.
+type SeenState struct {
+	Seen       map[string]time.Time `json:"seen"`                 // key -> last time seen
+	Watermarks map[string]time.Time `json:"watermarks,omitempty"` // search -> most recent posting
+	Favorites  map[string]time.Time `json:"favorites,omitempty"`  // href -> time added, see ToggleFavorite
+
+	path string
+	keys func(ResultEntry) []string // the keys of an entry, seenKeys if nil
+}
+
+// LoadSeenState loads the state from path (a missing file is an empty state),
+// removing entries not seen for longer than maxAge (if maxAge > 0).
+func LoadSeenState(path string, maxAge time.Duration) (*SeenState, error) {
+	state := SeenState{Seen: map[string]time.Time{}, Watermarks: map[string]time.Time{}, Favorites: map[string]time.Time{}, path: path}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &state, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("%v: %w", path, err)
+	}
+
+	if state.Seen == nil {
+		state.Seen = map[string]time.Time{}
+	}
+
+	if state.Watermarks == nil {
+		state.Watermarks = map[string]time.Time{}
+	}
+
+	if state.Favorites == nil {
+		state.Favorites = map[string]time.Time{}
+	}
+
+	if maxAge > 0 {
+		state.Expire(time.Now().Add(-maxAge))
+	}
+
+	return &state, nil
+}
+
+// Expire removes the keys last seen before t.
+func (s *SeenState) Expire(t time.Time) {
+	for k, seen := range s.Seen {
+		if seen.Before(t) {
+			delete(s.Seen, k)
+		}
+	}
+}
+
+// watermarkMargin is subtracted from the watermark, to allow for clock skew
+// and for postings with timezone-less dates
+const watermarkMargin = 2 * time.Hour
+
+// Watermark returns the time of the most recent posting seen for the search (minus a safety margin),
+// or the zero time if the search was never run.
+func (s *SeenState) Watermark(search string) time.Time {
+	t, ok := s.Watermarks[search]
+	if !ok {
+		return time.Time{}
+	}
+
+	return t.Add(-watermarkMargin)
+}
+
+// UpdateWatermark records the most recent posting time in entries for the search.
+// Posting times in the future (clock skew or bad dates) are ignored.
+func (s *SeenState) UpdateWatermark(search string, entries []ResultEntry) {
+	max := s.Watermarks[search]
+	now := time.Now().Add(watermarkMargin)
+
+	for _, e := range entries {
+		if e.Posted.After(max) && e.Posted.Before(now) {
+			max = e.Posted
+		}
+	}
+
+	if !max.IsZero() {
+		s.Watermarks[search] = max
+	}
+}
+
+// ToggleFavorite adds the entry with the href to the favorites, or removes it if it's already there.
+// It returns true if the entry is now a favorite. Favorites don't expire.
+func (s *SeenState) ToggleFavorite(href string) bool {
+	if _, ok := s.Favorites[href]; ok {
+		delete(s.Favorites, href)
+		return false
+	}
+
+	s.Favorites[href] = time.Now()
+	return true
+}
+
+func seenKeys(entry ResultEntry) []string {
+	keys := []string{fmt.Sprintf("hash:%016x", entry.Hash())}
+	if entry.Href != "" {
+		keys = append(keys, "href:"+entry.Href)
+	}
+
+	return keys
+}
+
+func (s *SeenState) entryKeys(entry ResultEntry) []string {
+	if s.keys != nil {
+		return s.keys(entry)
+	}
+
+	return seenKeys(entry)
+}
+
+// Filter returns the entries that were not seen before, and marks all entries as seen.
+func (s *SeenState) Filter(entries []ResultEntry) []ResultEntry {
+	now := time.Now()
+	out := make([]ResultEntry, 0, len(entries))
+
+	for _, e := range entries {
+		seen := false
+
+		for _, k := range s.entryKeys(e) {
+			if _, ok := s.Seen[k]; ok {
+				seen = true
+			}
+
+			s.Seen[k] = now
+		}
+
+		if !seen {
+			out = append(out, e)
+		}
+	}
+
+	return out
+}
+
+// Unseen returns the entries that were not seen before (nor earlier in entries), without marking them as seen.
+// Use Mark to mark the entries actually returned.
+func (s *SeenState) Unseen(entries []ResultEntry) []ResultEntry {
+	batch := map[string]bool{}
+	out := make([]ResultEntry, 0, len(entries))
+
+	for _, e := range entries {
+		seen := false
+
+		for _, k := range s.entryKeys(e) {
+			if _, ok := s.Seen[k]; ok || batch[k] {
+				seen = true
+			}
+
+			batch[k] = true
+		}
+
+		if !seen {
+			out = append(out, e)
+		}
+	}
+
+	return out
+}
+
+// Save writes the state to its file. The file is replaced atomically so
+// an interrupted save doesn't corrupt the existing state.
+func (s *SeenState) Save() error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(s.path, data)
+}
+
+// writeFileAtomic writes data to a temporary file in the same directory and renames it to path.
+func writeFileAtomic(path string, data []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+
+	return os.Rename(f.Name(), path)
+}
+
+// watch runs search every interval, calling output with the entries not seen before,
+// until ctx is done.
+func watch(ctx context.Context, interval time.Duration, state *SeenState,
+	search func(ctx context.Context) (*SearchResults, error), output func(*SearchResults)) {
+
+	for {
+		res, err := search(ctx)
+		if err != nil {
+			log.Printf("ERROR: %v", err)
+		} else {
+			total := len(res.Entries)
+			res.Entries = state.Filter(res.Entries)
+
+			log.Printf("%v new entries (of %v)", len(res.Entries), total)
+
+			if len(res.Entries) > 0 {
+				output(res)
+			}
+
+			if err := state.Save(); err != nil {
+				log.Printf("ERROR saving state: %v", err)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			if err := state.Save(); err != nil {
+				log.Printf("ERROR saving state: %v", err)
+			}
+
+			return
+
+		case <-time.After(interval):
+		}
+	}
+}
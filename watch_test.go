@@ -0,0 +1,193 @@
+package searchcraigs
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWatermark(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+
+	tests := []struct {
+		name   string
+		prev   time.Time // the previous watermark, if not zero
+		posted []time.Time
+		want   time.Time // the watermark recorded, before the margin
+	}{
+		{"newest", time.Time{}, []time.Time{now.Add(-3 * time.Hour), now.Add(-time.Hour), now.Add(-2 * time.Hour)}, now.Add(-time.Hour)},
+		{"older than previous", now.Add(-time.Hour), []time.Time{now.Add(-2 * time.Hour)}, now.Add(-time.Hour)},
+		{"newer than previous", now.Add(-2 * time.Hour), []time.Time{now.Add(-time.Hour)}, now.Add(-time.Hour)},
+		{"skewed clock", time.Time{}, []time.Time{now.Add(watermarkMargin / 2)}, now.Add(watermarkMargin / 2)},
+		{"future", now.Add(-time.Hour), []time.Time{now.Add(2 * watermarkMargin)}, now.Add(-time.Hour)},
+		{"no dates", time.Time{}, []time.Time{{}, {}}, time.Time{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state := &SeenState{Watermarks: map[string]time.Time{}}
+			if !tt.prev.IsZero() {
+				state.Watermarks["bike"] = tt.prev
+			}
+
+			var entries []ResultEntry
+			for _, p := range tt.posted {
+				entries = append(entries, ResultEntry{Posted: p})
+			}
+
+			state.UpdateWatermark("bike", entries)
+
+			want := time.Time{}
+			if !tt.want.IsZero() {
+				want = tt.want.Add(-watermarkMargin)
+			}
+
+			if got := state.Watermark("bike"); !got.Equal(want) {
+				t.Errorf("Watermark %v, want %v", got, want)
+			}
+
+			if got := state.Watermark("canoe"); !got.IsZero() {
+				t.Errorf("Watermark of another search %v", got)
+			}
+		})
+	}
+}
+
+func TestSeenStateRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "seen.json")
+
+	bike := ResultEntry{Title: "road bike", PriceValue: 500, Href: "https://sfbay.craigslist.org/1.html"}
+	repost := ResultEntry{Title: "Road Bike", PriceValue: 500, Href: "https://sfbay.craigslist.org/2.html"}
+	canoe := ResultEntry{Title: "canoe", PriceValue: 900, Href: "https://sfbay.craigslist.org/3.html"}
+
+	state, err := LoadSeenState(path, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := dedupTitles(state.Filter([]ResultEntry{bike, canoe})); len(got) != 2 {
+		t.Fatalf("empty state: %q", got)
+	}
+
+	state.ToggleFavorite(canoe.Href)
+	state.UpdateWatermark("bike", []ResultEntry{{Posted: time.Now().Add(-time.Hour)}})
+
+	// the canoe was seen long ago
+	for _, k := range seenKeys(canoe) {
+		state.Seen[k] = time.Now().Add(-2 * time.Hour)
+	}
+
+	if err := state.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	state, err = LoadSeenState(path, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the re-post has the same hash as the bike, the canoe expired
+	got := dedupTitles(state.Filter([]ResultEntry{repost, bike, canoe}))
+	if want := []string{"canoe " + canoe.Href}; !slices.Equal(got, want) {
+		t.Errorf("Filter %q, want %q", got, want)
+	}
+
+	if _, ok := state.Favorites[canoe.Href]; !ok {
+		t.Error("favorite not saved (favorites don't expire)")
+	}
+
+	if state.Watermark("bike").IsZero() {
+		t.Error("watermark not saved")
+	}
+}
+
+func TestLoadSeenStateErrors(t *testing.T) {
+	dir := t.TempDir()
+
+	state, err := LoadSeenState(filepath.Join(dir, "missing.json"), 0)
+	if err != nil || len(state.Seen) != 0 || state.Favorites == nil || state.Watermarks == nil {
+		t.Errorf("missing file: %+v, %v", state, err)
+	}
+
+	path := filepath.Join(dir, "bad.json")
+	if err := os.WriteFile(path, []byte(`{"seen":`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := LoadSeenState(path, 0); err == nil || !strings.Contains(err.Error(), path) {
+		t.Errorf("got %v, want an error for %v", err, path)
+	}
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "seen.json")
+
+	for _, data := range []string{"first", "second"} {
+		if err := writeFileAtomic(path, []byte(data)); err != nil {
+			t.Fatal(err)
+		}
+
+		if got, err := os.ReadFile(path); err != nil || string(got) != data {
+			t.Errorf("got %q, %v, want %q", got, err, data)
+		}
+	}
+
+	// no temporary files left
+	if files, _ := os.ReadDir(dir); len(files) != 1 {
+		t.Errorf("%v files in the directory, want 1", len(files))
+	}
+
+	if err := writeFileAtomic(filepath.Join(dir, "missing", "seen.json"), []byte("data")); err == nil {
+		t.Error("no error writing to a missing directory")
+	}
+}
+
+// the state is saved when watch is interrupted, even if the last search failed
+func TestWatchSaveOnCancel(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "seen.json")
+
+	state, err := LoadSeenState(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// a failed search doesn't save the state, only the interrupt does
+	search := func(ctx context.Context) (*SearchResults, error) {
+		state.Mark([]ResultEntry{{Title: "bike"}})
+		cancel()
+		return nil, errors.New("failed")
+	}
+
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		watch(ctx, time.Hour, state, search, func(res *SearchResults) {
+			t.Errorf("output %q", dedupTitles(res.Entries))
+		})
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("watch not stopped")
+	}
+
+	saved, err := LoadSeenState(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !saved.IsSeen(ResultEntry{Title: "bike"}) {
+		t.Error("state not saved on cancel")
+	}
+}
@@ -0,0 +1,237 @@
+package searchcraigs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/gobs/httpclient"
+)
+
+// Historical search results from the Internet Archive (Wayback Machine).
+// Archived entries are only used for statistics, never mixed with the live listings.
+
+const waybackuri = "https://web.archive.org/"
+
+// ArchiveResults are the (deduplicated) entries found in archived search pages.
+type ArchiveResults struct {
+	From      time.Time
+	To        time.Time
+	Snapshots int // archived pages parsed
+	Skipped   int // archived pages that could not be fetched or had no results
+	Stats     PriceStats
+	Entries   []ResultEntry
+}
+
+// A Snapshot is an archived copy of a craigslist page.
+type Snapshot struct {
+	Timestamp string // yyyyMMddhhmmss
+	Original  string
+}
+
+func (s Snapshot) Time() time.Time {
+	t, _ := time.Parse("20060102150405", s.Timestamp)
+	return t
+}
+
+type WaybackClient struct {
+	c     *ClClient
+	delay time.Duration
+	last  time.Time
+}
+
+// NewWayback returns a client for the Wayback Machine, waiting at least delay between requests.
+// The requests are sent with the default client options, see ClClient.Wayback.
+func NewWayback(delay time.Duration) *WaybackClient {
+	return &WaybackClient{c: &ClClient{h: httpclient.NewHttpClient(waybackuri), backoff: defaultBackoff}, delay: delay}
+}
+
+// Wayback returns a client for the Wayback Machine sending the requests with c (with its proxy,
+// user agent, retries and rate limit), waiting at least delay between requests.
+func (c *ClClient) Wayback(delay time.Duration) *WaybackClient {
+	return &WaybackClient{c: c, delay: delay}
+}
+
+// wait enforces the minimum delay between requests to archive.org
+func (w *WaybackClient) wait(ctx context.Context) error {
+	if d := w.delay - time.Since(w.last); d > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(d):
+		}
+	}
+
+	w.last = time.Now()
+	return nil
+}
+
+// Snapshots returns up to max archived search pages for query in region/category,
+// archived between from and to.
+func (w *WaybackClient) Snapshots(region Region, cat Category, query string, from, to time.Time, max int) ([]Snapshot, error) {
+	return w.SnapshotsContext(context.Background(), region, cat, query, from, to, max)
+}
+
+// SnapshotsContext is like Snapshots, but the request is cancelled when ctx is done.
+func (w *WaybackClient) SnapshotsContext(ctx context.Context, region Region, cat Category, query string, from, to time.Time, max int) ([]Snapshot, error) {
+	params := map[string]interface{}{
+		"url":       fmt.Sprintf("%v.craigslist.org/search/", region),
+		"matchType": "prefix",
+		"from":      from.Format("20060102"),
+		"to":        to.Format("20060102"),
+		"output":    "json",
+		"fl":        "timestamp,original,statuscode",
+		"collapse":  "digest",
+	}
+
+	words := strings.Fields(strings.ToLower(query))
+	if len(words) > 0 {
+		params["filter"] = "original:(?i).*[?&]query=[^&]*" + regexp.QuoteMeta(url.QueryEscape(words[0])) + ".*"
+	}
+
+	if err := w.wait(ctx); err != nil {
+		return nil, err
+	}
+
+	res, err := httpclient.CheckStatus(w.c.send(ctx, httpclient.URLString(waybackuri+"cdx/search/cdx"), httpclient.Params(params)))
+	if err != nil {
+		return nil, err
+	}
+
+	var rows [][]string
+	err = json.NewDecoder(res.Body).Decode(&rows)
+	res.Body.Close()
+
+	if err != nil && err != io.EOF { // no results is an empty body
+		return nil, err
+	}
+
+	var snapshots []Snapshot
+
+	for i, row := range rows {
+		if i == 0 || len(row) < 3 || row[2] != "200" { // the first row is the header
+			continue
+		}
+
+		u, err := url.Parse(row[1])
+		if err != nil {
+			continue
+		}
+
+		if c := strings.TrimPrefix(strings.TrimSuffix(u.Path, "/"), "/search/"); c != string(cat) && !strings.HasSuffix(c, "/"+string(cat)) {
+			continue
+		}
+
+		q := strings.ToLower(u.Query().Get("query"))
+		match := true
+
+		for _, w := range words {
+			if !strings.Contains(q, w) {
+				match = false
+				break
+			}
+		}
+
+		if match {
+			snapshots = append(snapshots, Snapshot{Timestamp: row[0], Original: row[1]})
+		}
+	}
+
+	// keep the most recent snapshots
+	if len(snapshots) > max {
+		snapshots = snapshots[len(snapshots)-max:]
+	}
+
+	return snapshots, nil
+}
+
+// Fetch returns the entries in an archived search page, marked as Archived.
+func (w *WaybackClient) Fetch(s Snapshot, region Region) ([]ResultEntry, error) {
+	return w.FetchContext(context.Background(), s, region)
+}
+
+// FetchContext is like Fetch, but the request is cancelled when ctx is done.
+func (w *WaybackClient) FetchContext(ctx context.Context, s Snapshot, region Region) ([]ResultEntry, error) {
+	if err := w.wait(ctx); err != nil {
+		return nil, err
+	}
+
+	// id_ returns the original page, without the archive.org toolbar and link rewriting
+	res, err := httpclient.CheckStatus(w.c.send(ctx, httpclient.URLString(fmt.Sprintf("%vweb/%vid_/%v", waybackuri, s.Timestamp, s.Original))))
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err := goquery.NewDocumentFromReader(res.Body)
+	res.Body.Close()
+
+	if err != nil {
+		return nil, err
+	}
+
+	var results SearchResults
+	if parseResults(doc, string(region), w.c.regionCountry(region), &results) == 0 {
+		return nil, fmt.Errorf("no results in archived page %v", s.Original)
+	}
+
+	t := s.Time()
+
+	for i := range results.Entries {
+		results.Entries[i].Archived = true
+		results.Entries[i].ArchivedAt = &t
+	}
+
+	return results.Entries, nil
+}
+
+// Search returns the entries found in up to maxSnapshots archived search pages.
+// Pages that can't be fetched or parsed are skipped.
+func (w *WaybackClient) Search(region Region, cat Category, query string, from, to time.Time, maxSnapshots int) (*ArchiveResults, error) {
+	return w.SearchContext(context.Background(), region, cat, query, from, to, maxSnapshots)
+}
+
+// SearchContext is like Search, but the requests are cancelled when ctx is done.
+func (w *WaybackClient) SearchContext(ctx context.Context, region Region, cat Category, query string, from, to time.Time, maxSnapshots int) (*ArchiveResults, error) {
+	snapshots, err := w.SnapshotsContext(ctx, region, cat, query, from, to, maxSnapshots)
+	if err != nil {
+		return nil, err
+	}
+
+	ar := ArchiveResults{From: from, To: to}
+	all := SearchResults{seen: map[uint64]bool{}} // the same listing is often in multiple snapshots
+
+	for _, s := range snapshots {
+		entries, err := w.FetchContext(ctx, s, region)
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		if err != nil {
+			w.c.warn("wayback snapshot skipped", "timestamp", s.Timestamp, "error", err)
+			ar.Skipped++
+			continue
+		}
+
+		ar.Snapshots++
+
+		for _, e := range entries {
+			all.add(e)
+		}
+	}
+
+	ar.Entries = all.Entries
+	ar.Stats = ComputePriceStats(ar.Entries, "")
+	return &ar, nil
+}
+
+func (ar *ArchiveResults) Print(w io.Writer) {
+	fmt.Fprintf(w, "Archived (%v to %v, %v pages, %v skipped): ",
+		ar.From.Format("2006-01-02"), ar.To.Format("2006-01-02"), ar.Snapshots, ar.Skipped)
+	ar.Stats.Print(w)
+}
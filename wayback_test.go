@@ -0,0 +1,96 @@
+package searchcraigs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// waybackServer serves a CDX index with two snapshots (only the first one for a bike search)
+// and search-static.html as the archived pages.
+func waybackServer(t *testing.T) *testServer {
+	page, err := os.ReadFile(filepath.Join("testdata", "search-static.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Host+r.URL.Path == "web.archive.org/cdx/search/cdx":
+			fmt.Fprint(w, `[["timestamp", "original", "statuscode"],
+				["20240101120000", "https://sfbay.craigslist.org/search/sss?query=bike", "200"],
+				["20240201120000", "https://sfbay.craigslist.org/search/sss?query=canoe", "200"]]`)
+		case r.Host == "web.archive.org" && strings.HasPrefix(r.URL.Path, "/web/"):
+			w.Write(page)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+}
+
+func TestWaybackSearch(t *testing.T) {
+	s := waybackServer(t)
+	w := s.client(t, WithUserAgent("wayback-test")).Wayback(0)
+
+	from, to := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	ar, err := w.SearchContext(context.Background(), SFBay, ForSale, "bike", from, to, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ar.Snapshots != 1 || ar.Skipped != 0 || len(ar.Entries) == 0 {
+		t.Fatalf("%v snapshots, %v skipped, %v entries", ar.Snapshots, ar.Skipped, len(ar.Entries))
+	}
+
+	for _, e := range ar.Entries {
+		if !e.Archived || e.ArchivedAt == nil || !e.ArchivedAt.Equal(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)) {
+			t.Errorf("%v: archived %v at %v", e.Title, e.Archived, e.ArchivedAt)
+		}
+	}
+
+	requests := s.received()
+	if len(requests) != 2 {
+		t.Fatalf("%v requests, want the index and one snapshot", len(requests))
+	}
+
+	if want := "web.archive.org/web/20240101120000id_/https://sfbay.craigslist.org/search/sss?query=bike"; requests[1].url != want {
+		t.Errorf("snapshot request %v, want %v", requests[1].url, want)
+	}
+
+	for _, r := range requests {
+		if r.userAgent != "wayback-test" {
+			t.Errorf("%v: user agent %q, the requests should be sent with the client", r.url, r.userAgent)
+		}
+	}
+}
+
+// the context stops the wait between the requests
+func TestWaybackSearchCancel(t *testing.T) {
+	s := waybackServer(t)
+	w := s.client(t).Wayback(time.Hour)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+
+	_, err := w.SearchContext(ctx, SFBay, ForSale, "bike", time.Now().AddDate(-1, 0, 0), time.Now(), 10)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got %v, want context.DeadlineExceeded", err)
+	}
+
+	if d := time.Since(start); d > 5*time.Second {
+		t.Errorf("SearchContext returned after %v", d)
+	}
+
+	if n := len(s.received()); n != 1 {
+		t.Errorf("%v requests, want only the index", n)
+	}
+}